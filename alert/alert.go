@@ -0,0 +1,315 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/email"
+	"mojito/market"
+	"mojito/market/synthetic"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// webhookClient is used to deliver webhook notifications. The client only
+// connects to public addresses and does not follow redirects, a redirect is
+// reported as a failed delivery.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         newWebhookDialer().DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// watchers tracks the securities whose candlesticks are being evaluated
+// against alerts.
+var watchers = struct {
+	mutex *sync.Mutex
+	keys  map[string]bool
+}{
+	mutex: &sync.Mutex{},
+	keys:  map[string]bool{},
+}
+
+// webhookPayload is the body of a webhook notification.
+type webhookPayload struct {
+	AlertID     uint      `json:"alert_id"`
+	Name        string    `json:"name"`
+	Exchange    string    `json:"exchange"`
+	Ticker      string    `json:"ticker"`
+	Condition   Condition `json:"condition"`
+	Message     string    `json:"message"`
+	Price       float64   `json:"price"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// ruleState associates a rule with the version of the alert it was built from.
+type ruleState struct {
+	rule      Rule
+	updatedAt time.Time
+}
+
+// GenerateWebhookSecret creates a random secret used to sign webhook requests.
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Watch begins evaluating alerts against each candlestick committed for the
+// specified exchange and ticker. Calling Watch for a security that is already
+// watched has no effect.
+func Watch(exchange, ticker string) error {
+
+	exchange = strings.ToUpper(exchange)
+	ticker = strings.ToUpper(ticker)
+	key := exchange + "-" + ticker
+
+	watchers.mutex.Lock()
+	defer watchers.mutex.Unlock()
+
+	if watchers.keys[key] {
+		return nil
+	}
+
+	channel, _, err := synthetic.Subscribe(exchange, ticker)
+	if err != nil {
+		return err
+	}
+
+	watchers.keys[key] = true
+
+	go func() {
+		rules := map[uint]*ruleState{}
+		for candlestick := range channel {
+			rules = evaluate(context.Background(), data.DB(), exchange, ticker,
+				candlestick, rules)
+		}
+	}()
+
+	return nil
+
+}
+
+// evaluate checks each enabled alert for the specified exchange and ticker
+// against a new candlestick and notifies users of any alerts that are met.
+// Returns the rules for the alerts that are still enabled.
+func evaluate(ctx context.Context, db *gorm.DB, exchange, ticker string,
+	candlestick market.Candlestick,
+	rules map[uint]*ruleState) map[uint]*ruleState {
+
+	items, err := ListEnabledAlertByTicker(ctx, db, exchange, ticker)
+	if err != nil {
+		logrus.Error(err)
+		return rules
+	}
+
+	current := map[uint]*ruleState{}
+
+	for _, item := range items {
+
+		// rebuild the rule if the alert is new or has been changed
+		state, ok := rules[item.ID]
+		if !ok || !state.updatedAt.Equal(item.UpdatedAt) {
+			rule, err := newPrimedRule(ctx, db, item, candlestick.CreatedAt)
+			if err != nil {
+				logrus.Errorf("alert %d: %v", item.ID, err)
+				continue
+			}
+			state = &ruleState{rule: rule, updatedAt: item.UpdatedAt}
+		}
+
+		current[item.ID] = state
+
+		met, message := state.rule.Update(candlestick)
+		if !met {
+			continue
+		}
+
+		// skip notifying if the alert is cooling down
+		now := time.Now()
+		if item.LastTriggeredAt != nil && now.Before(item.LastTriggeredAt.Add(
+			time.Duration(item.Cooldown)*time.Second)) {
+			continue
+		}
+
+		if err := UpdateAlertTriggered(ctx, db, item.ID, now); err != nil {
+			logrus.Error(err)
+			continue
+		}
+
+		go notify(ctx, db, item, candlestick.Close, message, now)
+
+	}
+
+	return current
+
+}
+
+// newPrimedRule creates the rule for the supplied alert and primes it with the
+// candlesticks committed before the specified time.
+func newPrimedRule(ctx context.Context, db *gorm.DB, item *Alert,
+	before time.Time) (Rule, error) {
+
+	rule, err := NewRule(item)
+	if err != nil {
+		return nil, err
+	}
+
+	start := before.Add(-time.Duration(rule.Lookback()+1) *
+		market.Resolution1Minute.Duration())
+
+	candlesticks, err := synthetic.ListByTicker(ctx, db, item.Exchange,
+		item.Ticker, market.Resolution1Minute, start, before)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candlestick := range candlesticks {
+		rule.Update(candlestick)
+	}
+
+	return rule, nil
+
+}
+
+// notify delivers the notifications configured for a triggered alert and
+// records the outcome.
+func notify(ctx context.Context, db *gorm.DB, item *Alert, price float64,
+	message string, at time.Time) {
+
+	trigger := &Trigger{
+		AlertID: item.ID,
+		UserID:  item.UserID,
+		Price:   price,
+		Message: message,
+	}
+
+	errs := []string{}
+
+	if item.Email {
+		if err := sendEmail(ctx, db, item, price, message, at); err != nil {
+			logrus.Error(err)
+			errs = append(errs, fmt.Sprintf("email: %v", err))
+		}
+	}
+
+	if item.WebhookURL != "" {
+		if err := sendWebhook(ctx, item, price, message, at); err != nil {
+			logrus.Error(err)
+			errs = append(errs, fmt.Sprintf("webhook: %v", err))
+		}
+	}
+
+	trigger.Error = strings.Join(errs, "; ")
+
+	if err := SaveTrigger(ctx, db, trigger); err != nil {
+		logrus.Error(err)
+	}
+
+}
+
+// sendEmail notifies the owner of the supplied alert by email.
+func sendEmail(ctx context.Context, db *gorm.DB, item *Alert, price float64,
+	message string, at time.Time) error {
+
+	u, err := user.GetUserByID(ctx, db, item.UserID)
+	if err != nil {
+		return err
+	}
+
+	return email.SendEmailTemplate(
+		email.DefaultFromAddress(),
+		email.DefaultReplyToAddress(),
+		[]string{u.Email},
+		nil,
+		nil,
+		email.TemplateTitleAlert,
+		email.AlertData{
+			ClientBaseURL: server.ClientBaseURL(),
+			Name:          item.Name,
+			Exchange:      item.Exchange,
+			Ticker:        item.Ticker,
+			Message:       message,
+			Price:         price,
+			TriggeredAt:   at.UTC().Format(time.RFC1123),
+		},
+	)
+
+}
+
+// sendWebhook notifies the webhook URL of the supplied alert with a signed
+// request.
+func sendWebhook(ctx context.Context, item *Alert, price float64,
+	message string, at time.Time) error {
+
+	body, err := json.Marshal(webhookPayload{
+		AlertID:     item.ID,
+		Name:        item.Name,
+		Exchange:    item.Exchange,
+		Ticker:      item.Ticker,
+		Condition:   item.Condition,
+		Message:     message,
+		Price:       price,
+		TriggeredAt: at,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		item.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Mojito-Timestamp", timestamp)
+	req.Header.Set("X-Mojito-Signature",
+		SignWebhook(item.WebhookSecret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+
+}
+
+// SignWebhook computes the signature of a webhook request, receivers may use
+// it to verify that a request was sent by mojito.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
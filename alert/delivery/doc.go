@@ -0,0 +1,3 @@
+// Package delivery exposes an API for managing the logged in user's price
+// alerts.
+package delivery
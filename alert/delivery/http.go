@@ -0,0 +1,400 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mojito/alert"
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market/indicator"
+	"mojito/market/pattern"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the alert API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(createAlertEndpoint, user.JWTAuthMiddleware(),
+		createAlert)
+	server.Router().GET(listAlertEndpoint, user.JWTAuthMiddleware(),
+		listAlert)
+	server.Router().GET(getAlertEndpoint, user.JWTAuthMiddleware(), getAlert)
+	server.Router().PUT(updateAlertEndpoint, user.JWTAuthMiddleware(),
+		updateAlert)
+	server.Router().DELETE(deleteAlertEndpoint, user.JWTAuthMiddleware(),
+		deleteAlert)
+	server.Router().GET(listTriggerEndpoint, user.JWTAuthMiddleware(),
+		listTrigger)
+
+}
+
+const (
+	// createAlertEndpoint the API endpoint used to create a new alert.
+	createAlertEndpoint = "/alert"
+	// listAlertEndpoint the API endpoint used to retrieve the logged in user's
+	// alerts.
+	listAlertEndpoint = "/alert"
+	// getAlertEndpoint the API endpoint used to retrieve an alert.
+	getAlertEndpoint = "/alert/:id"
+	// updateAlertEndpoint the API endpoint used to update an alert.
+	updateAlertEndpoint = "/alert/:id"
+	// deleteAlertEndpoint the API endpoint used to delete an alert.
+	deleteAlertEndpoint = "/alert/:id"
+	// listTriggerEndpoint the API endpoint used to retrieve the notifications
+	// sent for an alert.
+	listTriggerEndpoint = "/alert/:id/trigger"
+	// alertNotFound is an error message returned when the requested alert does
+	// not exist or belongs to another user.
+	alertNotFound = "alert not found"
+	// defaultCooldown the number of seconds between notifications if the
+	// cooldown is not specified.
+	defaultCooldown = 3600
+	// maxTriggers the maximum number of triggers returned for an alert.
+	maxTriggers = 100
+)
+
+// createAlert creates a new alert for the logged in user.
+func createAlert(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	item := &alert.Alert{
+		UserID:   u.ID,
+		Cooldown: defaultCooldown,
+		Enabled:  true,
+	}
+
+	if ok := readAlertRequest(c, item); !ok {
+		return
+	}
+
+	if ok := saveAlert(c, item); !ok {
+		return
+	}
+
+	// respond with the new alert
+	c.JSON(http.StatusOK, formatAlert(item))
+
+}
+
+// listAlert retrieves the logged in user's alerts.
+func listAlert(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve alerts
+	items, err := alert.ListAlertByUserID(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	response := []alertResponse{}
+	for _, item := range items {
+		response = append(response, formatAlert(item))
+	}
+
+	// respond with alerts
+	c.JSON(http.StatusOK, response)
+
+}
+
+// getAlert retrieves one of the logged in user's alerts.
+func getAlert(c *gin.Context) {
+
+	item, ok := readAlert(c)
+	if !ok {
+		return
+	}
+
+	// respond with the alert
+	c.JSON(http.StatusOK, formatAlert(item))
+
+}
+
+// updateAlert updates one of the logged in user's alerts.
+func updateAlert(c *gin.Context) {
+
+	item, ok := readAlert(c)
+	if !ok {
+		return
+	}
+
+	if ok := readAlertRequest(c, item); !ok {
+		return
+	}
+
+	if ok := saveAlert(c, item); !ok {
+		return
+	}
+
+	// respond with the updated alert
+	c.JSON(http.StatusOK, formatAlert(item))
+
+}
+
+// deleteAlert deletes one of the logged in user's alerts.
+func deleteAlert(c *gin.Context) {
+
+	item, ok := readAlert(c)
+	if !ok {
+		return
+	}
+
+	if err := alert.DeleteAlert(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the alert was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// listTrigger retrieves the most recent notifications sent for one of the
+// logged in user's alerts.
+func listTrigger(c *gin.Context) {
+
+	item, ok := readAlert(c)
+	if !ok {
+		return
+	}
+
+	// retrieve triggers
+	triggers, err := alert.ListTriggerByAlertID(c, data.DB(), item.ID,
+		maxTriggers)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with triggers
+	c.JSON(http.StatusOK, triggers)
+
+}
+
+// readAlert retrieves the alert specified in the request path. Writes an error
+// response and returns false if the alert cannot be retrieved.
+func readAlert(c *gin.Context) (*alert.Alert, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: alertNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the alert
+	item, err := alert.GetAlertByID(c, data.DB(), u.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: alertNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// readAlertRequest reads and validates an alert configuration from the request
+// body and applies it to the supplied alert. Writes an error response and
+// returns false if the request is invalid.
+func readAlertRequest(c *gin.Context, item *alert.Alert) bool {
+
+	var req saveAlertRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return false
+	}
+
+	// validate request parameters
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "name is required",
+		})
+		return false
+	}
+
+	if req.Exchange == "" || req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker are required",
+		})
+		return false
+	}
+
+	if req.WebhookURL != "" {
+		if err := alert.ValidateWebhookURL(c, req.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: err.Error(),
+			})
+			return false
+		}
+	}
+
+	if !req.Email && req.WebhookURL == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "email or webhook url is required",
+		})
+		return false
+	}
+
+	if req.Cooldown != nil && *req.Cooldown < 0 {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "cooldown must not be negative",
+		})
+		return false
+	}
+
+	parameters, err := json.Marshal(req.Parameters)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	item.Name = req.Name
+	item.Exchange = strings.ToUpper(req.Exchange)
+	item.Ticker = strings.ToUpper(req.Ticker)
+	item.Condition = alert.Condition(strings.ToLower(req.Condition))
+	item.Threshold = req.Threshold
+	item.Window = req.Window
+	item.Indicator = indicator.Name(strings.ToLower(req.Indicator))
+	item.Parameters = string(parameters)
+	item.Pattern = pattern.Name(strings.ToLower(req.Pattern))
+	item.Email = req.Email
+	item.WebhookURL = req.WebhookURL
+
+	if req.Cooldown != nil {
+		item.Cooldown = *req.Cooldown
+	}
+
+	if req.Enabled != nil {
+		item.Enabled = *req.Enabled
+	}
+
+	// check that the condition can be evaluated
+	if _, err := alert.NewRule(item); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return false
+	}
+
+	return true
+
+}
+
+// saveAlert saves the supplied alert and begins watching its security if it is
+// enabled. Writes an error response and returns false if the alert cannot be
+// saved.
+func saveAlert(c *gin.Context, item *alert.Alert) bool {
+
+	// generate a secret for signing webhook requests
+	if item.WebhookURL != "" && item.WebhookSecret == "" {
+		secret, err := alert.GenerateWebhookSecret()
+		if err != nil {
+			logrus.Error(err)
+			c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+				ErrorMessage: httperror.InternalServerError,
+			})
+			return false
+		}
+		item.WebhookSecret = secret
+	}
+
+	if item.Enabled {
+		if err := alert.Watch(item.Exchange, item.Ticker); err != nil {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: err.Error(),
+			})
+			return false
+		}
+	}
+
+	if err := alert.SaveAlert(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	return true
+
+}
+
+// formatAlert decodes the stored indicator parameters of an alert for use in an
+// API response.
+func formatAlert(item *alert.Alert) alertResponse {
+
+	response := alertResponse{Alert: *item}
+
+	if item.Parameters != "" {
+		if err := json.Unmarshal([]byte(item.Parameters),
+			&response.Parameters); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return response
+
+}
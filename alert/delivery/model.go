@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"mojito/alert"
+	"mojito/market/indicator"
+)
+
+// saveAlertRequest is used to read a request to the create and update alert
+// endpoints.
+type saveAlertRequest struct {
+	Name       string           `json:"name"`
+	Exchange   string           `json:"exchange"`
+	Ticker     string           `json:"ticker"`
+	Condition  string           `json:"condition"`
+	Threshold  float64          `json:"threshold"`
+	Window     int              `json:"window"`
+	Indicator  string           `json:"indicator"`
+	Parameters indicator.Params `json:"parameters"`
+	Pattern    string           `json:"pattern"`
+	Email      bool             `json:"email"`
+	WebhookURL string           `json:"webhook_url"`
+	Cooldown   *int             `json:"cooldown"`
+	Enabled    *bool            `json:"enabled"`
+}
+
+// alertResponse is used to format an alert record in API responses.
+type alertResponse struct {
+	alert.Alert
+	Parameters indicator.Params `json:"parameters"`
+}
@@ -0,0 +1,13 @@
+// Package alert notifies users when market conditions they care about are met.
+// Each alert belongs to a user and is evaluated against every candlestick
+// committed by the market data feed for its exchange and ticker, alerts on
+// synthetic tickers are evaluated as their candlesticks are computed. Triggered
+// alerts are delivered by email, by a signed webhook, or both, and are not
+// delivered again until their cooldown has passed.
+//
+// Webhooks are delivered as a JSON POST request. The request carries the
+// X-Mojito-Timestamp header and the X-Mojito-Signature header, which holds the
+// hex encoded HMAC-SHA256 of the timestamp, a period, and the request body
+// keyed with the alert's webhook secret. Webhooks are only delivered to public
+// addresses and redirects are not followed.
+package alert
@@ -0,0 +1,38 @@
+package alert
+
+import (
+	"context"
+
+	"mojito/data"
+
+	"github.com/sirupsen/logrus"
+)
+
+// init migrates the package model.
+func init() {
+
+	data.DB().AutoMigrate(
+		Alert{},
+		Trigger{},
+	)
+
+}
+
+// Resume begins watching the securities of all enabled alerts. Resume should
+// be called once the market data feeds are started.
+func Resume() {
+
+	// retrieve enabled alerts
+	items, err := ListEnabledAlert(context.Background(), data.DB())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// watch the security of each alert
+	for _, item := range items {
+		if err := Watch(item.Exchange, item.Ticker); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+}
@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"time"
+
+	"mojito/market/indicator"
+	"mojito/market/pattern"
+
+	"gorm.io/gorm"
+)
+
+// Condition refers to the market condition an alert watches for.
+type Condition string
+
+// Define alert conditions.
+const (
+	ConditionPriceAbove          Condition = "price_above"           // the close crosses above the threshold
+	ConditionPriceBelow          Condition = "price_below"           // the close crosses below the threshold
+	ConditionPercentChange       Condition = "percent_change"        // the close changes by the threshold percent over the window
+	ConditionIndicatorCrossAbove Condition = "indicator_cross_above" // the indicator crosses above its reference line
+	ConditionIndicatorCrossBelow Condition = "indicator_cross_below" // the indicator crosses below its reference line
+	ConditionVolumeSpike         Condition = "volume_spike"          // volume exceeds the threshold multiple of the window average
+	ConditionPattern             Condition = "pattern"               // the candlestick pattern forms with at least the threshold confidence
+)
+
+/* Data Types */
+
+// Alert stores a condition a user wants to be notified of and how the user
+// should be notified.
+type Alert struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	Name       string         `json:"name"`
+	Exchange   string         `gorm:"index" json:"exchange"`
+	Ticker     string         `gorm:"index" json:"ticker"`
+	Condition  Condition      `json:"condition"`
+	Threshold  float64        `json:"threshold"`          // a price, a percent change, a volume multiple, or a pattern confidence depending on the condition
+	Window     int            `json:"window"`             // the number of candlesticks compared by percent change and volume spike conditions
+	Indicator  indicator.Name `json:"indicator"`          // the indicator watched by indicator conditions
+	Parameters string         `gorm:"type:text" json:"-"` // JSON encoded indicator parameters
+	Pattern    pattern.Name   `json:"pattern"`            // the candlestick pattern watched by pattern conditions
+
+	Email         bool   `json:"email"`          // whether to notify the user by email
+	WebhookURL    string `json:"webhook_url"`    // the URL notified when the alert is triggered, if any
+	WebhookSecret string `json:"webhook_secret"` // used to sign webhook requests
+
+	Cooldown int  `json:"cooldown"` // the minimum number of seconds between notifications
+	Enabled  bool `gorm:"index" json:"enabled"`
+
+	LastTriggeredAt *time.Time `json:"last_triggered_at"` // records when the alert was last triggered
+}
+
+// Trigger records a notification sent for an alert.
+type Trigger struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	AlertID uint `gorm:"index" json:"alert_id"`
+	UserID  uint `gorm:"index" json:"user_id"`
+
+	Price   float64 `json:"price"`
+	Message string  `json:"message"`
+	Error   string  `json:"error"` // records why a notification could not be delivered
+}
@@ -0,0 +1,126 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Alert                                                                      //
+////////////////////////////////////////////////////////////////////////////////
+
+// GetAlertByID retrieves an alert record belonging to the specified user by id.
+func GetAlertByID(ctx context.Context, db *gorm.DB, userID,
+	id uint) (*Alert, error) {
+
+	var item Alert
+
+	if err := db.Model(&Alert{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListAlertByUserID retrieves all alert records belonging to the specified
+// user.
+func ListAlertByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Alert, error) {
+
+	var items []*Alert
+
+	if err := db.Model(&Alert{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// ListEnabledAlert retrieves all enabled alert records.
+func ListEnabledAlert(ctx context.Context, db *gorm.DB) ([]*Alert, error) {
+
+	var items []*Alert
+
+	if err := db.Model(&Alert{}).
+		Where("enabled = ?", true).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// ListEnabledAlertByTicker retrieves all enabled alert records for the
+// specified exchange and ticker.
+func ListEnabledAlertByTicker(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) ([]*Alert, error) {
+
+	var items []*Alert
+
+	if err := db.Model(&Alert{}).
+		Where("enabled = ? AND exchange = ? AND ticker = ?", true, exchange,
+			ticker).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveAlert inserts or updates the supplied alert record.
+func SaveAlert(ctx context.Context, db *gorm.DB, item *Alert) error {
+	return db.Save(item).Error
+}
+
+// DeleteAlert deletes the supplied alert record.
+func DeleteAlert(ctx context.Context, db *gorm.DB, item *Alert) error {
+	return db.Delete(item).Error
+}
+
+// UpdateAlertTriggered records when the alert with the supplied id was last
+// triggered.
+func UpdateAlertTriggered(ctx context.Context, db *gorm.DB, id uint,
+	at time.Time) error {
+	return db.Model(&Alert{}).
+		Where("id = ?", id).
+		UpdateColumn("last_triggered_at", at).Error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Trigger                                                                    //
+////////////////////////////////////////////////////////////////////////////////
+
+// ListTriggerByAlertID retrieves the most recent trigger records for the
+// specified alert.
+func ListTriggerByAlertID(ctx context.Context, db *gorm.DB, alertID uint,
+	limit int) ([]*Trigger, error) {
+
+	var items []*Trigger
+
+	if err := db.Model(&Trigger{}).
+		Where("alert_id = ?", alertID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveTrigger inserts or updates the supplied trigger record.
+func SaveTrigger(ctx context.Context, db *gorm.DB, item *Trigger) error {
+	return db.Save(item).Error
+}
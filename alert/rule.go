@@ -0,0 +1,336 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"mojito/market"
+	"mojito/market/indicator"
+	"mojito/market/pattern"
+)
+
+// ErrUnknownCondition is returned when an alert is configured with a condition
+// that is not defined.
+var ErrUnknownCondition = errors.New("unknown condition")
+
+// Rule evaluates an alert condition over a series of candlesticks.
+type Rule interface {
+	// Update adds the next candlestick in the series to the rule and returns
+	// whether the condition was met along with a description of what
+	// happened.
+	Update(candlestick market.Candlestick) (bool, string)
+	// Lookback gets the number of candlesticks that must be supplied before
+	// the rule is able to evaluate its condition.
+	Lookback() int
+}
+
+// NewRule creates the rule that evaluates the condition of the supplied alert.
+func NewRule(item *Alert) (Rule, error) {
+
+	switch item.Condition {
+	case ConditionPriceAbove, ConditionPriceBelow:
+		if item.Threshold <= 0 {
+			return nil, errors.New("threshold must be greater than zero")
+		}
+		return &priceRule{
+			threshold: item.Threshold,
+			above:     item.Condition == ConditionPriceAbove,
+		}, nil
+	case ConditionPercentChange:
+		if item.Threshold == 0 {
+			return nil, errors.New("threshold must not be zero")
+		}
+		window, err := ruleWindow(item.Window, 60)
+		if err != nil {
+			return nil, err
+		}
+		return &percentChangeRule{
+			threshold: item.Threshold,
+			closes:    make([]float64, 0, window+1),
+			window:    window,
+		}, nil
+	case ConditionVolumeSpike:
+		if item.Threshold <= 1 {
+			return nil, errors.New("threshold must be greater than one")
+		}
+		window, err := ruleWindow(item.Window, 20)
+		if err != nil {
+			return nil, err
+		}
+		return &volumeSpikeRule{
+			threshold: item.Threshold,
+			volumes:   make([]float64, 0, window),
+			window:    window,
+		}, nil
+	case ConditionIndicatorCrossAbove, ConditionIndicatorCrossBelow:
+		params := indicator.Params{}
+		if item.Parameters != "" {
+			if err := json.Unmarshal([]byte(item.Parameters),
+				&params); err != nil {
+				return nil, err
+			}
+		}
+		ind, err := indicator.New(item.Indicator, params)
+		if err != nil {
+			return nil, err
+		}
+		return &crossRule{
+			name:      item.Indicator,
+			indicator: ind,
+			above:     item.Condition == ConditionIndicatorCrossAbove,
+		}, nil
+	case ConditionPattern:
+		if item.Threshold < 0 || item.Threshold > 1 {
+			return nil, errors.New("threshold must be between 0 and 1")
+		}
+		name, err := pattern.ParseName(string(item.Pattern))
+		if err != nil {
+			return nil, err
+		}
+		scanner, err := pattern.NewScanner(name)
+		if err != nil {
+			return nil, err
+		}
+		return &patternRule{scanner: scanner, threshold: item.Threshold}, nil
+	}
+
+	return nil, ErrUnknownCondition
+
+}
+
+// ruleWindow validates the window of a rule, returning the supplied default if
+// the window is not set.
+func ruleWindow(window, defaultVal int) (int, error) {
+	if window == 0 {
+		return defaultVal, nil
+	}
+	if window < 1 || window > 1440 {
+		return 0, errors.New("window must be between 1 and 1440")
+	}
+	return window, nil
+}
+
+// priceRule is met when the close crosses a price threshold.
+type priceRule struct {
+	threshold float64
+	above     bool
+	prevClose float64
+	ready     bool
+}
+
+// Update adds the next candlestick to the rule.
+func (p *priceRule) Update(candlestick market.Candlestick) (bool, string) {
+
+	prevClose, ready := p.prevClose, p.ready
+	p.prevClose, p.ready = candlestick.Close, true
+
+	if !ready {
+		return false, ""
+	}
+
+	if p.above && prevClose <= p.threshold && candlestick.Close > p.threshold {
+		return true, fmt.Sprintf("%s crossed above %.2f, closing at %.2f",
+			candlestick.Ticker, p.threshold, candlestick.Close)
+	}
+
+	if !p.above && prevClose >= p.threshold && candlestick.Close < p.threshold {
+		return true, fmt.Sprintf("%s crossed below %.2f, closing at %.2f",
+			candlestick.Ticker, p.threshold, candlestick.Close)
+	}
+
+	return false, ""
+
+}
+
+// Lookback gets the number of candlesticks needed before the rule is
+// evaluated.
+func (p *priceRule) Lookback() int {
+	return 1
+}
+
+// percentChangeRule is met when the close has changed by at least the
+// threshold percent over the window. A positive threshold watches for rises, a
+// negative threshold watches for falls.
+type percentChangeRule struct {
+	threshold float64
+	closes    []float64
+	window    int
+}
+
+// Update adds the next candlestick to the rule.
+func (p *percentChangeRule) Update(
+	candlestick market.Candlestick) (bool, string) {
+
+	if len(p.closes) == p.window+1 {
+		p.closes = p.closes[1:]
+	}
+	p.closes = append(p.closes, candlestick.Close)
+
+	if len(p.closes) < p.window+1 || p.closes[0] == 0 {
+		return false, ""
+	}
+
+	change := (candlestick.Close - p.closes[0]) / p.closes[0] * 100
+
+	if (p.threshold > 0 && change >= p.threshold) ||
+		(p.threshold < 0 && change <= p.threshold) {
+		return true, fmt.Sprintf("%s changed %+.2f%% over %d candlesticks, "+
+			"closing at %.2f", candlestick.Ticker, change, p.window,
+			candlestick.Close)
+	}
+
+	return false, ""
+
+}
+
+// Lookback gets the number of candlesticks needed before the rule is
+// evaluated.
+func (p *percentChangeRule) Lookback() int {
+	return p.window
+}
+
+// volumeSpikeRule is met when the volume of a candlestick is at least the
+// threshold multiple of the average volume over the window.
+type volumeSpikeRule struct {
+	threshold float64
+	volumes   []float64
+	window    int
+}
+
+// Update adds the next candlestick to the rule.
+func (v *volumeSpikeRule) Update(
+	candlestick market.Candlestick) (bool, string) {
+
+	volume := candlestick.Volume
+
+	// compare against the average before adding this candlestick
+	var average float64
+	for _, v := range v.volumes {
+		average += v
+	}
+	full := len(v.volumes) == v.window
+	if full {
+		average /= float64(v.window)
+		v.volumes = v.volumes[1:]
+	}
+	v.volumes = append(v.volumes, volume)
+
+	if !full || average == 0 || volume < average*v.threshold {
+		return false, ""
+	}
+
+	return true, fmt.Sprintf("%s volume spiked to %.1fx the %d candlestick "+
+		"average, closing at %.2f", candlestick.Ticker, volume/average,
+		v.window, candlestick.Close)
+
+}
+
+// Lookback gets the number of candlesticks needed before the rule is
+// evaluated.
+func (v *volumeSpikeRule) Lookback() int {
+	return v.window
+}
+
+// crossRule is met when an indicator crosses its reference line. Indicators
+// with a signal line are compared to it, other indicators are compared to the
+// close.
+type crossRule struct {
+	name      indicator.Name
+	indicator indicator.Indicator
+	above     bool
+	prevDiff  float64
+	ready     bool
+}
+
+// Update adds the next candlestick to the rule.
+func (c *crossRule) Update(candlestick market.Candlestick) (bool, string) {
+
+	value, ok := c.indicator.Update(candlestick)
+	if !ok {
+		return false, ""
+	}
+
+	line, reference, lineName, referenceName := crossLines(c.name, value,
+		candlestick)
+	diff := line - reference
+
+	prevDiff, ready := c.prevDiff, c.ready
+	c.prevDiff, c.ready = diff, true
+
+	if !ready || math.IsNaN(diff) {
+		return false, ""
+	}
+
+	if c.above && prevDiff <= 0 && diff > 0 {
+		return true, fmt.Sprintf("%s %s crossed above %s, closing at %.2f",
+			candlestick.Ticker, lineName, referenceName, candlestick.Close)
+	}
+
+	if !c.above && prevDiff >= 0 && diff < 0 {
+		return true, fmt.Sprintf("%s %s crossed below %s, closing at %.2f",
+			candlestick.Ticker, lineName, referenceName, candlestick.Close)
+	}
+
+	return false, ""
+
+}
+
+// Lookback gets the number of candlesticks needed before the rule is
+// evaluated.
+func (c *crossRule) Lookback() int {
+	return c.indicator.Lookback() + 1
+}
+
+// patternRule is met when a candlestick pattern forms with at least the
+// threshold confidence.
+type patternRule struct {
+	scanner   *pattern.Scanner
+	threshold float64
+}
+
+// Update adds the next candlestick to the rule.
+func (p *patternRule) Update(candlestick market.Candlestick) (bool, string) {
+
+	for _, match := range p.scanner.Update(candlestick) {
+		if match.Confidence >= p.threshold {
+			return true, fmt.Sprintf("%s formed a %s %s pattern with %.0f%% "+
+				"confidence, closing at %.2f", candlestick.Ticker,
+				match.Direction, strings.ReplaceAll(string(match.Pattern), "_",
+					" "), match.Confidence*100, candlestick.Close)
+		}
+	}
+
+	return false, ""
+
+}
+
+// Lookback gets the number of candlesticks needed before the rule is
+// evaluated.
+func (p *patternRule) Lookback() int {
+	return p.scanner.Lookback()
+}
+
+// crossLines gets the line of an indicator value that is watched for crosses
+// and the reference line it is compared to, along with the name of each line.
+func crossLines(name indicator.Name, value indicator.Value,
+	candlestick market.Candlestick) (float64, float64, string, string) {
+
+	switch name {
+	case indicator.NameMACD:
+		return value["macd"], value["signal"], "MACD", "its signal line"
+	case indicator.NameStochastic:
+		return value["k"], value["d"], "stochastic %K", "%D"
+	case indicator.NameBollinger:
+		return candlestick.Close, value["middle"], "price",
+			"the middle Bollinger Band"
+	case indicator.NameRSI:
+		return value["value"], 50, "RSI", "50"
+	}
+
+	return candlestick.Close, value["value"], "price",
+		strings.ToUpper(string(name))
+
+}
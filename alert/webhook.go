@@ -0,0 +1,113 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookAddress is returned if a webhook URL refers to an address that is
+// not reachable from the public internet.
+var ErrWebhookAddress = errors.New("webhook url must resolve to a public " +
+	"address")
+
+// webhookTimeout how long we wait to connect to a webhook receiver.
+const webhookTimeout = 10 * time.Second
+
+// reservedNetworks lists the networks webhooks may not be delivered to in
+// addition to the loopback, link-local, and unspecified addresses, so a user
+// cannot direct the server at internal services.
+var reservedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"fc00::/7",
+)
+
+// ValidateWebhookURL checks that the supplied webhook URL uses HTTP or HTTPS
+// and that each address its host resolves to is public. The address is
+// checked again when the webhook is delivered since the host may resolve
+// differently by then.
+func ValidateWebhookURL(ctx context.Context, webhookURL string) error {
+
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		u.Hostname() == "" {
+		return errors.New("invalid webhook url")
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host could not be resolved: %s",
+			u.Hostname())
+	}
+
+	for _, address := range addresses {
+		if !publicIP(address.IP) {
+			return ErrWebhookAddress
+		}
+	}
+
+	return nil
+
+}
+
+// newWebhookDialer creates a dialer that refuses to connect to addresses that
+// are not public. The check runs after the host is resolved so a host cannot
+// resolve to a public address when validated and a private one when dialed.
+func newWebhookDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return ErrWebhookAddress
+			}
+			return nil
+		},
+	}
+}
+
+// publicIP determines whether the supplied address is reachable from the
+// public internet.
+func publicIP(ip net.IP) bool {
+
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// parseNetworks parses the supplied CIDR notation networks.
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
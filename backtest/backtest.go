@@ -0,0 +1,202 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"mojito/market"
+	"mojito/strategy"
+)
+
+// Config defines the simulated trading conditions for a backtest.
+type Config struct {
+	InitialCash  float64
+	FeeRate      float64
+	SlippageRate float64
+	Resolution   market.Resolution
+}
+
+// Result stores the outcome of a backtest.
+type Result struct {
+	Trades      []Trade
+	EquityCurve []EquityPoint
+	FinalEquity float64
+	TotalReturn float64
+	MaxDrawdown float64
+	SharpeRatio float64
+	WinRate     float64
+}
+
+// Run replays the supplied candlesticks through a strategy in order. The
+// simulated account goes all in on a buy signal and closes its position on a
+// sell signal. Signals are produced at the close of a candlestick and filled
+// at the open of the following candlestick, fill prices are moved against the
+// account by the slippage rate and each fill pays the fee rate. A position
+// that is still open at the end of the candlesticks is closed at the final
+// close.
+func Run(s strategy.Strategy, candlesticks []market.Candlestick,
+	config Config) Result {
+
+	result := Result{
+		Trades:      []Trade{},
+		EquityCurve: []EquityPoint{},
+	}
+
+	cash := config.InitialCash
+	var position *Trade
+	pending := strategy.SignalHold
+
+	// buy spends all available cash on the security at the supplied price
+	buy := func(at time.Time, price float64) {
+		price *= 1 + config.SlippageRate
+		quantity := cash / (price * (1 + config.FeeRate))
+		fee := quantity * price * config.FeeRate
+		position = &Trade{
+			EntryTime:  at,
+			EntryPrice: price,
+			Quantity:   quantity,
+			Fees:       fee,
+		}
+		cash -= quantity*price + fee
+	}
+
+	// sell closes the open position at the supplied price
+	sell := func(at time.Time, price float64) {
+		price *= 1 - config.SlippageRate
+		fee := position.Quantity * price * config.FeeRate
+		cash += position.Quantity*price - fee
+
+		trade := *position
+		trade.ExitTime = at
+		trade.ExitPrice = price
+		trade.Fees += fee
+		trade.Profit = position.Quantity*(price-trade.EntryPrice) -
+			trade.Fees
+		trade.Return = trade.Profit /
+			(position.Quantity*trade.EntryPrice + position.Fees)
+
+		result.Trades = append(result.Trades, trade)
+		position = nil
+	}
+
+	for _, candlestick := range candlesticks {
+
+		// fill the signal produced by the previous candlestick
+		if pending == strategy.SignalBuy && position == nil {
+			buy(candlestick.CreatedAt, candlestick.Open)
+		} else if pending == strategy.SignalSell && position != nil {
+			sell(candlestick.CreatedAt, candlestick.Open)
+		}
+
+		pending = s.Update(candlestick)
+
+		// record the value of the account at the close
+		equity := cash
+		if position != nil {
+			equity += position.Quantity * candlestick.Close
+		}
+
+		result.EquityCurve = append(result.EquityCurve, EquityPoint{
+			Time:   candlestick.CreatedAt,
+			Equity: equity,
+		})
+
+	}
+
+	// close any position still open at the end of the backtest
+	if position != nil {
+		last := candlesticks[len(candlesticks)-1]
+		sell(last.CreatedAt, last.Close)
+		result.EquityCurve[len(result.EquityCurve)-1].Equity = cash
+	}
+
+	result.FinalEquity = cash
+	if config.InitialCash > 0 {
+		result.TotalReturn = cash/config.InitialCash - 1
+	}
+	result.MaxDrawdown = maxDrawdown(result.EquityCurve)
+	result.SharpeRatio = sharpeRatio(result.EquityCurve, config.Resolution)
+	result.WinRate = winRate(result.Trades)
+
+	return result
+
+}
+
+// maxDrawdown gets the largest decline from a peak in the equity curve as a
+// fraction of the peak.
+func maxDrawdown(curve []EquityPoint) float64 {
+
+	var peak, drawdown float64
+
+	for _, point := range curve {
+		peak = math.Max(peak, point.Equity)
+		if peak > 0 {
+			drawdown = math.Max(drawdown, (peak-point.Equity)/peak)
+		}
+	}
+
+	return drawdown
+
+}
+
+// sharpeRatio gets the annualized Sharpe ratio of the returns between points
+// in the equity curve, assuming a risk-free rate of zero. Returns are
+// annualized assuming the market trades around the clock.
+func sharpeRatio(curve []EquityPoint, resolution market.Resolution) float64 {
+
+	if len(curve) < 3 || resolution.Duration() == 0 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(curve)-1)
+	for i := 1; i < len(curve); i++ {
+		if curve[i-1].Equity == 0 {
+			continue
+		}
+		returns = append(returns, curve[i].Equity/curve[i-1].Equity-1)
+	}
+
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+
+	if stddev == 0 {
+		return 0
+	}
+
+	periodsPerYear := float64(365*24*time.Hour) /
+		float64(resolution.Duration())
+
+	return mean / stddev * math.Sqrt(periodsPerYear)
+
+}
+
+// winRate gets the fraction of trades that were profitable.
+func winRate(trades []Trade) float64 {
+
+	if len(trades) == 0 {
+		return 0
+	}
+
+	var wins int
+	for _, trade := range trades {
+		if trade.Profit > 0 {
+			wins++
+		}
+	}
+
+	return float64(wins) / float64(len(trades))
+
+}
@@ -0,0 +1,3 @@
+// Package delivery exposes an API for running backtests and retrieving their
+// results.
+package delivery
@@ -0,0 +1,352 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mojito/backtest"
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market"
+	"mojito/server"
+	"mojito/strategy"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the backtest API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(createBacktestEndpoint, user.JWTAuthMiddleware(),
+		createBacktest)
+	server.Router().GET(listBacktestEndpoint, user.JWTAuthMiddleware(),
+		listBacktest)
+	server.Router().GET(getBacktestEndpoint, user.JWTAuthMiddleware(),
+		getBacktest)
+	server.Router().DELETE(deleteBacktestEndpoint, user.JWTAuthMiddleware(),
+		deleteBacktest)
+
+}
+
+const (
+	// createBacktestEndpoint the API endpoint used to run a new backtest.
+	createBacktestEndpoint = "/backtest"
+	// listBacktestEndpoint the API endpoint used to retrieve the logged in
+	// user's backtests.
+	listBacktestEndpoint = "/backtest"
+	// getBacktestEndpoint the API endpoint used to retrieve the full results of
+	// a backtest.
+	getBacktestEndpoint = "/backtest/:id"
+	// deleteBacktestEndpoint the API endpoint used to delete a backtest.
+	deleteBacktestEndpoint = "/backtest/:id"
+	// maxBacktestCandlesticks the maximum number of candlesticks a single
+	// backtest may replay.
+	maxBacktestCandlesticks = 50000
+	// backtestNotFound is an error message returned when the requested
+	// backtest does not exist or belongs to another user.
+	backtestNotFound = "backtest not found"
+)
+
+// createBacktest replays stored candlesticks through a strategy and stores the
+// results for the logged in user.
+func createBacktest(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	var req createBacktestRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	// validate request parameters
+	if req.Exchange == "" || req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker are required",
+		})
+		return
+	}
+
+	resolution, err := market.ParseResolution(req.Resolution)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid resolution, expected one of 1m, 5m, 15m, 1h, 4h, 1d, 1w",
+		})
+		return
+	}
+
+	if !req.StartDate.Before(req.EndDate) {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "start date must be before end date",
+		})
+		return
+	}
+
+	if req.EndDate.Sub(req.StartDate)/resolution.Duration() >
+		maxBacktestCandlesticks {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: fmt.Sprintf(
+				"date range too large, at most %d candlesticks may be replayed",
+				maxBacktestCandlesticks),
+		})
+		return
+	}
+
+	if req.InitialCash <= 0 {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "initial cash must be greater than zero",
+		})
+		return
+	}
+
+	if req.FeeRate < 0 || req.FeeRate >= 1 ||
+		req.SlippageRate < 0 || req.SlippageRate >= 1 {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "fee and slippage rates must be between 0 and 1",
+		})
+		return
+	}
+
+	// create the strategy
+	s, err := strategy.New(strategy.Type(req.Strategy), req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// retrieve the candlesticks to replay
+	exchange := strings.ToUpper(req.Exchange)
+	ticker := strings.ToUpper(req.Ticker)
+
+	candlesticks, err := market.ListByTicker(c, data.DB(), exchange, ticker,
+		resolution, req.StartDate, req.EndDate)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	if len(candlesticks) == 0 {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "no price data for the requested date range",
+		})
+		return
+	}
+
+	// run the backtest
+	result := backtest.Run(s, candlesticks, backtest.Config{
+		InitialCash:  req.InitialCash,
+		FeeRate:      req.FeeRate,
+		SlippageRate: req.SlippageRate,
+		Resolution:   resolution,
+	})
+
+	parameters, err := json.Marshal(req.Parameters)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	equityCurve, err := json.Marshal(result.EquityCurve)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// store the backtest results
+	item := &backtest.Backtest{
+		UserID:       u.ID,
+		Exchange:     exchange,
+		Ticker:       ticker,
+		Strategy:     strategy.Type(strings.ToLower(req.Strategy)),
+		Parameters:   string(parameters),
+		Resolution:   resolution,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		InitialCash:  req.InitialCash,
+		FeeRate:      req.FeeRate,
+		SlippageRate: req.SlippageRate,
+		FinalEquity:  result.FinalEquity,
+		TotalReturn:  result.TotalReturn,
+		MaxDrawdown:  result.MaxDrawdown,
+		SharpeRatio:  result.SharpeRatio,
+		WinRate:      result.WinRate,
+		EquityCurve:  string(equityCurve),
+		Trades:       result.Trades,
+	}
+
+	if err := backtest.SaveBacktest(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the backtest results
+	c.JSON(http.StatusOK, formatBacktest(item, true))
+
+}
+
+// listBacktest retrieves a summary of each of the logged in user's backtests.
+func listBacktest(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve backtests
+	items, err := backtest.ListBacktestByUserID(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	response := []backtestResponse{}
+	for _, item := range items {
+		response = append(response, formatBacktest(item, false))
+	}
+
+	// respond with backtests
+	c.JSON(http.StatusOK, response)
+
+}
+
+// getBacktest retrieves the full results of one of the logged in user's
+// backtests.
+func getBacktest(c *gin.Context) {
+
+	item, ok := readBacktest(c)
+	if !ok {
+		return
+	}
+
+	// respond with the backtest results
+	c.JSON(http.StatusOK, formatBacktest(item, true))
+
+}
+
+// deleteBacktest deletes one of the logged in user's backtests.
+func deleteBacktest(c *gin.Context) {
+
+	item, ok := readBacktest(c)
+	if !ok {
+		return
+	}
+
+	if err := backtest.DeleteBacktest(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the backtest was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// readBacktest retrieves the backtest referenced by the request path for the
+// logged in user. If the backtest cannot be retrieved an error response is
+// written and false is returned.
+func readBacktest(c *gin.Context) (*backtest.Backtest, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: backtestNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the backtest
+	item, err := backtest.GetBacktestByID(c, data.DB(), u.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: backtestNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// formatBacktest decodes the stored parameters and, if requested, the equity
+// curve of a backtest for use in an API response.
+func formatBacktest(item *backtest.Backtest,
+	includeEquityCurve bool) backtestResponse {
+
+	response := backtestResponse{Backtest: *item}
+
+	if err := json.Unmarshal([]byte(item.Parameters),
+		&response.Parameters); err != nil {
+		logrus.Error(err)
+	}
+
+	if includeEquityCurve {
+		if err := json.Unmarshal([]byte(item.EquityCurve),
+			&response.EquityCurve); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return response
+
+}
@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"time"
+
+	"mojito/backtest"
+	"mojito/market/indicator"
+)
+
+// createBacktestRequest is used to read a request to the create backtest
+// endpoint.
+type createBacktestRequest struct {
+	Exchange     string           `json:"exchange"`
+	Ticker       string           `json:"ticker"`
+	Strategy     string           `json:"strategy"`
+	Parameters   indicator.Params `json:"parameters"`
+	Resolution   string           `json:"resolution"`
+	StartDate    time.Time        `json:"start_date"`
+	EndDate      time.Time        `json:"end_date"`
+	InitialCash  float64          `json:"initial_cash"`
+	FeeRate      float64          `json:"fee_rate"`
+	SlippageRate float64          `json:"slippage_rate"`
+}
+
+// backtestResponse is used to format a backtest record in API responses.
+type backtestResponse struct {
+	backtest.Backtest
+	Parameters  indicator.Params       `json:"parameters"`
+	EquityCurve []backtest.EquityPoint `json:"equity_curve,omitempty"`
+}
@@ -0,0 +1,4 @@
+// Package backtest evaluates trading strategies by replaying stored
+// candlesticks through a strategy and simulating the resulting trades. The
+// results of each backtest are stored for the user that requested it.
+package backtest
@@ -0,0 +1,13 @@
+package backtest
+
+import (
+	"mojito/data"
+)
+
+// init migrates the package model.
+func init() {
+	data.DB().AutoMigrate(
+		Backtest{},
+		Trade{},
+	)
+}
@@ -0,0 +1,69 @@
+package backtest
+
+import (
+	"time"
+
+	"mojito/market"
+	"mojito/strategy"
+
+	"gorm.io/gorm"
+)
+
+/* Data Types */
+
+// Backtest stores the configuration and results of replaying a strategy over
+// historical price data.
+type Backtest struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	// strategy and price data used to run the backtest
+	Exchange   string            `json:"exchange"`
+	Ticker     string            `json:"ticker"`
+	Strategy   strategy.Type     `json:"strategy"`
+	Parameters string            `gorm:"type:text" json:"-"` // JSON encoded strategy parameters
+	Resolution market.Resolution `json:"resolution"`
+	StartDate  time.Time         `json:"start_date"`
+	EndDate    time.Time         `json:"end_date"`
+
+	// simulated trading conditions
+	InitialCash  float64 `json:"initial_cash"`
+	FeeRate      float64 `json:"fee_rate"`      // fraction of each fill paid as a fee
+	SlippageRate float64 `json:"slippage_rate"` // fraction each fill price moves against us
+
+	// results
+	FinalEquity float64 `json:"final_equity"`
+	TotalReturn float64 `json:"total_return"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+	SharpeRatio float64 `json:"sharpe_ratio"`
+	WinRate     float64 `json:"win_rate"`
+	EquityCurve string  `gorm:"type:text" json:"-"` // JSON encoded equity curve
+
+	Trades []Trade `json:"trades,omitempty"`
+}
+
+// Trade stores a single simulated round trip, a buy followed by a sell.
+type Trade struct {
+	ID         uint `gorm:"primarykey" json:"id"`
+	BacktestID uint `gorm:"index" json:"backtest_id"`
+
+	EntryTime  time.Time `json:"entry_time"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitTime   time.Time `json:"exit_time"`
+	ExitPrice  float64   `json:"exit_price"`
+	Quantity   float64   `json:"quantity"`
+	Fees       float64   `json:"fees"`
+	Profit     float64   `json:"profit"`
+	Return     float64   `json:"return"`
+}
+
+// EquityPoint records the value of the simulated account at the close of a
+// candlestick.
+type EquityPoint struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
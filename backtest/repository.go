@@ -0,0 +1,53 @@
+package backtest
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// GetBacktestByID retrieves a backtest record and its trades by id. The
+// backtest must belong to the specified user.
+func GetBacktestByID(ctx context.Context, db *gorm.DB, userID,
+	id uint) (*Backtest, error) {
+
+	var item Backtest
+
+	if err := db.Preload("Trades").Model(&Backtest{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListBacktestByUserID retrieves all backtest records associated with the
+// supplied user id. Trades are not loaded.
+func ListBacktestByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Backtest, error) {
+
+	var items []*Backtest
+
+	if err := db.Model(&Backtest{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveBacktest inserts or updates the supplied backtest record along with its
+// trades.
+func SaveBacktest(ctx context.Context, db *gorm.DB, item *Backtest) error {
+	return db.Save(item).Error
+}
+
+// DeleteBacktest deletes the supplied backtest record.
+func DeleteBacktest(ctx context.Context, db *gorm.DB, item *Backtest) error {
+	return db.Delete(item).Error
+}
@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/broker"
+	"mojito/market"
+	"mojito/market/feed"
+	"mojito/market/indicator"
+	"mojito/paper"
+	"mojito/strategy"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// runner evaluates the strategy of a running bot against each candlestick
+// committed by the market data feed.
+type runner struct {
+	mutex    *sync.Mutex
+	bot      Bot
+	strategy strategy.Strategy
+	paused   bool
+	cancel   func()
+}
+
+// runners keeps track of the runner for each running or paused bot by bot id.
+var runners = struct {
+	mutex *sync.Mutex
+	items map[uint]*runner
+}{
+	mutex: &sync.Mutex{},
+	items: map[uint]*runner{},
+}
+
+// NewStrategy creates the strategy configured for the supplied bot.
+func NewStrategy(item *Bot) (strategy.Strategy, error) {
+
+	params := indicator.Params{}
+
+	if item.Parameters != "" {
+		if err := json.Unmarshal([]byte(item.Parameters), &params); err != nil {
+			return nil, err
+		}
+	}
+
+	return strategy.New(item.Strategy, params)
+
+}
+
+// Start begins running the supplied bot. If the bot is paused it resumes
+// acting on signals.
+func Start(ctx context.Context, db *gorm.DB, item *Bot) error {
+	return run(ctx, db, item, StatusRunning)
+}
+
+// Pause stops the supplied bot from acting on signals. A paused bot continues
+// to evaluate its strategy so it is ready to act as soon as it is started.
+func Pause(ctx context.Context, db *gorm.DB, item *Bot) error {
+	return run(ctx, db, item, StatusPaused)
+}
+
+// Stop stops running the supplied bot.
+func Stop(ctx context.Context, db *gorm.DB, item *Bot) error {
+
+	runners.mutex.Lock()
+	r, ok := runners.items[item.ID]
+	delete(runners.items, item.ID)
+	runners.mutex.Unlock()
+
+	if ok {
+		r.cancel()
+	}
+
+	item.Status = StatusStopped
+	item.Error = ""
+
+	return UpdateBotStatus(ctx, db, item.ID, item.Status, item.Error)
+
+}
+
+// resume restores a bot to the status it had when the server stopped.
+func resume(ctx context.Context, db *gorm.DB, item *Bot) error {
+	return run(ctx, db, item, item.Status)
+}
+
+// run ensures a runner exists for the supplied bot and sets whether the bot is
+// paused. If the bot cannot be run it is placed in the errored status.
+func run(ctx context.Context, db *gorm.DB, item *Bot, status Status) error {
+
+	runners.mutex.Lock()
+	defer runners.mutex.Unlock()
+
+	r, ok := runners.items[item.ID]
+	if !ok {
+
+		var err error
+		r, err = newRunner(ctx, db, item)
+		if err != nil {
+			item.Status = StatusErrored
+			item.Error = err.Error()
+			if err := UpdateBotStatus(ctx, db, item.ID, item.Status,
+				item.Error); err != nil {
+				logrus.Error(err)
+			}
+			return err
+		}
+
+		runners.items[item.ID] = r
+
+	}
+
+	r.mutex.Lock()
+	r.paused = status == StatusPaused
+	r.mutex.Unlock()
+
+	item.Status = status
+	item.Error = ""
+
+	return UpdateBotStatus(ctx, db, item.ID, item.Status, item.Error)
+
+}
+
+// newRunner creates the strategy for the supplied bot, primes it with recent
+// price data, and subscribes it to the market data feed.
+func newRunner(ctx context.Context, db *gorm.DB, item *Bot) (*runner, error) {
+
+	s, err := NewStrategy(item)
+	if err != nil {
+		return nil, err
+	}
+
+	exchange := strings.ToUpper(item.Exchange)
+	ticker := strings.ToUpper(item.Ticker)
+
+	// replay recent candlesticks so the strategy can produce signals as soon
+	// as the next candlestick is committed
+	end := time.Now()
+	start := end.Add(-time.Duration(s.Lookback()+1) *
+		market.Resolution1Minute.Duration())
+
+	candlesticks, err := market.ListByTicker(ctx, db, exchange, ticker,
+		market.Resolution1Minute, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candlestick := range candlesticks {
+		s.Update(candlestick)
+	}
+
+	// subscribe to new candlesticks
+	channel, cancel, err := feed.Subscribe(exchange, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &runner{
+		mutex:    &sync.Mutex{},
+		bot:      *item,
+		strategy: s,
+		cancel:   cancel,
+	}
+
+	go func() {
+		for candlestick := range channel {
+			r.handle(db, candlestick)
+		}
+	}()
+
+	return r, nil
+
+}
+
+// handle evaluates the bot strategy against a new candlestick and acts on any
+// resulting signal.
+func (r *runner) handle(db *gorm.DB, candlestick market.Candlestick) {
+
+	signal := r.strategy.Update(candlestick)
+
+	r.mutex.Lock()
+	paused := r.paused
+	r.mutex.Unlock()
+
+	if signal == strategy.SignalHold || paused {
+		return
+	}
+
+	logrus.Infof("bot %d signaled %s for %s %s at %.2f", r.bot.ID, signal,
+		r.bot.Exchange, r.bot.Ticker, candlestick.Close)
+
+	ctx := context.Background()
+
+	if err := UpdateBotSignal(ctx, db, r.bot.ID, signal,
+		candlestick.CreatedAt); err != nil {
+		logrus.Error(err)
+	}
+
+	if err := r.execute(ctx, db, signal, candlestick.Close); err != nil {
+		logrus.Errorf("bot %d failed to execute %s: %v", r.bot.ID, signal, err)
+	}
+
+}
+
+// execute places an order acting on the supplied signal. A buy signal spends
+// the bot's order size on the security at the best ask if the order book is
+// available, a sell signal closes the bot's position. Paper bots trade through
+// the paper trading exchange, live bots trade through the user's brokerage
+// account.
+func (r *runner) execute(ctx context.Context, db *gorm.DB,
+	signal strategy.Signal, price float64) error {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	order := &broker.Order{
+		Exchange: r.bot.Exchange,
+		Ticker:   r.bot.Ticker,
+		Type:     broker.OrderTypeMarket,
+	}
+
+	switch signal {
+	case strategy.SignalBuy:
+		price = askPrice(r.bot.Exchange, r.bot.Ticker, price)
+		if r.bot.Position > 0 || price <= 0 {
+			return nil
+		}
+		order.Side = broker.SideBuy
+		order.Quantity = r.bot.OrderSize / price
+		if r.bot.Mode == ModePaper {
+			// leave room for the fee so the order is not rejected
+			order.Quantity /= 1 + paper.FeeRate()
+		}
+	case strategy.SignalSell:
+		if r.bot.Position <= 0 {
+			return nil
+		}
+		order.Side = broker.SideSell
+		order.Quantity = r.bot.Position
+	default:
+		return nil
+	}
+
+	exchange, err := r.exchange(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if err := exchange.PlaceOrder(ctx, order); err != nil {
+		return err
+	}
+
+	if order.Status == broker.OrderStatusRejected ||
+		order.Status == broker.OrderStatusCancelled {
+		return fmt.Errorf("order %s was %s", order.ID, order.Status)
+	}
+
+	// track the quantity held so the next sell closes the position, market
+	// orders that have not been reported as filled yet are assumed to fill
+	// in full
+	quantity := order.FilledQuantity
+	if quantity == 0 {
+		quantity = order.Quantity
+	}
+
+	if order.Side == broker.SideBuy {
+		r.bot.Position += quantity
+	} else {
+		r.bot.Position = math.Max(r.bot.Position-quantity, 0)
+	}
+
+	return UpdateBotPosition(ctx, db, r.bot.ID, r.bot.Position)
+
+}
+
+// askPrice gets the price a buy order for the specified security is expected to
+// fill at. Buys are sized against the best ask when the market data feed tracks
+// the order book of the security, otherwise against the supplied close.
+func askPrice(exchange, ticker string, close float64) float64 {
+
+	book, err := feed.GetOrderBook(exchange, ticker)
+	if err != nil || !book.Ready() {
+		return close
+	}
+
+	if ask := book.BestAsk(); ask > 0 {
+		return ask
+	}
+
+	return close
+
+}
+
+// exchange creates the exchange the bot places orders through.
+func (r *runner) exchange(ctx context.Context,
+	db *gorm.DB) (broker.Exchange, error) {
+
+	if r.bot.Mode == ModePaper {
+		return broker.Paper(db, r.bot.UserID), nil
+	}
+
+	platform, err := broker.PlatformForExchange(r.bot.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	return broker.Live(ctx, db, r.bot.UserID, platform)
+
+}
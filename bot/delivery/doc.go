@@ -0,0 +1,2 @@
+// Package delivery exposes an API for managing trading bots.
+package delivery
@@ -0,0 +1,382 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mojito/bot"
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/server"
+	"mojito/strategy"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the bot API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(createBotEndpoint, user.JWTAuthMiddleware(),
+		createBot)
+	server.Router().GET(listBotEndpoint, user.JWTAuthMiddleware(), listBot)
+	server.Router().GET(getBotEndpoint, user.JWTAuthMiddleware(), getBot)
+	server.Router().PUT(updateBotEndpoint, user.JWTAuthMiddleware(),
+		updateBot)
+	server.Router().DELETE(deleteBotEndpoint, user.JWTAuthMiddleware(),
+		deleteBot)
+	server.Router().POST(startBotEndpoint, user.JWTAuthMiddleware(), startBot)
+	server.Router().POST(stopBotEndpoint, user.JWTAuthMiddleware(), stopBot)
+	server.Router().POST(pauseBotEndpoint, user.JWTAuthMiddleware(), pauseBot)
+
+}
+
+const (
+	// createBotEndpoint the API endpoint used to create a new bot.
+	createBotEndpoint = "/bot"
+	// listBotEndpoint the API endpoint used to retrieve the logged in user's
+	// bots.
+	listBotEndpoint = "/bot"
+	// getBotEndpoint the API endpoint used to retrieve a bot.
+	getBotEndpoint = "/bot/:id"
+	// updateBotEndpoint the API endpoint used to update a bot.
+	updateBotEndpoint = "/bot/:id"
+	// deleteBotEndpoint the API endpoint used to delete a bot.
+	deleteBotEndpoint = "/bot/:id"
+	// startBotEndpoint the API endpoint used to start running a bot or resume
+	// a paused bot.
+	startBotEndpoint = "/bot/:id/start"
+	// stopBotEndpoint the API endpoint used to stop running a bot.
+	stopBotEndpoint = "/bot/:id/stop"
+	// pauseBotEndpoint the API endpoint used to stop a bot from acting on
+	// signals without stopping it.
+	pauseBotEndpoint = "/bot/:id/pause"
+	// botNotFound is an error message returned when the requested bot does not
+	// exist or belongs to another user.
+	botNotFound = "bot not found"
+)
+
+// createBot creates a new bot for the logged in user. New bots are stopped.
+func createBot(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	item := &bot.Bot{
+		UserID: u.ID,
+		Status: bot.StatusStopped,
+	}
+
+	if ok := readBotRequest(c, item); !ok {
+		return
+	}
+
+	if err := bot.SaveBot(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the new bot
+	c.JSON(http.StatusOK, formatBot(item))
+
+}
+
+// listBot retrieves the logged in user's bots.
+func listBot(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve bots
+	items, err := bot.ListBotByUserID(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	response := []botResponse{}
+	for _, item := range items {
+		response = append(response, formatBot(item))
+	}
+
+	// respond with bots
+	c.JSON(http.StatusOK, response)
+
+}
+
+// getBot retrieves one of the logged in user's bots.
+func getBot(c *gin.Context) {
+
+	item, ok := readBot(c)
+	if !ok {
+		return
+	}
+
+	// respond with the bot
+	c.JSON(http.StatusOK, formatBot(item))
+
+}
+
+// updateBot updates the configuration of one of the logged in user's bots. The
+// bot must be stopped.
+func updateBot(c *gin.Context) {
+
+	item, ok := readBot(c)
+	if !ok {
+		return
+	}
+
+	if item.Status == bot.StatusRunning || item.Status == bot.StatusPaused {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "bot must be stopped before it can be updated",
+		})
+		return
+	}
+
+	if ok := readBotRequest(c, item); !ok {
+		return
+	}
+
+	if err := bot.SaveBot(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the updated bot
+	c.JSON(http.StatusOK, formatBot(item))
+
+}
+
+// deleteBot stops and deletes one of the logged in user's bots.
+func deleteBot(c *gin.Context) {
+
+	item, ok := readBot(c)
+	if !ok {
+		return
+	}
+
+	if err := bot.Stop(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	if err := bot.DeleteBot(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the bot was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// startBot starts running one of the logged in user's bots.
+func startBot(c *gin.Context) {
+	changeBotStatus(c, bot.Start)
+}
+
+// stopBot stops running one of the logged in user's bots.
+func stopBot(c *gin.Context) {
+	changeBotStatus(c, bot.Stop)
+}
+
+// pauseBot pauses one of the logged in user's bots.
+func pauseBot(c *gin.Context) {
+	changeBotStatus(c, bot.Pause)
+}
+
+// changeBotStatus applies the supplied lifecycle function to the bot
+// referenced by the request path and responds with the updated bot.
+func changeBotStatus(c *gin.Context,
+	change func(context.Context, *gorm.DB, *bot.Bot) error) {
+
+	item, ok := readBot(c)
+	if !ok {
+		return
+	}
+
+	if err := change(c, data.DB(), item); err != nil {
+		logrus.Warn(err)
+		if item.Status == bot.StatusErrored {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: item.Error,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the updated bot
+	c.JSON(http.StatusOK, formatBot(item))
+
+}
+
+// readBot retrieves the bot referenced by the request path for the logged in
+// user. If the bot cannot be retrieved an error response is written and false
+// is returned.
+func readBot(c *gin.Context) (*bot.Bot, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: botNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the bot
+	item, err := bot.GetBotByID(c, data.DB(), u.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: botNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// readBotRequest reads and validates the bot configuration from the request
+// body and applies it to the supplied bot. If the request is invalid an error
+// response is written and false is returned.
+func readBotRequest(c *gin.Context, item *bot.Bot) bool {
+
+	var req saveBotRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return false
+	}
+
+	// validate request parameters
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "name is required",
+		})
+		return false
+	}
+
+	if req.Exchange == "" || req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker are required",
+		})
+		return false
+	}
+
+	mode := bot.Mode(strings.ToLower(req.Mode))
+	if mode != bot.ModePaper && mode != bot.ModeLive {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid mode, expected paper or live",
+		})
+		return false
+	}
+
+	if req.OrderSize <= 0 {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "order size must be greater than zero",
+		})
+		return false
+	}
+
+	if _, err := strategy.New(strategy.Type(req.Strategy),
+		req.Parameters); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return false
+	}
+
+	parameters, err := json.Marshal(req.Parameters)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	item.Name = req.Name
+	item.Exchange = strings.ToUpper(req.Exchange)
+	item.Ticker = strings.ToUpper(req.Ticker)
+	item.Strategy = strategy.Type(strings.ToLower(req.Strategy))
+	item.Parameters = string(parameters)
+	item.Mode = mode
+	item.OrderSize = req.OrderSize
+
+	return true
+
+}
+
+// formatBot decodes the stored strategy parameters of a bot for use in an API
+// response.
+func formatBot(item *bot.Bot) botResponse {
+
+	response := botResponse{Bot: *item}
+
+	if err := json.Unmarshal([]byte(item.Parameters),
+		&response.Parameters); err != nil {
+		logrus.Error(err)
+	}
+
+	return response
+
+}
@@ -0,0 +1,24 @@
+package delivery
+
+import (
+	"mojito/bot"
+	"mojito/market/indicator"
+)
+
+// saveBotRequest is used to read a request to the create and update bot
+// endpoints.
+type saveBotRequest struct {
+	Name       string           `json:"name"`
+	Exchange   string           `json:"exchange"`
+	Ticker     string           `json:"ticker"`
+	Strategy   string           `json:"strategy"`
+	Parameters indicator.Params `json:"parameters"`
+	Mode       string           `json:"mode"`
+	OrderSize  float64          `json:"order_size"`
+}
+
+// botResponse is used to format a bot record in API responses.
+type botResponse struct {
+	bot.Bot
+	Parameters indicator.Params `json:"parameters"`
+}
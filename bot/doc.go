@@ -0,0 +1,8 @@
+// Package bot provides trading bots that run a strategy against live market
+// data. Each bot belongs to a user and reacts to every candlestick committed by
+// the market data feed for its exchange and ticker. Bots that were running when
+// the server stopped are resumed when it starts. Bots in paper mode place their
+// orders through the paper trading exchange, bots in live mode place their
+// orders through the user's brokerage account. Buy orders are sized against
+// the best ask when the market data feed tracks the order book of the security.
+package bot
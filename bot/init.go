@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"context"
+
+	"mojito/data"
+
+	"github.com/sirupsen/logrus"
+)
+
+// init migrates the package model.
+func init() {
+
+	data.DB().AutoMigrate(
+		Bot{},
+	)
+
+}
+
+// Resume resumes any bots that were running when the server stopped. Resume
+// should be called once the market data feeds are started.
+func Resume() {
+
+	// retrieve bots that were running or paused
+	items, err := ListBotByStatus(context.Background(), data.DB(),
+		StatusRunning, StatusPaused)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// resume each bot in its previous state
+	for _, item := range items {
+		if err := resume(context.Background(), data.DB(), item); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"time"
+
+	"mojito/strategy"
+
+	"gorm.io/gorm"
+)
+
+// Mode determines whether a bot trades with real or simulated funds.
+type Mode string
+
+// Define bot modes.
+const (
+	ModePaper Mode = "paper"
+	ModeLive  Mode = "live"
+)
+
+// Status describes the lifecycle state of a bot.
+type Status string
+
+// Define bot statuses.
+const (
+	StatusStopped Status = "stopped"
+	StatusRunning Status = "running"
+	StatusPaused  Status = "paused"
+	StatusErrored Status = "errored"
+)
+
+/* Data Types */
+
+// Bot stores the configuration and state of a trading bot.
+type Bot struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	Name       string        `json:"name"`
+	Exchange   string        `json:"exchange"`
+	Ticker     string        `json:"ticker"`
+	Strategy   strategy.Type `json:"strategy"`
+	Parameters string        `gorm:"type:text" json:"-"` // JSON encoded strategy parameters
+	Mode       Mode          `json:"mode"`
+	OrderSize  float64       `json:"order_size"` // the amount of cash spent on each buy order
+
+	Position float64 `json:"position"` // the quantity of the security currently held by the bot
+
+	Status Status `gorm:"index" json:"status"`
+	Error  string `json:"error"` // records why the bot entered the errored status
+
+	LastSignal   strategy.Signal `json:"last_signal"`    // the last buy or sell signal produced by the strategy
+	LastSignalAt *time.Time      `json:"last_signal_at"` // records when the last signal was produced
+}
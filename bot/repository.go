@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"mojito/strategy"
+
+	"gorm.io/gorm"
+)
+
+// GetBotByID retrieves a bot record by id. The bot must belong to the
+// specified user.
+func GetBotByID(ctx context.Context, db *gorm.DB, userID,
+	id uint) (*Bot, error) {
+
+	var item Bot
+
+	if err := db.Model(&Bot{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListBotByUserID retrieves all bot records associated with the supplied user
+// id.
+func ListBotByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Bot, error) {
+
+	var items []*Bot
+
+	if err := db.Model(&Bot{}).
+		Where("user_id = ?", userID).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// ListBotByStatus retrieves all bot records with any of the supplied statuses.
+func ListBotByStatus(ctx context.Context, db *gorm.DB,
+	statuses ...Status) ([]*Bot, error) {
+
+	var items []*Bot
+
+	if err := db.Model(&Bot{}).
+		Where("status IN ?", statuses).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveBot inserts or updates the supplied bot record.
+func SaveBot(ctx context.Context, db *gorm.DB, item *Bot) error {
+	return db.Save(item).Error
+}
+
+// DeleteBot deletes the supplied bot record.
+func DeleteBot(ctx context.Context, db *gorm.DB, item *Bot) error {
+	return db.Delete(item).Error
+}
+
+// UpdateBotStatus sets the status of the bot with the supplied id along with
+// the error that caused the status, if any.
+func UpdateBotStatus(ctx context.Context, db *gorm.DB, id uint, status Status,
+	errorMessage string) error {
+	return db.Model(&Bot{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": status,
+			"error":  errorMessage,
+		}).Error
+}
+
+// UpdateBotSignal records the last signal produced by the bot with the supplied
+// id.
+func UpdateBotSignal(ctx context.Context, db *gorm.DB, id uint,
+	signal strategy.Signal, at time.Time) error {
+	return db.Model(&Bot{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_signal":    signal,
+			"last_signal_at": at,
+		}).Error
+}
+
+// UpdateBotPosition sets the quantity of the security held by the bot with the
+// supplied id.
+func UpdateBotPosition(ctx context.Context, db *gorm.DB, id uint,
+	position float64) error {
+	return db.Model(&Bot{}).
+		Where("id = ?", id).
+		Update("position", position).Error
+}
@@ -0,0 +1,198 @@
+package broker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// alpacaExchange places orders through the Alpaca trading REST API.
+type alpacaExchange struct {
+	apiKey    string
+	secretKey string
+}
+
+// alpacaOrderRequest is the payload used to place an order through the Alpaca
+// API.
+type alpacaOrderRequest struct {
+	Symbol      string `json:"symbol"`
+	Qty         string `json:"qty"`
+	Side        string `json:"side"`
+	Type        string `json:"type"`
+	TimeInForce string `json:"time_in_force"`
+	LimitPrice  string `json:"limit_price,omitempty"`
+	StopPrice   string `json:"stop_price,omitempty"`
+}
+
+// alpacaOrder is used to read orders from the Alpaca API.
+type alpacaOrder struct {
+	alpacaOrderRequest
+	ID             string    `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Status         string    `json:"status"`
+	FilledQty      string    `json:"filled_qty"`
+	FilledAvgPrice string    `json:"filled_avg_price"`
+}
+
+// alpacaAccount is used to read the cash balance from the Alpaca API.
+type alpacaAccount struct {
+	Currency                 string `json:"currency"`
+	Cash                     string `json:"cash"`
+	NonMarginableBuyingPower string `json:"non_marginable_buying_power"`
+}
+
+// alpacaPosition is used to read the quantity of a held security from the
+// Alpaca API.
+type alpacaPosition struct {
+	Symbol       string `json:"symbol"`
+	Qty          string `json:"qty"`
+	QtyAvailable string `json:"qty_available"`
+}
+
+// newAlpacaExchange creates an exchange that authenticates requests with the
+// supplied Alpaca API credentials.
+func newAlpacaExchange(apiKey, secretKey string) Exchange {
+	return &alpacaExchange{
+		apiKey:    apiKey,
+		secretKey: secretKey,
+	}
+}
+
+func (a *alpacaExchange) PlaceOrder(ctx context.Context, order *Order) error {
+
+	req := alpacaOrderRequest{
+		Symbol:      strings.ToUpper(order.Ticker),
+		Qty:         formatFloat(order.Quantity),
+		Side:        string(order.Side),
+		Type:        string(order.Type),
+		TimeInForce: "day",
+	}
+
+	switch order.Type {
+	case OrderTypeLimit:
+		req.LimitPrice = formatFloat(order.LimitPrice)
+		req.TimeInForce = "gtc"
+	case OrderTypeStop:
+		req.StopPrice = formatFloat(order.StopPrice)
+		req.TimeInForce = "gtc"
+	}
+
+	var resp alpacaOrder
+	if _, err := doRequest(ctx, a.sign, http.MethodPost,
+		alpacaAPIURL+"/v2/orders", req, &resp); err != nil {
+		return err
+	}
+
+	exchange := order.Exchange
+	a.formatOrder(&resp, order)
+	order.Exchange = exchange
+
+	return nil
+
+}
+
+func (a *alpacaExchange) CancelOrder(ctx context.Context, id string) error {
+
+	status, err := doRequest(ctx, a.sign, http.MethodDelete,
+		alpacaAPIURL+"/v2/orders/"+url.PathEscape(id), nil, nil)
+	if status == http.StatusNotFound {
+		return ErrOrderNotFound
+	}
+
+	return err
+
+}
+
+func (a *alpacaExchange) GetOrder(ctx context.Context,
+	id string) (*Order, error) {
+
+	var resp alpacaOrder
+	status, err := doRequest(ctx, a.sign, http.MethodGet,
+		alpacaAPIURL+"/v2/orders/"+url.PathEscape(id), nil, &resp)
+	if status == http.StatusNotFound {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var order Order
+	a.formatOrder(&resp, &order)
+	return &order, nil
+
+}
+
+func (a *alpacaExchange) ListBalances(
+	ctx context.Context) ([]Balance, error) {
+
+	var account alpacaAccount
+	if _, err := doRequest(ctx, a.sign, http.MethodGet,
+		alpacaAPIURL+"/v2/account", nil, &account); err != nil {
+		return nil, err
+	}
+
+	var positions []alpacaPosition
+	if _, err := doRequest(ctx, a.sign, http.MethodGet,
+		alpacaAPIURL+"/v2/positions", nil, &positions); err != nil {
+		return nil, err
+	}
+
+	balances := []Balance{
+		{
+			Asset:     strings.ToUpper(account.Currency),
+			Amount:    parseFloat(account.Cash),
+			Available: parseFloat(account.NonMarginableBuyingPower),
+		},
+	}
+
+	for _, position := range positions {
+		available := position.QtyAvailable
+		if available == "" {
+			available = position.Qty
+		}
+		balances = append(balances, Balance{
+			Asset:     position.Symbol,
+			Amount:    parseFloat(position.Qty),
+			Available: parseFloat(available),
+		})
+	}
+
+	return balances, nil
+
+}
+
+// sign adds the Alpaca authentication headers to a request.
+func (a *alpacaExchange) sign(req *http.Request, body []byte) error {
+	req.Header.Set("APCA-API-KEY-ID", a.apiKey)
+	req.Header.Set("APCA-API-SECRET-KEY", a.secretKey)
+	return nil
+}
+
+// formatOrder converts an order returned by the Alpaca API to a brokerage
+// order. Alpaca routes orders itself so the order does not record an exchange.
+func (a *alpacaExchange) formatOrder(resp *alpacaOrder, order *Order) {
+
+	order.ID = resp.ID
+	order.CreatedAt = resp.CreatedAt
+	order.Ticker = resp.Symbol
+	order.Side = Side(resp.Side)
+	order.Type = OrderType(resp.Type)
+	order.Quantity = parseFloat(resp.Qty)
+	order.LimitPrice = parseFloat(resp.LimitPrice)
+	order.StopPrice = parseFloat(resp.StopPrice)
+	order.FilledQuantity = parseFloat(resp.FilledQty)
+	order.FilledPrice = parseFloat(resp.FilledAvgPrice)
+
+	switch resp.Status {
+	case "filled":
+		order.Status = OrderStatusFilled
+	case "canceled", "expired", "done_for_day", "replaced":
+		order.Status = OrderStatusCancelled
+	case "rejected", "suspended":
+		order.Status = OrderStatusRejected
+	default:
+		order.Status = OrderStatusOpen
+	}
+
+}
@@ -0,0 +1,158 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"mojito/market"
+	"mojito/paper"
+	"mojito/user"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnsupportedExchange is returned when an order is placed on an exchange
+// that no brokerage integration supports.
+var ErrUnsupportedExchange = errors.New("exchange does not support trading")
+
+// ErrMissingCredentials is returned when a user has not stored the API
+// credentials required to trade through a brokerage.
+var ErrMissingCredentials = errors.New("brokerage credentials are not configured")
+
+// ErrOrderNotFound is returned when the requested order does not exist.
+var ErrOrderNotFound = errors.New("order not found")
+
+// Side determines whether an order buys or sells. Brokerage orders share the
+// sides, types, and statuses of paper trading orders so either can be placed
+// through the same interface.
+type Side = paper.Side
+
+// Define order sides.
+const (
+	SideBuy  = paper.SideBuy
+	SideSell = paper.SideSell
+)
+
+// OrderType determines how the price of an order is chosen.
+type OrderType = paper.OrderType
+
+// Define order types.
+const (
+	OrderTypeMarket = paper.OrderTypeMarket
+	OrderTypeLimit  = paper.OrderTypeLimit
+	OrderTypeStop   = paper.OrderTypeStop
+)
+
+// OrderStatus describes the state of an order.
+type OrderStatus = paper.OrderStatus
+
+// Define order statuses.
+const (
+	OrderStatusOpen      = paper.OrderStatusOpen
+	OrderStatusFilled    = paper.OrderStatusFilled
+	OrderStatusCancelled = paper.OrderStatusCancelled
+	OrderStatusRejected  = paper.OrderStatusRejected
+)
+
+// Order describes an order placed through a brokerage.
+type Order struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Exchange   string      `json:"exchange"`
+	Ticker     string      `json:"ticker"`
+	Side       Side        `json:"side"`
+	Type       OrderType   `json:"type"`
+	Quantity   float64     `json:"quantity"`
+	LimitPrice float64     `json:"limit_price"`
+	StopPrice  float64     `json:"stop_price"`
+	Status     OrderStatus `json:"status"`
+
+	FilledQuantity float64 `json:"filled_quantity"`
+	FilledPrice    float64 `json:"filled_price"` // the average price of all fills
+}
+
+// Balance describes the amount of an asset held with a brokerage.
+type Balance struct {
+	Asset     string  `json:"asset"`
+	Amount    float64 `json:"amount"`
+	Available float64 `json:"available"` // the portion of the amount not reserved by open orders
+}
+
+// Exchange places and tracks orders through a brokerage account.
+type Exchange interface {
+	// PlaceOrder submits the supplied order, setting its id and status.
+	PlaceOrder(ctx context.Context, order *Order) error
+	// CancelOrder cancels the open order with the supplied id.
+	CancelOrder(ctx context.Context, id string) error
+	// GetOrder retrieves the order with the supplied id.
+	GetOrder(ctx context.Context, id string) (*Order, error)
+	// ListBalances retrieves the balance of each asset held in the account.
+	ListBalances(ctx context.Context) ([]Balance, error)
+}
+
+// platforms maps each exchange to the platform that places orders on it.
+var platforms = map[market.ExchangeKey]market.PlatformKey{
+	market.ExchangeCoinbase:     market.PlatformCoinbase,
+	market.ExchangeIEX:          market.PlatformAlpaca,
+	market.ExchangeNASDAQBX:     market.PlatformAlpaca,
+	market.ExchangeNASDAQPSX:    market.PlatformAlpaca,
+	market.ExchangeNYSENational: market.PlatformAlpaca,
+	market.ExchangeNYSEChicago:  market.PlatformAlpaca,
+}
+
+// PlatformForExchange gets the platform used to place orders on the specified
+// exchange.
+func PlatformForExchange(exchange string) (market.PlatformKey, error) {
+
+	platform, ok := platforms[market.ExchangeKey(strings.ToUpper(exchange))]
+	if !ok {
+		return "", ErrUnsupportedExchange
+	}
+
+	return platform, nil
+
+}
+
+// Live creates an exchange that trades through the specified platform using
+// the API credentials stored in the user's settings. Returns
+// ErrMissingCredentials unless every credential the platform requires is
+// stored, including the passphrase for Coinbase.
+func Live(ctx context.Context, db *gorm.DB, userID uint,
+	platform market.PlatformKey) (Exchange, error) {
+
+	settings, err := user.GetUserSettingsByUserID(ctx, db, userID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrMissingCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch platform {
+	case market.PlatformCoinbase:
+		if settings.CoinbaseAPIKey == "" || settings.CoinbaseSignature == "" ||
+			settings.CoinbasePassphrase == "" {
+			return nil, ErrMissingCredentials
+		}
+		return newCoinbaseExchange(settings.CoinbaseAPIKey,
+			settings.CoinbaseSignature, settings.CoinbasePassphrase), nil
+	case market.PlatformAlpaca:
+		if settings.AlpacaAPIKey == "" || settings.AlpacaSecretKey == "" {
+			return nil, ErrMissingCredentials
+		}
+		return newAlpacaExchange(settings.AlpacaAPIKey,
+			settings.AlpacaSecretKey), nil
+	}
+
+	return nil, ErrUnsupportedExchange
+
+}
+
+// ValidateOrder checks that the supplied order is well formed. Orders are held
+// to the same rules whether they are placed through a brokerage or through the
+// paper trading exchange.
+func ValidateOrder(order *Order) error {
+	return paper.ValidateOrder(newPaperOrder(0, order))
+}
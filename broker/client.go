@@ -0,0 +1,105 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// coinbaseAPIURL is the base URL of the Coinbase exchange REST API.
+var coinbaseAPIURL string
+
+// alpacaAPIURL is the base URL of the Alpaca trading REST API.
+var alpacaAPIURL string
+
+// client is used to send requests to brokerage APIs.
+var client = &http.Client{Timeout: 15 * time.Second}
+
+// APIError is returned when a brokerage API rejects a request.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("brokerage request failed with status %d: %s",
+		e.StatusCode, e.Message)
+}
+
+// signFunc adds authentication headers to a request. The body is supplied
+// separately as it has already been consumed into the request.
+type signFunc func(req *http.Request, body []byte) error
+
+// doRequest sends a request to a brokerage API and decodes the JSON response
+// into out, if out is not nil. Responses outside of the 2xx range are returned
+// as errors carrying the message supplied by the API.
+func doRequest(ctx context.Context, sign signFunc, method, url string,
+	payload, out interface{}) (int, error) {
+
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url,
+		bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := sign(req, body); err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil ||
+			apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return resp.StatusCode, apiErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return resp.StatusCode, nil
+	}
+
+	return resp.StatusCode, json.Unmarshal(data, out)
+
+}
+
+// parseFloat parses a decimal string returned by a brokerage API, treating an
+// empty string as zero.
+func parseFloat(s string) float64 {
+	value, _ := strconv.ParseFloat(s, 64)
+	return value
+}
+
+// formatFloat formats a decimal for a brokerage API request.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
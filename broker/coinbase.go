@@ -0,0 +1,217 @@
+package broker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"mojito/market"
+)
+
+// coinbaseExchange places orders through the Coinbase exchange REST API.
+type coinbaseExchange struct {
+	apiKey     string
+	secret     string
+	passphrase string
+}
+
+// coinbaseOrderRequest is the payload used to place an order through the
+// Coinbase API.
+type coinbaseOrderRequest struct {
+	ProductID string `json:"product_id"`
+	Side      string `json:"side"`
+	Type      string `json:"type"`
+	Size      string `json:"size"`
+	Price     string `json:"price,omitempty"`
+	Stop      string `json:"stop,omitempty"`
+	StopPrice string `json:"stop_price,omitempty"`
+}
+
+// coinbaseOrder is used to read orders from the Coinbase API.
+type coinbaseOrder struct {
+	coinbaseOrderRequest
+	ID            string    `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	Status        string    `json:"status"`
+	DoneReason    string    `json:"done_reason"`
+	FilledSize    string    `json:"filled_size"`
+	ExecutedValue string    `json:"executed_value"`
+}
+
+// coinbaseAccount is used to read account balances from the Coinbase API.
+type coinbaseAccount struct {
+	Currency  string `json:"currency"`
+	Balance   string `json:"balance"`
+	Available string `json:"available"`
+}
+
+// newCoinbaseExchange creates an exchange that signs requests with the supplied
+// Coinbase API credentials.
+func newCoinbaseExchange(apiKey, secret, passphrase string) Exchange {
+	return &coinbaseExchange{
+		apiKey:     apiKey,
+		secret:     secret,
+		passphrase: passphrase,
+	}
+}
+
+func (c *coinbaseExchange) PlaceOrder(ctx context.Context,
+	order *Order) error {
+
+	// the ticker may name a currency pair such as BTC-EUR
+	base, quote := market.SplitTicker(order.Ticker)
+
+	req := coinbaseOrderRequest{
+		ProductID: base + "-" + quote,
+		Side:      string(order.Side),
+		Type:      string(order.Type),
+		Size:      formatFloat(order.Quantity),
+	}
+
+	switch order.Type {
+	case OrderTypeLimit:
+		req.Price = formatFloat(order.LimitPrice)
+	case OrderTypeStop:
+		// Coinbase places stop orders as limit orders that become active once
+		// the stop price is reached
+		req.Type = string(OrderTypeLimit)
+		req.Price = formatFloat(order.StopPrice)
+		req.StopPrice = formatFloat(order.StopPrice)
+		req.Stop = "loss"
+		if order.Side == SideBuy {
+			req.Stop = "entry"
+		}
+	}
+
+	var resp coinbaseOrder
+	if _, err := doRequest(ctx, c.sign, http.MethodPost,
+		coinbaseAPIURL+"/orders", req, &resp); err != nil {
+		return err
+	}
+
+	c.formatOrder(&resp, order)
+	return nil
+
+}
+
+func (c *coinbaseExchange) CancelOrder(ctx context.Context, id string) error {
+
+	status, err := doRequest(ctx, c.sign, http.MethodDelete,
+		coinbaseAPIURL+"/orders/"+url.PathEscape(id), nil, nil)
+	if status == http.StatusNotFound {
+		return ErrOrderNotFound
+	}
+
+	return err
+
+}
+
+func (c *coinbaseExchange) GetOrder(ctx context.Context,
+	id string) (*Order, error) {
+
+	var resp coinbaseOrder
+	status, err := doRequest(ctx, c.sign, http.MethodGet,
+		coinbaseAPIURL+"/orders/"+url.PathEscape(id), nil, &resp)
+	if status == http.StatusNotFound {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var order Order
+	c.formatOrder(&resp, &order)
+	return &order, nil
+
+}
+
+func (c *coinbaseExchange) ListBalances(
+	ctx context.Context) ([]Balance, error) {
+
+	var accounts []coinbaseAccount
+	if _, err := doRequest(ctx, c.sign, http.MethodGet,
+		coinbaseAPIURL+"/accounts", nil, &accounts); err != nil {
+		return nil, err
+	}
+
+	balances := []Balance{}
+	for _, account := range accounts {
+		balances = append(balances, Balance{
+			Asset:     account.Currency,
+			Amount:    parseFloat(account.Balance),
+			Available: parseFloat(account.Available),
+		})
+	}
+
+	return balances, nil
+
+}
+
+// sign adds the Coinbase authentication headers to a request. The signature
+// is an HMAC-SHA256 of the timestamp, method, path, and body keyed with the
+// base64 decoded API secret.
+func (c *coinbaseExchange) sign(req *http.Request, body []byte) error {
+
+	secret, err := base64.StdEncoding.DecodeString(c.secret)
+	if err != nil {
+		return fmt.Errorf("invalid coinbase secret: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + req.Method + req.URL.RequestURI() +
+		string(body)))
+
+	req.Header.Set("CB-ACCESS-KEY", c.apiKey)
+	req.Header.Set("CB-ACCESS-SIGN",
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("CB-ACCESS-TIMESTAMP", timestamp)
+	req.Header.Set("CB-ACCESS-PASSPHRASE", c.passphrase)
+
+	return nil
+
+}
+
+// formatOrder converts an order returned by the Coinbase API to a brokerage
+// order.
+func (c *coinbaseExchange) formatOrder(resp *coinbaseOrder, order *Order) {
+
+	order.ID = resp.ID
+	order.CreatedAt = resp.CreatedAt
+	order.Exchange = string(market.ExchangeCoinbase)
+	order.Ticker = market.FormatTicker(market.SplitTicker(resp.ProductID))
+	order.Side = Side(resp.Side)
+	order.Type = OrderType(resp.Type)
+	order.Quantity = parseFloat(resp.Size)
+	order.FilledQuantity = parseFloat(resp.FilledSize)
+
+	if resp.Stop != "" {
+		order.Type = OrderTypeStop
+		order.StopPrice = parseFloat(resp.StopPrice)
+	} else if order.Type == OrderTypeLimit {
+		order.LimitPrice = parseFloat(resp.Price)
+	}
+
+	if order.FilledQuantity > 0 {
+		order.FilledPrice = parseFloat(resp.ExecutedValue) /
+			order.FilledQuantity
+	}
+
+	switch {
+	case resp.Status != "done" && resp.Status != "rejected":
+		order.Status = OrderStatusOpen
+	case resp.Status == "rejected":
+		order.Status = OrderStatusRejected
+	case resp.DoneReason == "canceled":
+		order.Status = OrderStatusCancelled
+	default:
+		order.Status = OrderStatusFilled
+	}
+
+}
@@ -0,0 +1,110 @@
+package broker
+
+import (
+	"context"
+	"errors"
+
+	"mojito/market"
+	"mojito/user"
+
+	"gorm.io/gorm"
+)
+
+// Credentials stores the API credentials used to trade through a brokerage.
+// The passphrase is only used by Coinbase.
+type Credentials struct {
+	APIKey     string
+	Secret     string
+	Passphrase string
+}
+
+// HasCredentials checks whether the user has stored API credentials for the
+// specified platform.
+func HasCredentials(ctx context.Context, db *gorm.DB, userID uint,
+	platform market.PlatformKey) (bool, error) {
+
+	if err := checkPlatform(platform); err != nil {
+		return false, err
+	}
+
+	_, err := Live(ctx, db, userID, platform)
+	if err == ErrMissingCredentials {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+
+}
+
+// SaveCredentials stores the API credentials used to trade through the
+// specified platform in the user's settings, replacing any stored before.
+func SaveCredentials(ctx context.Context, db *gorm.DB, userID uint,
+	platform market.PlatformKey, credentials Credentials) error {
+
+	if err := checkPlatform(platform); err != nil {
+		return err
+	}
+
+	if credentials.APIKey == "" || credentials.Secret == "" {
+		return errors.New("api key and secret are required")
+	}
+
+	if platform == market.PlatformCoinbase && credentials.Passphrase == "" {
+		return errors.New("passphrase is required")
+	}
+
+	return updateCredentials(ctx, db, userID, platform, credentials)
+
+}
+
+// DeleteCredentials removes the API credentials used to trade through the
+// specified platform from the user's settings.
+func DeleteCredentials(ctx context.Context, db *gorm.DB, userID uint,
+	platform market.PlatformKey) error {
+
+	if err := checkPlatform(platform); err != nil {
+		return err
+	}
+
+	return updateCredentials(ctx, db, userID, platform, Credentials{})
+
+}
+
+// updateCredentials sets the API credentials for the specified platform in the
+// user's settings, creating the settings if the user has none.
+func updateCredentials(ctx context.Context, db *gorm.DB, userID uint,
+	platform market.PlatformKey, credentials Credentials) error {
+
+	settings, err := user.GetUserSettingsByUserID(ctx, db, userID)
+	if err == gorm.ErrRecordNotFound {
+		settings = &user.UserSettings{UserID: userID}
+	} else if err != nil {
+		return err
+	}
+
+	switch platform {
+	case market.PlatformCoinbase:
+		settings.CoinbaseAPIKey = credentials.APIKey
+		settings.CoinbaseSignature = credentials.Secret
+		settings.CoinbasePassphrase = credentials.Passphrase
+	case market.PlatformAlpaca:
+		settings.AlpacaAPIKey = credentials.APIKey
+		settings.AlpacaSecretKey = credentials.Secret
+	}
+
+	return user.SaveUserSettings(ctx, db, settings)
+
+}
+
+// checkPlatform checks that orders can be placed through the specified
+// platform.
+func checkPlatform(platform market.PlatformKey) error {
+	for _, item := range platforms {
+		if item == platform {
+			return nil
+		}
+	}
+	return ErrUnsupportedExchange
+}
@@ -0,0 +1,3 @@
+// Package delivery exposes an API for trading through a user's brokerage
+// accounts and for managing the API credentials used to access them.
+package delivery
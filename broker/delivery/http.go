@@ -0,0 +1,338 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+
+	"mojito/broker"
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// init registers the brokerage API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(placeOrderEndpoint, user.JWTAuthMiddleware(),
+		placeOrder)
+	server.Router().GET(getOrderEndpoint, user.JWTAuthMiddleware(), getOrder)
+	server.Router().DELETE(cancelOrderEndpoint, user.JWTAuthMiddleware(),
+		cancelOrder)
+	server.Router().GET(listBalanceEndpoint, user.JWTAuthMiddleware(),
+		listBalance)
+	server.Router().GET(getCredentialsEndpoint, user.JWTAuthMiddleware(),
+		getCredentials)
+	server.Router().PUT(saveCredentialsEndpoint, user.JWTAuthMiddleware(),
+		saveCredentials)
+	server.Router().DELETE(deleteCredentialsEndpoint, user.JWTAuthMiddleware(),
+		deleteCredentials)
+
+}
+
+const (
+	// placeOrderEndpoint the API endpoint used to place an order through the
+	// brokerage that trades on the requested exchange.
+	placeOrderEndpoint = "/broker/order"
+	// getOrderEndpoint the API endpoint used to retrieve an order placed
+	// through a brokerage.
+	getOrderEndpoint = "/broker/:platform/order/:id"
+	// cancelOrderEndpoint the API endpoint used to cancel an open order placed
+	// through a brokerage.
+	cancelOrderEndpoint = "/broker/:platform/order/:id"
+	// listBalanceEndpoint the API endpoint used to retrieve the balances held
+	// in a brokerage account.
+	listBalanceEndpoint = "/broker/:platform/balance"
+	// getCredentialsEndpoint the API endpoint used to check whether API
+	// credentials are stored for a brokerage.
+	getCredentialsEndpoint = "/broker/:platform/credentials"
+	// saveCredentialsEndpoint the API endpoint used to store the API
+	// credentials used to trade through a brokerage.
+	saveCredentialsEndpoint = "/broker/:platform/credentials"
+	// deleteCredentialsEndpoint the API endpoint used to remove the API
+	// credentials stored for a brokerage.
+	deleteCredentialsEndpoint = "/broker/:platform/credentials"
+)
+
+// placeOrder places an order through the logged in user's brokerage account.
+func placeOrder(c *gin.Context) {
+
+	var req placeOrderRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	order := &broker.Order{
+		Exchange:   strings.ToUpper(req.Exchange),
+		Ticker:     strings.ToUpper(req.Ticker),
+		Side:       broker.Side(strings.ToLower(req.Side)),
+		Type:       broker.OrderType(strings.ToLower(req.Type)),
+		Quantity:   req.Quantity,
+		LimitPrice: req.LimitPrice,
+		StopPrice:  req.StopPrice,
+	}
+
+	// validate request parameters
+	if err := broker.ValidateOrder(order); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	platform, err := broker.PlatformForExchange(order.Exchange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	exchange, ok := readExchange(c, platform)
+	if !ok {
+		return
+	}
+
+	// place the order
+	if err := exchange.PlaceOrder(c, order); err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	// respond with the order
+	c.JSON(http.StatusOK, order)
+
+}
+
+// getOrder retrieves an order placed through the logged in user's brokerage
+// account.
+func getOrder(c *gin.Context) {
+
+	exchange, ok := readExchange(c, market.PlatformKey(
+		strings.ToLower(c.Param("platform"))))
+	if !ok {
+		return
+	}
+
+	// retrieve the order
+	order, err := exchange.GetOrder(c, c.Param("id"))
+	if err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	// respond with the order
+	c.JSON(http.StatusOK, order)
+
+}
+
+// cancelOrder cancels an open order placed through the logged in user's
+// brokerage account.
+func cancelOrder(c *gin.Context) {
+
+	exchange, ok := readExchange(c, market.PlatformKey(
+		strings.ToLower(c.Param("platform"))))
+	if !ok {
+		return
+	}
+
+	// cancel the order
+	if err := exchange.CancelOrder(c, c.Param("id")); err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	// respond with 200 - OK if the order was cancelled
+	c.Status(http.StatusOK)
+
+}
+
+// listBalance retrieves the balances held in the logged in user's brokerage
+// account.
+func listBalance(c *gin.Context) {
+
+	exchange, ok := readExchange(c, market.PlatformKey(
+		strings.ToLower(c.Param("platform"))))
+	if !ok {
+		return
+	}
+
+	// retrieve balances
+	balances, err := exchange.ListBalances(c)
+	if err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	// respond with balances
+	c.JSON(http.StatusOK, balances)
+
+}
+
+// getCredentials reports whether the logged in user has stored API credentials
+// for a brokerage. Stored credentials are never returned.
+func getCredentials(c *gin.Context) {
+
+	u, platform, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	configured, err := broker.HasCredentials(c, data.DB(), u.ID, platform)
+	if err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credentialsResponse{
+		Platform:   platform,
+		Configured: configured,
+	})
+
+}
+
+// saveCredentials stores the API credentials the logged in user trades through
+// a brokerage with.
+func saveCredentials(c *gin.Context) {
+
+	u, platform, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	var req saveCredentialsRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	if err := broker.SaveCredentials(c, data.DB(), u.ID, platform,
+		broker.Credentials{
+			APIKey:     strings.TrimSpace(req.APIKey),
+			Secret:     strings.TrimSpace(req.Secret),
+			Passphrase: strings.TrimSpace(req.Passphrase),
+		}); err == broker.ErrUnsupportedExchange {
+		respondBrokerError(c, err)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentialsResponse{
+		Platform:   platform,
+		Configured: true,
+	})
+
+}
+
+// deleteCredentials removes the API credentials the logged in user stored for
+// a brokerage.
+func deleteCredentials(c *gin.Context) {
+
+	u, platform, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	if err := broker.DeleteCredentials(c, data.DB(), u.ID,
+		platform); err != nil {
+		respondBrokerError(c, err)
+		return
+	}
+
+	// respond with 200 - OK if the credentials were removed
+	c.Status(http.StatusOK)
+
+}
+
+// readPlatform reads the logged in user and the platform specified by the
+// request path. Writes an error response and returns false if the user cannot
+// be retrieved.
+func readPlatform(c *gin.Context) (*user.User, market.PlatformKey, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, "", false
+	}
+
+	return u, market.PlatformKey(strings.ToLower(c.Param("platform"))), true
+
+}
+
+// readExchange creates an exchange that trades through the logged in user's
+// account on the specified platform. Writes an error response and returns
+// false if the exchange cannot be created.
+func readExchange(c *gin.Context,
+	platform market.PlatformKey) (broker.Exchange, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	exchange, err := broker.Live(c, data.DB(), u.ID, platform)
+	if err != nil {
+		respondBrokerError(c, err)
+		return nil, false
+	}
+
+	return exchange, true
+
+}
+
+// respondBrokerError writes the response for an error returned by a brokerage.
+// Errors caused by the request or rejected by the brokerage are returned to the
+// client, all other errors are logged.
+func respondBrokerError(c *gin.Context, err error) {
+
+	if apiErr, ok := err.(*broker.APIError); ok {
+		c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+			ErrorMessage: apiErr.Message,
+		})
+		return
+	}
+
+	switch err {
+	case broker.ErrOrderNotFound:
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+	case broker.ErrMissingCredentials, broker.ErrUnsupportedExchange:
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+	default:
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+	}
+
+}
@@ -0,0 +1,31 @@
+package delivery
+
+import (
+	"mojito/market"
+)
+
+// placeOrderRequest is used to read a request to the place order endpoint.
+type placeOrderRequest struct {
+	Exchange   string  `json:"exchange"`
+	Ticker     string  `json:"ticker"`
+	Side       string  `json:"side"`
+	Type       string  `json:"type"`
+	Quantity   float64 `json:"quantity"`
+	LimitPrice float64 `json:"limit_price"`
+	StopPrice  float64 `json:"stop_price"`
+}
+
+// saveCredentialsRequest is used to read a request to the save credentials
+// endpoint. The passphrase is only required by Coinbase.
+type saveCredentialsRequest struct {
+	APIKey     string `json:"api_key"`
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"`
+}
+
+// credentialsResponse is used to format responses from the credentials
+// endpoints.
+type credentialsResponse struct {
+	Platform   market.PlatformKey `json:"platform"`
+	Configured bool               `json:"configured"`
+}
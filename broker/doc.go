@@ -0,0 +1,13 @@
+// Package broker provides a common interface for placing orders through a
+// brokerage. Live orders are signed with the API credentials stored in the
+// user's settings with SaveCredentials, paper orders are placed through the
+// paper trading exchange.
+//
+// Environment:
+//     MOJITO_COINBASE_API_URL
+//         string - the base URL of the Coinbase exchange REST API.
+//                  Default: https://api.pro.coinbase.com
+//     MOJITO_ALPACA_API_URL
+//         string - the base URL of the Alpaca trading REST API.
+//                  Default: https://paper-api.alpaca.markets
+package broker
@@ -0,0 +1,27 @@
+package broker
+
+import (
+	"strings"
+
+	"mojito/env"
+)
+
+// init reads the base URL of each brokerage API.
+func init() {
+
+	coinbaseAPIURL = strings.TrimRight(env.GetStringSafe(coinbaseAPIURLVariable,
+		"https://api.pro.coinbase.com"), "/")
+
+	alpacaAPIURL = strings.TrimRight(env.GetStringSafe(alpacaAPIURLVariable,
+		"https://paper-api.alpaca.markets"), "/")
+
+}
+
+const (
+	// coinbaseAPIURLVariable defines an environment variable for the base URL
+	// of the Coinbase exchange REST API.
+	coinbaseAPIURLVariable = "MOJITO_COINBASE_API_URL"
+	// alpacaAPIURLVariable defines an environment variable for the base URL of
+	// the Alpaca trading REST API.
+	alpacaAPIURLVariable = "MOJITO_ALPACA_API_URL"
+)
@@ -0,0 +1,148 @@
+package broker
+
+import (
+	"context"
+	"strconv"
+
+	"mojito/paper"
+
+	"gorm.io/gorm"
+)
+
+// paperExchange places orders through a user's paper trading account.
+type paperExchange struct {
+	db     *gorm.DB
+	userID uint
+}
+
+// Paper creates an exchange that trades through the user's paper trading
+// account.
+func Paper(db *gorm.DB, userID uint) Exchange {
+	return &paperExchange{
+		db:     db,
+		userID: userID,
+	}
+}
+
+func (p *paperExchange) PlaceOrder(ctx context.Context, order *Order) error {
+
+	item := newPaperOrder(p.userID, order)
+
+	if err := paper.PlaceOrder(ctx, p.db, item); err != nil {
+		return err
+	}
+
+	*order = formatPaperOrder(item)
+	return nil
+
+}
+
+func (p *paperExchange) CancelOrder(ctx context.Context, id string) error {
+
+	item, err := p.getOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return paper.CancelOrder(ctx, p.db, item)
+
+}
+
+func (p *paperExchange) GetOrder(ctx context.Context,
+	id string) (*Order, error) {
+
+	item, err := p.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	order := formatPaperOrder(item)
+	return &order, nil
+
+}
+
+func (p *paperExchange) ListBalances(ctx context.Context) ([]Balance, error) {
+
+	items, err := paper.ListBalance(ctx, p.db, p.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []Balance{}
+	for _, item := range items {
+		balances = append(balances, Balance{
+			Asset:     item.Asset,
+			Amount:    item.Amount,
+			Available: item.Available(),
+		})
+	}
+
+	return balances, nil
+
+}
+
+// getOrder retrieves a paper trading order belonging to the user by id.
+func (p *paperExchange) getOrder(ctx context.Context,
+	id string) (*paper.Order, error) {
+
+	orderID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	item, err := paper.GetOrderByID(ctx, p.db, p.userID, uint(orderID))
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+
+}
+
+// newPaperOrder converts a brokerage order to a paper trading order belonging
+// to the specified user.
+func newPaperOrder(userID uint, order *Order) *paper.Order {
+	return &paper.Order{
+		UserID:     userID,
+		Exchange:   order.Exchange,
+		Ticker:     order.Ticker,
+		Side:       order.Side,
+		Type:       order.Type,
+		Quantity:   order.Quantity,
+		LimitPrice: order.LimitPrice,
+		StopPrice:  order.StopPrice,
+	}
+}
+
+// formatPaperOrder converts a paper trading order to a brokerage order.
+func formatPaperOrder(item *paper.Order) Order {
+
+	order := Order{
+		ID:         strconv.FormatUint(uint64(item.ID), 10),
+		CreatedAt:  item.CreatedAt,
+		Exchange:   item.Exchange,
+		Ticker:     item.Ticker,
+		Side:       item.Side,
+		Type:       item.Type,
+		Quantity:   item.Quantity,
+		LimitPrice: item.LimitPrice,
+		StopPrice:  item.StopPrice,
+		Status:     item.Status,
+	}
+
+	// compute the average price of all fills
+	var value float64
+	for _, fill := range item.Fills {
+		order.FilledQuantity += fill.Quantity
+		value += fill.Quantity * fill.Price
+	}
+
+	if order.FilledQuantity > 0 {
+		order.FilledPrice = value / order.FilledQuantity
+	}
+
+	return order
+
+}
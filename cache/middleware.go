@@ -10,7 +10,8 @@ import (
 
 // LocalCacheMiddleware responds with values from the local cache if possible.
 // If the request is not present in the cache add it to the cache with the
-// specified time to live.
+// specified time to live. Only successful responses are cached so errors are
+// not repeated once their cause is resolved.
 func LocalCacheMiddleware(ttl time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -26,7 +27,7 @@ func LocalCacheMiddleware(ttl time.Duration) gin.HandlerFunc {
 		// check if the request is cached, if so respond with the cached value
 		if item, ok := GetLocal(key); ok {
 			if resp, ok := item.(responseCacheItem); ok {
-				c.Data(http.StatusOK, resp.ContentType, resp.Data)
+				c.Data(resp.Status, resp.ContentType, resp.Data)
 				c.Abort()
 				return
 			}
@@ -43,8 +44,14 @@ func LocalCacheMiddleware(ttl time.Duration) gin.HandlerFunc {
 		// execute the next handler function
 		c.Next()
 
-		// cache the response
+		// cache the response if the request succeeded
+		status := c.Writer.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
 		SetLocal(key, responseCacheItem{
+			Status:      status,
 			ContentType: c.Writer.Header().Get("Content-Type"),
 			Data:        writer.responseData.Bytes(),
 		}, ttl)
@@ -55,6 +62,7 @@ func LocalCacheMiddleware(ttl time.Duration) gin.HandlerFunc {
 // responseCacheItem is used to store the raw response to an HTTP request in the
 // local cache.
 type responseCacheItem struct {
+	Status      int
 	ContentType string
 	Data        []byte
 }
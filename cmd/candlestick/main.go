@@ -0,0 +1,174 @@
+// Package main is a command line tool for bulk loading candlestick data into
+// the mojito database and exporting it to files. The database connection is
+// configured through the same environment as the server.
+//
+// Usage:
+//     candlestick import [-format csv|ndjson|columnar] [-file path]
+//     candlestick export -exchange EXCHANGE -ticker TICKER [-start time]
+//         [-end time] [-format csv|ndjson|columnar] [-file path]
+//     candlestick dedupe
+//
+// Files are read from standard input and written to standard output if no file
+// is specified. Times may be RFC 3339 strings or unix timestamps in seconds.
+// The dedupe command removes candlesticks stored before duplicates were
+// rejected so the unique index on each security and open time can be created.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "import":
+		importCommand(os.Args[2:])
+	case "export":
+		exportCommand(os.Args[2:])
+	case "dedupe":
+		dedupeCommand()
+	default:
+		usage()
+	}
+
+}
+
+// importCommand bulk loads candlesticks from a file.
+func importCommand(args []string) {
+
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", string(market.FormatCSV),
+		"the file format: csv, ndjson, or columnar")
+	file := flags.String("file", "", "the file to import, defaults to stdin")
+	flags.Parse(args)
+
+	f, err := market.ParseFormat(*format)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "" {
+		in, err := os.Open(*file)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer in.Close()
+		r = in
+	}
+
+	result, err := market.Import(context.Background(), data.DB(), r, f)
+	logrus.Infof("inserted %d candlesticks, skipped %d duplicates",
+		result.Inserted, result.Skipped)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+}
+
+// exportCommand writes candlesticks for a ticker to a file.
+func exportCommand(args []string) {
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	exchange := flags.String("exchange", "", "the exchange to export")
+	ticker := flags.String("ticker", "", "the ticker to export")
+	start := flags.String("start", "0", "the start of the date range")
+	end := flags.String("end", "", "the end of the date range, defaults to now")
+	format := flags.String("format", string(market.FormatCSV),
+		"the file format: csv, ndjson, or columnar")
+	file := flags.String("file", "", "the file to write, defaults to stdout")
+	flags.Parse(args)
+
+	if *exchange == "" || *ticker == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f, err := market.ParseFormat(*format)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	startDate, err := parseTime(*start)
+	if err != nil {
+		logrus.Fatal("invalid start date")
+	}
+
+	endDate := time.Now()
+	if *end != "" {
+		if endDate, err = parseTime(*end); err != nil {
+			logrus.Fatal("invalid end date")
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		out, err := os.Create(*file)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		defer out.Close()
+		w = out
+	}
+
+	if err := market.Export(context.Background(), data.DB(), w, f,
+		strings.ToUpper(*exchange), strings.ToUpper(*ticker), startDate,
+		endDate); err != nil {
+		logrus.Fatal(err)
+	}
+
+}
+
+// dedupeCommand removes duplicate candlesticks and creates the unique index
+// that rejects them.
+func dedupeCommand() {
+
+	count, err := market.CountDuplicates(context.Background(), data.DB())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	logrus.Infof("found %d duplicate candlesticks", count)
+
+	removed, err := market.RemoveDuplicates(context.Background(), data.DB())
+	logrus.Infof("removed %d duplicate candlesticks", removed)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Info("created the candlestick unique index")
+
+}
+
+// parseTime parses a date supplied as a command line flag. Dates may be
+// formatted as RFC 3339 strings or unix timestamps in seconds.
+func parseTime(value string) (time.Time, error) {
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+
+}
+
+// usage prints the available commands and exits.
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: candlestick import|export|dedupe [flags]")
+	os.Exit(2)
+}
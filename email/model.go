@@ -58,4 +58,11 @@ var mockEmailTemplates = []emailTemplate{
 		BodyText: "You recently requested to recover your Mojito account.\n\nTo recover your account please click the following link:\n{{.ClientBaseURL}}/recover/reset?token={{.VerificationToken}}}\n\nIf you did not initiate this request please disregard this email.\n\nThank you!\nThe Mojito Team",
 		BodyHTML: "You recently requested to recover your Mojito account.<br><br><br><center><a style=\"border-radius: 5px; background-color: #007bff; color: white; padding: 1em 1.5em; text-decoration: none;\" href=\"{{.ClientBaseURL}}/recover/reset?token={{.VerificationToken}}\">Reset My Password</a></center><br><br>If you did not initiate this request please disregard this email.<br><br>Thank you!<br>The Mojito Team",
 	},
+	{
+		ID:       4,
+		Title:    TemplateTitleAlert,
+		Subject:  "Mojito alert: {{.Name}}",
+		BodyText: "Your alert \"{{.Name}}\" was triggered.\n\n{{.Message}}\n\nExchange: {{.Exchange}}\nTicker: {{.Ticker}}\nPrice: {{printf \"%.2f\" .Price}}\nTime: {{.TriggeredAt}}\n\nTo manage your alerts visit:\n{{.ClientBaseURL}}/alerts\n\nThank you!\nThe Mojito Team",
+		BodyHTML: "Your alert <b>{{.Name}}</b> was triggered.<br><br>{{.Message}}<br><br>Exchange: {{.Exchange}}<br>Ticker: {{.Ticker}}<br>Price: {{printf \"%.2f\" .Price}}<br>Time: {{.TriggeredAt}}<br><br><br><center><a style=\"border-radius: 5px; background-color: #007bff; color: white; padding: 1em 1.5em; text-decoration: none;\" href=\"{{.ClientBaseURL}}/alerts\">Manage My Alerts</a></center><br><br>Thank you!<br>The Mojito Team",
+	},
 }
@@ -21,6 +21,9 @@ const (
 	// TemplateTitleRecover is the email content sent when a user initiates the
 	// recover user account process.
 	TemplateTitleRecover TemplateTitle = "Recover"
+	// TemplateTitleAlert is the email content sent when one of a user's price
+	// alerts is triggered.
+	TemplateTitleAlert TemplateTitle = "Alert"
 )
 
 // SignupData is the data that is used to execute the signup email template.
@@ -28,6 +31,17 @@ type SignupData struct {
 	ValidateLink string
 }
 
+// AlertData is the data that is used to execute the alert email template.
+type AlertData struct {
+	ClientBaseURL string
+	Name          string
+	Exchange      string
+	Ticker        string
+	Message       string
+	Price         float64
+	TriggeredAt   string
+}
+
 // ExecuteTemplate loads and executes the specified template with the supplied
 // data.
 func ExecuteTemplate(templateTitle TemplateTitle,
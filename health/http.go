@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"mojito/data"
+	"mojito/market/feed"
 	"mojito/server"
 
 	"github.com/gin-gonic/gin"
@@ -35,10 +36,21 @@ func healthHandler(c *gin.Context) {
 		logrus.Error(dbError)
 	}
 
+	// check if every market data feed is connected and receiving messages
+	feeds := feed.HealthAll()
+	feedsHealthy := true
+	for _, f := range feeds {
+		if !f.Healthy {
+			feedsHealthy = false
+		}
+	}
+
 	// write health check response
 	c.JSON(http.StatusOK, healthResponse{
-		Uptime:      time.Now().Sub(startTime),
-		DBAvailable: dbError == nil,
+		Uptime:       time.Now().Sub(startTime),
+		DBAvailable:  dbError == nil,
+		FeedsHealthy: feedsHealthy,
+		Feeds:        feeds,
 	})
 
 }
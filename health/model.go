@@ -1,9 +1,15 @@
 package health
 
-import "time"
+import (
+	"time"
+
+	"mojito/market/feed"
+)
 
 // healthResponse is used to format responses from the health check endpoint.
 type healthResponse struct {
-	Uptime      time.Duration `json:"uptime"`
-	DBAvailable bool          `json:"db_available"`
+	Uptime       time.Duration `json:"uptime"`
+	DBAvailable  bool          `json:"db_available"`
+	FeedsHealthy bool          `json:"feeds_healthy"`
+	Feeds        []feed.Health `json:"feeds"`
 }
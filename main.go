@@ -6,17 +6,26 @@
 package main
 
 import (
+	"mojito/alert"
+	"mojito/bot"
 	"mojito/env"
+	"mojito/market/feed"
+	"mojito/paper"
 	"mojito/server"
 
 	// import APIs
+	_ "mojito/alert/delivery"
+	_ "mojito/backtest/delivery"
+	_ "mojito/bot/delivery"
+	_ "mojito/broker/delivery"
 	_ "mojito/health"
 	_ "mojito/market/delivery"
+	_ "mojito/market/feed/delivery"
+	_ "mojito/market/screener/delivery"
+	_ "mojito/market/synthetic/delivery"
+	_ "mojito/paper/delivery"
 	_ "mojito/user/delivery"
 
-	// import market data feeds
-	_ "mojito/market/feed"
-
 	"github.com/sirupsen/logrus"
 )
 
@@ -33,6 +42,13 @@ func main() {
 		logrus.SetLevel(logrus.DebugLevel)
 	}
 
+	// connect the market data feeds once every feed implementation has
+	// registered, then resume the work that depends on them
+	feed.Start()
+	paper.Resume()
+	alert.Resume()
+	bot.Resume()
+
 	// run the API server
 	server.Run()
 
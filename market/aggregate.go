@@ -0,0 +1,192 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bucketOffset shifts the unix epoch, which falls on a Thursday, back to the
+// preceding Monday so that weekly candlesticks open at the start of the week.
+// The offset is a whole number of days so it does not affect the alignment of
+// shorter resolutions.
+const bucketOffset = 3 * 24 * 60 * 60
+
+// BucketStart gets the time at which the candlestick containing the supplied
+// time opens at the specified resolution.
+func BucketStart(t time.Time, resolution Resolution) time.Time {
+	seconds := int64(resolution.Duration() / time.Second)
+	if seconds <= 0 {
+		return t
+	}
+	bucket := floorDiv(t.Unix()+bucketOffset, seconds)
+	return time.Unix(bucket*seconds-bucketOffset, 0)
+}
+
+// Aggregate combines the supplied candlesticks into candlesticks at the
+// specified resolution. Each resulting candlestick takes the open of its first
+// candlestick, the close of its last candlestick, the highest high, the lowest
+// low, and the total volumes. The supplied candlesticks must be sorted by
+// creation time.
+func Aggregate(candlesticks []Candlestick,
+	resolution Resolution) []Candlestick {
+
+	items := []Candlestick{}
+
+	for _, candlestick := range candlesticks {
+
+		opens := BucketStart(candlestick.CreatedAt, resolution)
+
+		// start a new candlestick if this one falls in a new interval
+		if len(items) == 0 || !items[len(items)-1].CreatedAt.Equal(opens) {
+			items = append(items, Candlestick{
+				CreatedAt: opens,
+				Exchange:  candlestick.Exchange,
+				Ticker:    candlestick.Ticker,
+				Quote:     candlestick.Quote,
+				Open:      candlestick.Open,
+				Close:     candlestick.Close,
+				High:      candlestick.High,
+				Low:       candlestick.Low,
+
+				Volume:      candlestick.Volume,
+				QuoteVolume: candlestick.QuoteVolume,
+				TradeCount:  candlestick.TradeCount,
+				BuyVolume:   candlestick.BuyVolume,
+				SellVolume:  candlestick.SellVolume,
+			})
+			continue
+		}
+
+		// merge this candlestick into the current interval
+		current := items[len(items)-1].
+			SetClose(candlestick.Close).
+			Add(0, 0, 0, 0, candlestick.Volume)
+		current.QuoteVolume += candlestick.QuoteVolume
+		current.TradeCount += candlestick.TradeCount
+		current.BuyVolume += candlestick.BuyVolume
+		current.SellVolume += candlestick.SellVolume
+
+		if candlestick.High > current.High {
+			current = current.SetHigh(candlestick.High)
+		}
+
+		if candlestick.Low < current.Low {
+			current = current.SetLow(candlestick.Low)
+		}
+
+		items[len(items)-1] = current
+
+	}
+
+	return items
+
+}
+
+// listAggregateSQL aggregates candlesticks to the specified resolution in the
+// database. Returns false if aggregation is not supported by the database
+// dialect, in which case the caller should aggregate the candlesticks itself.
+func listAggregateSQL(ctx context.Context, db *gorm.DB, exchange,
+	ticker string, resolution Resolution, startDate,
+	endDate time.Time) ([]Candlestick, bool, error) {
+
+	// build an expression that numbers the interval each candlestick falls in
+	var bucketExpr string
+	switch db.Dialector.Name() {
+	case "mysql":
+		bucketExpr = "FLOOR((UNIX_TIMESTAMP(created_at) + ?) / ?)"
+	case "sqlite":
+		bucketExpr = "((CAST(strftime('%s', created_at) AS INTEGER) + ?) / ?)"
+	default:
+		return nil, false, nil
+	}
+
+	seconds := int64(resolution.Duration() / time.Second)
+
+	// group candlesticks by interval, the open and close of each interval are
+	// taken from its first and last candlesticks
+	query := fmt.Sprintf(`
+SELECT
+	a.bucket,
+	(SELECT o.open FROM candlesticks o
+		WHERE o.exchange = a.exchange AND o.ticker = a.ticker
+		AND o.quote = a.quote
+		AND o.created_at = a.first_at ORDER BY o.id LIMIT 1) AS open,
+	(SELECT l.close FROM candlesticks l
+		WHERE l.exchange = a.exchange AND l.ticker = a.ticker
+		AND l.quote = a.quote
+		AND l.created_at = a.last_at ORDER BY l.id DESC LIMIT 1) AS close,
+	a.high,
+	a.low,
+	a.volume,
+	a.quote_volume,
+	a.trade_count,
+	a.buy_volume,
+	a.sell_volume
+FROM (
+	SELECT
+		exchange,
+		ticker,
+		quote,
+		%s AS bucket,
+		MIN(created_at) AS first_at,
+		MAX(created_at) AS last_at,
+		MAX(high) AS high,
+		MIN(low) AS low,
+		SUM(volume) AS volume,
+		SUM(quote_volume) AS quote_volume,
+		SUM(trade_count) AS trade_count,
+		SUM(buy_volume) AS buy_volume,
+		SUM(sell_volume) AS sell_volume
+	FROM candlesticks
+	WHERE exchange = ? AND ticker = ? AND quote = ?
+	AND created_at > ? AND created_at < ?
+	GROUP BY exchange, ticker, quote, bucket
+) a
+ORDER BY a.bucket`, bucketExpr)
+
+	base, quote := SplitTicker(ticker)
+
+	rows, err := db.Raw(query, bucketOffset, seconds, exchange, base, quote,
+		startDate, endDate).Rows()
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	items := []Candlestick{}
+
+	for rows.Next() {
+
+		var bucket int64
+		var item Candlestick
+
+		if err := rows.Scan(&bucket, &item.Open, &item.Close, &item.High,
+			&item.Low, &item.Volume, &item.QuoteVolume, &item.TradeCount,
+			&item.BuyVolume, &item.SellVolume); err != nil {
+			return nil, true, err
+		}
+
+		item.CreatedAt = time.Unix(bucket*seconds-bucketOffset, 0)
+		item.Exchange = exchange
+		item.Ticker = base
+		item.Quote = quote
+
+		items = append(items, item)
+
+	}
+
+	return items, true, rows.Err()
+
+}
+
+// floorDiv divides a by b rounding towards negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
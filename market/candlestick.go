@@ -34,11 +34,28 @@ func (c Candlestick) SetLow(low float64) Candlestick {
 
 // SetVolume sets the volume to the supplied value, result is returned as a new
 // candlestick.
-func (c Candlestick) SetVolume(volume int) Candlestick {
+func (c Candlestick) SetVolume(volume float64) Candlestick {
 	c.Volume = volume
 	return c
 }
 
+// AddTrade adds a trade of the supplied size and price to the volume of this
+// candlestick, result is returned as a new candlestick. The side is the side
+// of the trade's taker; trades with an unknown side count towards neither the
+// buy nor the sell volume.
+func (c Candlestick) AddTrade(price, size float64, side string) Candlestick {
+	c.Volume += size
+	c.QuoteVolume += size * price
+	c.TradeCount++
+	switch side {
+	case TradeSideBuy:
+		c.BuyVolume += size
+	case TradeSideSell:
+		c.SellVolume += size
+	}
+	return c
+}
+
 // SetOpensHour sets whether this candlestick opens a new hour.
 func (c Candlestick) SetOpensHour(opensHour bool) Candlestick {
 	c.OpensHour = opensHour
@@ -51,10 +68,19 @@ func (c Candlestick) SetOpensDay(opensDay bool) Candlestick {
 	return c
 }
 
+// SetOpensAfter sets whether this candlestick opens a new hour or a new day
+// given the candlestick that precedes it, result is returned as a new
+// candlestick.
+func (c Candlestick) SetOpensAfter(last Candlestick) Candlestick {
+	c.OpensHour = last.CreatedAt.Hour() != c.CreatedAt.Hour()
+	c.OpensDay = last.CreatedAt.Day() != c.CreatedAt.Day()
+	return c
+}
+
 // Add adds the supplied values to this candlestick, result is returned as a new
 // candlestick.
-func (c Candlestick) Add(open, close, high, low float64,
-	volume int) Candlestick {
+func (c Candlestick) Add(open, close, high, low,
+	volume float64) Candlestick {
 	c.Open += open
 	c.Close += close
 	c.High += high
@@ -71,13 +97,17 @@ func (c Candlestick) AddCandlestick(other Candlestick) Candlestick {
 	c.High += other.High
 	c.Low += other.Low
 	c.Volume += other.Volume
+	c.QuoteVolume += other.QuoteVolume
+	c.BuyVolume += other.BuyVolume
+	c.SellVolume += other.SellVolume
+	c.TradeCount += other.TradeCount
 	return c
 }
 
 // Subtract subtracts the supplied values from this candlestick, result is
 // returned as a new candlestick.
-func (c Candlestick) Subtract(open, close, high, low float64,
-	volume int) Candlestick {
+func (c Candlestick) Subtract(open, close, high, low,
+	volume float64) Candlestick {
 	c.Open -= open
 	c.Close -= close
 	c.High -= high
@@ -94,13 +124,17 @@ func (c Candlestick) SubtractCandlestick(other Candlestick) Candlestick {
 	c.High -= other.High
 	c.Low -= other.Low
 	c.Volume -= other.Volume
+	c.QuoteVolume -= other.QuoteVolume
+	c.BuyVolume -= other.BuyVolume
+	c.SellVolume -= other.SellVolume
+	c.TradeCount -= other.TradeCount
 	return c
 }
 
 // Multiply multiplies the supplied values to this candlestick, result is
 // returned as a new candlestick.
-func (c Candlestick) Multiply(open, close, high, low float64,
-	volume int) Candlestick {
+func (c Candlestick) Multiply(open, close, high, low,
+	volume float64) Candlestick {
 	c.Open *= open
 	c.Close *= close
 	c.High *= high
@@ -110,20 +144,24 @@ func (c Candlestick) Multiply(open, close, high, low float64,
 }
 
 // MultiplyCandlestick multiplies the values of another candlestick to this
-// candlestick, result is returned as a new candlestick.
+// candlestick, result is returned as a new candlestick. The trade count is not
+// multiplied.
 func (c Candlestick) MultiplyCandlestick(other Candlestick) Candlestick {
 	c.Open *= other.Open
 	c.Close *= other.Close
 	c.High *= other.High
 	c.Low *= other.Low
 	c.Volume *= other.Volume
+	c.QuoteVolume *= other.QuoteVolume
+	c.BuyVolume *= other.BuyVolume
+	c.SellVolume *= other.SellVolume
 	return c
 }
 
 // Divide divides this candlestick by the supplied values, result is returned as
 // a new candlestick.
-func (c Candlestick) Divide(open, close, high, low float64,
-	volume int) Candlestick {
+func (c Candlestick) Divide(open, close, high, low,
+	volume float64) Candlestick {
 	c.Open /= open
 	c.Close /= close
 	c.High /= high
@@ -133,20 +171,30 @@ func (c Candlestick) Divide(open, close, high, low float64,
 }
 
 // DivideCandlestick divides this candlestick by the values of another
-// candlestick, result is returned as a new candlestick.
+// candlestick, result is returned as a new candlestick. The trade count is not
+// divided.
 func (c Candlestick) DivideCandlestick(other Candlestick) Candlestick {
 	c.Open /= other.Open
 	c.Close /= other.Close
 	c.High /= other.High
 	c.Low /= other.Low
 	c.Volume /= other.Volume
+	c.QuoteVolume /= other.QuoteVolume
+	c.BuyVolume /= other.BuyVolume
+	c.SellVolume /= other.SellVolume
 	return c
 }
 
+// Symbol gets the ticker of this candlestick including its quote currency, as
+// formatted by FormatTicker.
+func (c Candlestick) Symbol() string {
+	return FormatTicker(c.Ticker, c.Quote)
+}
+
 // String returns a string representation of this candlestick.
 func (c Candlestick) String() string {
 	return fmt.Sprintf(
-		"Exchange: %s Ticker: %s Created At: %v, Open: %.2f, Close: %.2f, High: %.2f Low: %.2f Volume: %d",
-		c.Exchange, c.Ticker, c.CreatedAt, c.Open, c.Close, c.High, c.Low,
+		"Exchange: %s Ticker: %s Created At: %v, Open: %.2f, Close: %.2f, High: %.2f Low: %.2f Volume: %g",
+		c.Exchange, c.Symbol(), c.CreatedAt, c.Open, c.Close, c.High, c.Low,
 		c.Volume)
 }
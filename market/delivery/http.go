@@ -1,7 +1,10 @@
 package delivery
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,6 +12,9 @@ import (
 	"mojito/data"
 	"mojito/httperror"
 	"mojito/market"
+	"mojito/market/indicator"
+	"mojito/market/pattern"
+	"mojito/market/synthetic"
 	"mojito/server"
 	"mojito/user"
 
@@ -24,6 +30,18 @@ func init() {
 		cache.LocalCacheMiddleware(60*time.Second), candlestickSpec)
 	server.Router().GET(listCandlestickEndpoint, user.JWTAuthMiddleware(),
 		cache.LocalCacheMiddleware(60*time.Second), listCandlestick)
+	server.Router().GET(listIndicatorEndpoint, user.JWTAuthMiddleware(),
+		cache.LocalCacheMiddleware(60*time.Second), listIndicator)
+	server.Router().GET(listPatternEndpoint, user.JWTAuthMiddleware(),
+		cache.LocalCacheMiddleware(60*time.Second), listPattern)
+	server.Router().GET(streamCandlestickEndpoint, user.JWTAuthMiddleware(),
+		streamCandlestick)
+	server.Router().GET(exportCandlestickEndpoint, user.JWTAuthMiddleware(),
+		exportCandlestick)
+
+	// bind admin endpoints
+	server.Router().POST(importCandlestickEndpoint, user.JWTAdminMiddleware(),
+		importCandlestick)
 
 }
 
@@ -34,6 +52,15 @@ const (
 	// listCandlestickEndpoint the API endpoint used to retrieve candlestick
 	// data.
 	listCandlestickEndpoint = "/candlestick/exchange/:exchange/ticker/:ticker"
+	// listIndicatorEndpoint the API endpoint used to retrieve technical
+	// indicators computed over candlestick data.
+	listIndicatorEndpoint = "/candlestick/exchange/:exchange/ticker/:ticker/indicator/:name"
+	// listPatternEndpoint the API endpoint used to retrieve candlestick
+	// patterns found in candlestick data.
+	listPatternEndpoint = "/candlestick/exchange/:exchange/ticker/:ticker/pattern"
+	// maxCandlesticks the maximum number of candlesticks that may be requested
+	// at once.
+	maxCandlesticks = 2000
 )
 
 // candlestickSpec retrieves available options for requesting candlestick data.
@@ -70,9 +97,12 @@ func candlestickSpec(c *gin.Context) {
 
 		// get display names for tickers
 		for _, ticker := range tickerList {
+			base, quote := market.SplitTicker(ticker)
 			tickers = append(tickers, candlestickSpecTicker{
-				ID:   ticker,
-				Name: ticker,
+				ID:    ticker,
+				Name:  ticker,
+				Base:  base,
+				Quote: quote,
 			})
 		}
 
@@ -85,6 +115,37 @@ func candlestickSpec(c *gin.Context) {
 
 	}
 
+	// retrieve synthetic tickers
+	synthetics, err := synthetic.ListSynthetic(c, data.DB())
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// add synthetic tickers to the response under their own exchange
+	if len(synthetics) > 0 {
+
+		tickers := []candlestickSpecTicker{}
+		for _, item := range synthetics {
+			tickers = append(tickers, candlestickSpecTicker{
+				ID:         item.Name,
+				Name:       item.Name,
+				Base:       item.Name,
+				Expression: item.Expression,
+			})
+		}
+
+		response.Exchanges = append(response.Exchanges, candlestickSpecExchange{
+			ID:      synthetic.Exchange,
+			Name:    synthetic.Exchange,
+			Tickers: tickers,
+		})
+
+	}
+
 	//response with spec
 	c.JSON(http.StatusOK, response)
 }
@@ -96,30 +157,93 @@ func listCandlestick(c *gin.Context) {
 	exchange := strings.ToUpper(c.Param("exchange"))
 	ticker := strings.ToUpper(c.Param("ticker"))
 
-	// default date range to the current day
-	end := time.Now()
-	start := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0,
-		end.Location())
+	// read query parameters
+	start, end, resolution, err := parseCandlestickQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// retrieve candlestick data, synthetic tickers are computed on the fly
+	candlesticks, err := synthetic.ListByTicker(c, data.DB(), exchange,
+		ticker, resolution, start, end)
+	if err == synthetic.ErrNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: "ticker not found",
+		})
+		return
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
 
-	// TODO: get start and end date from GET params
+	// respond with candlesticks
+	c.JSON(http.StatusOK, candlesticks)
+}
 
-	var hourly bool
-	var daily bool
+// listIndicator retrieves a technical indicator computed over candlestick data.
+func listIndicator(c *gin.Context) {
 
-	if end.Sub(start) > time.Hour*24*60 {
-		// if the date range is larger than two months only retrieve
-		// candlesticks that open a new day
-		daily = true
-	} else if end.Sub(start) > time.Hour*24 {
-		// if the date range is larger than a day only retrieve candlesticks
-		// that open a new hour
-		hourly = true
+	// read path parameters
+	exchange := strings.ToUpper(c.Param("exchange"))
+	ticker := strings.ToUpper(c.Param("ticker"))
+	name := indicator.Name(strings.ToLower(c.Param("name")))
+
+	// read query parameters
+	start, end, resolution, err := parseCandlestickQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
 	}
 
-	// retrieve candlestick data
-	candlesticks, err := market.ListByTicker(c, data.DB(), exchange,
-		ticker, hourly, daily, start, end)
+	// read indicator parameters
+	params := indicator.Params{}
+	for _, key := range indicator.ParamKeys {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+
+		param, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: fmt.Sprintf("invalid %s", key),
+			})
+			return
+		}
+
+		params[key] = param
+	}
+
+	// create the indicator
+	ind, err := indicator.New(name, params)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// retrieve enough candlesticks before the start of the date range for the
+	// indicator to produce a value for the first candlestick in the range
+	lookbackStart := start.Add(-time.Duration(ind.Lookback()+1) *
+		resolution.Duration())
+
+	candlesticks, err := synthetic.ListByTicker(c, data.DB(), exchange,
+		ticker, resolution, lookbackStart, end)
+	if err == synthetic.ErrNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: "ticker not found",
+		})
+		return
+	} else if err != nil {
 		logrus.Error(err)
 		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
 			ErrorMessage: httperror.InternalServerError,
@@ -127,6 +251,167 @@ func listCandlestick(c *gin.Context) {
 		return
 	}
 
-	// respond with candlesticks
-	c.JSON(http.StatusOK, candlesticks)
+	// compute the indicator, only respond with values inside the date range
+	points := []indicator.Point{}
+	for _, point := range indicator.Compute(ind, candlesticks) {
+		if !point.CreatedAt.Before(market.BucketStart(start, resolution)) {
+			points = append(points, point)
+		}
+	}
+
+	// respond with indicator values
+	c.JSON(http.StatusOK, points)
+}
+
+// listPattern retrieves candlestick patterns found in candlestick data. The
+// patterns to look for may be limited with a comma separated list of pattern
+// names and matches below a minimum confidence may be excluded.
+func listPattern(c *gin.Context) {
+
+	// read path parameters
+	exchange := strings.ToUpper(c.Param("exchange"))
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	// read query parameters
+	start, end, resolution, err := parseCandlestickQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	names, err := pattern.ParseNames(c.Query("pattern"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	confidence := 0.0
+	if value := c.Query("confidence"); value != "" {
+		if confidence, err = strconv.ParseFloat(value, 64); err != nil ||
+			confidence < 0 || confidence > 1 {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: "invalid confidence, expected a number between 0 and 1",
+			})
+			return
+		}
+	}
+
+	scanner, err := pattern.NewScanner(names...)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// retrieve candlesticks before the start of the date range so the trend
+	// preceding patterns at the start of the range can be measured
+	lookbackStart := start.Add(-time.Duration(scanner.Lookback()+2) *
+		resolution.Duration())
+
+	candlesticks, err := synthetic.ListByTicker(c, data.DB(), exchange,
+		ticker, resolution, lookbackStart, end)
+	if err == synthetic.ErrNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: "ticker not found",
+		})
+		return
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// scan for patterns, only respond with patterns completed inside the date
+	// range
+	matches := []pattern.Match{}
+	for _, candlestick := range candlesticks {
+		for _, match := range scanner.Update(candlestick) {
+			if !match.CreatedAt.Before(market.BucketStart(start, resolution)) &&
+				match.Confidence >= confidence {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	// respond with pattern matches
+	c.JSON(http.StatusOK, matches)
+}
+
+// parseCandlestickQuery reads the date range and resolution of a request for
+// candlestick data from the GET parameters. The date range defaults to the
+// current day; if no resolution is supplied it is selected based on the size
+// of the date range.
+func parseCandlestickQuery(c *gin.Context) (start, end time.Time,
+	resolution market.Resolution, err error) {
+
+	// read the end of the date range, default to the current time
+	end = time.Now()
+	if value := c.Query("end"); value != "" {
+		if end, err = parseTime(value); err != nil {
+			return time.Time{}, time.Time{}, "",
+				errors.New("invalid end date")
+		}
+	}
+
+	// read the start of the date range, default to the start of the day
+	start = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0,
+		end.Location())
+	if value := c.Query("start"); value != "" {
+		if start, err = parseTime(value); err != nil {
+			return time.Time{}, time.Time{}, "",
+				errors.New("invalid start date")
+		}
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, "",
+			errors.New("start date must be before end date")
+	}
+
+	// read the resolution
+	if value := c.Query("resolution"); value != "" {
+		if resolution, err = market.ParseResolution(value); err != nil {
+			return time.Time{}, time.Time{}, "",
+				errors.New("invalid resolution, expected one of 1m, 5m, 15m, 1h, 4h, 1d, 1w")
+		}
+	} else if end.Sub(start) > time.Hour*24*60 {
+		// if the date range is larger than two months default to daily
+		// candlesticks
+		resolution = market.Resolution1Day
+	} else if end.Sub(start) > time.Hour*24 {
+		// if the date range is larger than a day default to hourly
+		// candlesticks
+		resolution = market.Resolution1Hour
+	} else {
+		resolution = market.Resolution1Minute
+	}
+
+	// limit the number of candlesticks that may be requested at once
+	if end.Sub(start)/resolution.Duration() > maxCandlesticks {
+		return time.Time{}, time.Time{}, "", fmt.Errorf(
+			"date range too large, at most %d candlesticks may be requested",
+			maxCandlesticks)
+	}
+
+	return start, end, resolution, nil
+
+}
+
+// parseTime parses a date supplied as a GET parameter. Dates may be formatted
+// as RFC 3339 strings or unix timestamps in seconds.
+func parseTime(value string) (time.Time, error) {
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+
 }
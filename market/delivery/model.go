@@ -1,5 +1,10 @@
 package delivery
 
+import (
+	"mojito/httperror"
+	"mojito/market"
+)
+
 // candlestickSpecResponse is used to format responses from the get candlestick
 // spec endpoint.
 type candlestickSpecResponse struct {
@@ -13,8 +18,37 @@ type candlestickSpecExchange struct {
 	Tickers []candlestickSpecTicker `json:"tickers"`
 }
 
-// candlestickSpecTicker stores information about an available ticker.
+// candlestickSpecTicker stores information about an available ticker. The id
+// includes the quote currency if it is not the default quote currency.
 type candlestickSpecTicker struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Base       string `json:"base"`
+	Quote      string `json:"quote"`
+	Expression string `json:"expression,omitempty"` // how a synthetic ticker is computed
+}
+
+// streamRequest is used to read subscription requests sent by clients of the
+// candlestick stream endpoint.
+type streamRequest struct {
+	Action   string `json:"action"`
+	Exchange string `json:"exchange"`
+	Ticker   string `json:"ticker"`
+}
+
+// streamMessage is used to format messages sent to clients of the candlestick
+// stream endpoint.
+type streamMessage struct {
+	Type        string              `json:"type"`
+	Exchange    string              `json:"exchange,omitempty"`
+	Ticker      string              `json:"ticker,omitempty"`
+	Candlestick *market.Candlestick `json:"candlestick,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// importErrorResponse is used to format an error from the import candlestick
+// endpoint along with the number of candlesticks imported before the error.
+type importErrorResponse struct {
+	httperror.ErrorResponse
+	market.ImportResult
 }
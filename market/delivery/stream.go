@@ -0,0 +1,361 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+	"mojito/market/feed"
+	"mojito/market/synthetic"
+	"mojito/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// streamCandlestickEndpoint the API endpoint used to open a websocket that
+	// streams candlestick data as it is produced by the market data feed.
+	streamCandlestickEndpoint = "/candlestick/stream"
+	// streamActionSubscribe is the action sent by a client to begin receiving
+	// candlesticks for an exchange and ticker.
+	streamActionSubscribe = "subscribe"
+	// streamActionUnsubscribe is the action sent by a client to stop receiving
+	// candlesticks for an exchange and ticker.
+	streamActionUnsubscribe = "unsubscribe"
+	// streamTypeCandlestick identifies a message carrying a candlestick that
+	// was committed by the feed.
+	streamTypeCandlestick = "candlestick"
+	// streamTypeUpdate identifies a message carrying the candlestick that is
+	// currently being aggregated by the feed.
+	streamTypeUpdate = "update"
+	// streamTypeSubscribed acknowledges a subscribe request.
+	streamTypeSubscribed = "subscribed"
+	// streamTypeUnsubscribed acknowledges an unsubscribe request.
+	streamTypeUnsubscribed = "unsubscribed"
+	// streamTypeError identifies a message reporting a failed request.
+	streamTypeError = "error"
+	// maxStreamSubscriptions the maximum number of tickers a single stream may
+	// be subscribed to.
+	maxStreamSubscriptions = 20
+	// streamBufferSize the number of messages that may be queued for a client
+	// before further messages are dropped.
+	streamBufferSize = 64
+	// streamUpdateInterval how often in-progress candlesticks are checked for
+	// changes.
+	streamUpdateInterval = time.Second
+	// streamPingInterval how often the server pings the client to keep the
+	// connection alive.
+	streamPingInterval = 30 * time.Second
+	// streamPongWait how long the server waits for any message from the client
+	// before closing the connection.
+	streamPongWait = 60 * time.Second
+	// streamWriteWait how long the server waits to write a message.
+	streamWriteWait = 10 * time.Second
+)
+
+// errSecurityNotTracked is reported to a client that subscribes to a security
+// the market data feed is not configured to track.
+var errSecurityNotTracked = errors.New("security is not tracked by the " +
+	"market data feed")
+
+// stream tracks the subscriptions of a single websocket client.
+type stream struct {
+	mutex         *sync.Mutex
+	send          chan streamMessage
+	subscriptions map[string]*streamSubscription
+	done          chan struct{}
+}
+
+// streamSubscription tracks a client's subscription to an exchange and ticker.
+type streamSubscription struct {
+	exchange string
+	ticker   string
+	cancel   func()
+	last     market.Candlestick // the last in-progress candlestick sent
+}
+
+// streamCandlestick upgrades the request to a websocket that streams
+// candlesticks for the exchanges and tickers the client subscribes to. Clients
+// send subscribe and unsubscribe requests as JSON messages, the server responds
+// with each committed candlestick and with in-progress candlestick updates.
+func streamCandlestick(c *gin.Context) {
+
+	conn, err := server.Upgrade(c)
+	if err != nil {
+		logrus.Debug(err)
+		return
+	}
+
+	s := &stream{
+		mutex:         &sync.Mutex{},
+		send:          make(chan streamMessage, streamBufferSize),
+		subscriptions: map[string]*streamSubscription{},
+		done:          make(chan struct{}),
+	}
+
+	go s.write(conn)
+	go s.poll()
+
+	s.read(conn)
+
+	// the client disconnected, release all subscriptions
+	close(s.done)
+
+	s.mutex.Lock()
+	for key, subscription := range s.subscriptions {
+		subscription.cancel()
+		delete(s.subscriptions, key)
+	}
+	s.mutex.Unlock()
+
+}
+
+// read handles requests from the client until the connection is closed.
+func (s *stream) read(conn *websocket.Conn) {
+
+	conn.SetReadLimit(1024)
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+
+	for {
+
+		var req streamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if _, ok := err.(*websocket.CloseError); !ok {
+				logrus.Debug(err)
+			}
+			return
+		}
+
+		conn.SetReadDeadline(time.Now().Add(streamPongWait))
+
+		exchange := strings.ToUpper(req.Exchange)
+		ticker := strings.ToUpper(req.Ticker)
+
+		switch strings.ToLower(req.Action) {
+		case streamActionSubscribe:
+			s.subscribe(exchange, ticker)
+		case streamActionUnsubscribe:
+			s.unsubscribe(exchange, ticker)
+		default:
+			s.queue(streamMessage{
+				Type:  streamTypeError,
+				Error: "invalid action, expected subscribe or unsubscribe",
+			})
+		}
+
+	}
+
+}
+
+// write sends queued messages and periodic pings to the client. Closes the
+// connection once the client disconnects.
+func (s *stream) write(conn *websocket.Conn) {
+
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+	defer conn.Close()
+
+	for {
+		select {
+		case message := <-s.send:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteJSON(message); err != nil {
+				logrus.Debug(err)
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage,
+				nil); err != nil {
+				logrus.Debug(err)
+				return
+			}
+		case <-s.done:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(streamWriteWait))
+			return
+		}
+	}
+
+}
+
+// poll periodically sends the candlestick currently being aggregated for each
+// subscription if it has changed since it was last sent.
+func (s *stream) poll() {
+
+	ticker := time.NewTicker(streamUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mutex.Lock()
+		for _, subscription := range s.subscriptions {
+
+			f, err := feed.ForExchange(subscription.exchange)
+			if err != nil {
+				continue
+			}
+
+			candlestick, err := f.Check(subscription.exchange,
+				subscription.ticker)
+			if err != nil || candlestick == subscription.last {
+				continue
+			}
+
+			subscription.last = candlestick
+			s.queue(streamMessage{
+				Type:        streamTypeUpdate,
+				Exchange:    subscription.exchange,
+				Ticker:      subscription.ticker,
+				Candlestick: &candlestick,
+			})
+
+		}
+		s.mutex.Unlock()
+	}
+
+}
+
+// subscribe begins streaming candlesticks for the specified exchange and
+// ticker.
+func (s *stream) subscribe(exchange, ticker string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := exchange + "-" + ticker
+	if _, ok := s.subscriptions[key]; ok {
+		s.queue(streamMessage{
+			Type:     streamTypeSubscribed,
+			Exchange: exchange,
+			Ticker:   ticker,
+		})
+		return
+	}
+
+	if len(s.subscriptions) >= maxStreamSubscriptions {
+		s.queue(streamMessage{
+			Type:     streamTypeError,
+			Exchange: exchange,
+			Ticker:   ticker,
+			Error:    "too many subscriptions",
+		})
+		return
+	}
+
+	// only securities the market data feed is configured to track may be
+	// streamed, clients cannot subscribe the feed to arbitrary securities
+	if err := checkTracked(exchange, ticker); err != nil {
+		s.queue(streamMessage{
+			Type:     streamTypeError,
+			Exchange: exchange,
+			Ticker:   ticker,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	channel, cancel, err := synthetic.Subscribe(exchange, ticker)
+	if err != nil {
+		s.queue(streamMessage{
+			Type:     streamTypeError,
+			Exchange: exchange,
+			Ticker:   ticker,
+			Error:    err.Error(),
+		})
+		return
+	}
+
+	s.subscriptions[key] = &streamSubscription{
+		exchange: exchange,
+		ticker:   ticker,
+		cancel:   cancel,
+	}
+
+	// forward committed candlesticks until the subscription is cancelled
+	go func() {
+		for candlestick := range channel {
+			candlestick := candlestick
+			s.queue(streamMessage{
+				Type:        streamTypeCandlestick,
+				Exchange:    exchange,
+				Ticker:      ticker,
+				Candlestick: &candlestick,
+			})
+		}
+	}()
+
+	s.queue(streamMessage{
+		Type:     streamTypeSubscribed,
+		Exchange: exchange,
+		Ticker:   ticker,
+	})
+
+}
+
+// unsubscribe stops streaming candlesticks for the specified exchange and
+// ticker.
+func (s *stream) unsubscribe(exchange, ticker string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := exchange + "-" + ticker
+	if subscription, ok := s.subscriptions[key]; ok {
+		subscription.cancel()
+		delete(s.subscriptions, key)
+	}
+
+	s.queue(streamMessage{
+		Type:     streamTypeUnsubscribed,
+		Exchange: exchange,
+		Ticker:   ticker,
+	})
+
+}
+
+// checkTracked checks that the market data feed is configured to track the
+// specified security, synthetic tickers are tracked if each security in their
+// expression is tracked.
+func checkTracked(exchange, ticker string) error {
+
+	securities, err := synthetic.Securities(context.Background(), data.DB(),
+		exchange, ticker)
+	if err != nil {
+		return err
+	}
+
+	for _, security := range securities {
+		if !feed.Tracks(security.Exchange, security.Ticker) {
+			return errSecurityNotTracked
+		}
+	}
+
+	return nil
+
+}
+
+// queue adds a message to be sent to the client, the message is dropped if
+// the client is not keeping up.
+func (s *stream) queue(message streamMessage) {
+	select {
+	case s.send <- message:
+	default:
+		logrus.Warnf("stream dropped message: %v", message)
+	}
+}
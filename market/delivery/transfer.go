@@ -0,0 +1,126 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// exportCandlestickEndpoint the API endpoint used to download candlestick
+	// data as a file.
+	exportCandlestickEndpoint = "/candlestick/exchange/:exchange/ticker/:ticker/export"
+	// importCandlestickEndpoint the API endpoint used to upload candlestick
+	// data from a file.
+	importCandlestickEndpoint = "/candlestick/import"
+)
+
+// exportCandlestick streams candlestick data for a ticker as a CSV, NDJSON, or
+// columnar JSON file.
+func exportCandlestick(c *gin.Context) {
+
+	// read path parameters
+	exchange := strings.ToUpper(c.Param("exchange"))
+	ticker := strings.ToUpper(c.Param("ticker"))
+
+	// read query parameters
+	format, err := market.ParseFormat(c.DefaultQuery("format",
+		string(market.FormatCSV)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	start, end, err := parseExportRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=\"%s-%s.%s\"", exchange, ticker,
+		format.Extension()))
+	c.Status(http.StatusOK)
+
+	// stream the file, the status has already been written so errors can only
+	// be logged
+	if err := market.Export(c, data.DB(), c.Writer, format, exchange, ticker,
+		start, end); err != nil {
+		logrus.Error(err)
+	}
+
+}
+
+// importCandlestick bulk loads candlestick data from a CSV, NDJSON, or columnar
+// JSON file supplied as the request body.
+func importCandlestick(c *gin.Context) {
+
+	// read query parameters
+	format, err := market.ParseFormat(c.DefaultQuery("format",
+		string(market.FormatCSV)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	result, err := market.Import(c, data.DB(), c.Request.Body, format)
+	if err != nil {
+		// candlesticks in earlier batches remain imported, report how many
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, importErrorResponse{
+			ErrorResponse: httperror.ErrorResponse{ErrorMessage: err.Error()},
+			ImportResult:  result,
+		})
+		return
+	}
+
+	// respond with the number of candlesticks imported
+	c.JSON(http.StatusOK, result)
+
+}
+
+// parseExportRange reads the date range of a request to export candlestick
+// data from the GET parameters. The date range defaults to the current day.
+func parseExportRange(c *gin.Context) (start, end time.Time, err error) {
+
+	// read the end of the date range, default to the current time
+	end = time.Now()
+	if value := c.Query("end"); value != "" {
+		if end, err = parseTime(value); err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid end date")
+		}
+	}
+
+	// read the start of the date range, default to the start of the day
+	start = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0,
+		end.Location())
+	if value := c.Query("start"); value != "" {
+		if start, err = parseTime(value); err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid start date")
+		}
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{},
+			errors.New("start date must be before end date")
+	}
+
+	return start, end, nil
+
+}
@@ -1,3 +1,9 @@
 // Package market provides a standardized way to retrieve market data across
 // platforms and exchanges.
+//
+// Securities are priced in a quote currency, USD unless otherwise specified.
+// Tickers of securities priced in another currency name the currency pair as
+// BASE-QUOTE, such as BTC-EUR or ETH-BTC, while a plain ticker such as BTC is
+// priced in USD. Candlesticks store the base asset and quote currency
+// separately.
 package market
@@ -0,0 +1,272 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+	"mojito/pubsub"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// aggregator builds candlesticks from individual trades and commits them at a
+// fixed interval. Feed implementations embed an aggregator to provide the
+// Check, Commit, and Health methods of the Feed interface. The clock defaults
+// to the current time, feeds replaying recorded data supply their own.
+type aggregator struct {
+	*monitor
+	mutex        *sync.Mutex
+	interval     time.Duration
+	clock        func() time.Time
+	candlesticks map[string]market.Candlestick
+}
+
+// newAggregator creates an aggregator that commits candlesticks covering the
+// supplied interval.
+func newAggregator(interval time.Duration) *aggregator {
+	return &aggregator{
+		monitor:      newMonitor(),
+		mutex:        &sync.Mutex{},
+		interval:     interval,
+		clock:        time.Now,
+		candlesticks: map[string]market.Candlestick{},
+	}
+}
+
+func (a *aggregator) Check(exchange,
+	ticker string) (market.Candlestick, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// retrieve the candlestick for this ticker
+	candlestick, ok := a.candlesticks[formatFeedKey(exchange, ticker)]
+	if !ok {
+		return market.Candlestick{}, ErrTickerNotFound
+	}
+
+	// check that the candlestick contains data
+	if candlestick.TradeCount == 0 {
+		return market.Candlestick{}, ErrNoPriceData
+	}
+
+	return candlestick, nil
+}
+
+func (a *aggregator) Commit(exchange,
+	ticker string) (market.Candlestick, error) {
+
+	candlestick, err := a.reset(exchange, ticker)
+	if err != nil {
+		return market.Candlestick{}, err
+	}
+
+	a.committed(candlestick.Exchange, candlestick.Symbol())
+
+	// publish the candlestick to subscribers once the feed is unlocked so a
+	// slow subscriber cannot hold up the feed
+	pubsub.Publish(Topic(exchange, ticker), candlestick)
+
+	// return the final candlestick data
+	return candlestick, nil
+}
+
+// reset saves the candlestick that is currently being aggregated and begins
+// aggregating a new candlestick.
+func (a *aggregator) reset(exchange,
+	ticker string) (market.Candlestick, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// format the key that will be used to get the candlestick associated with
+	// the specified ticker
+	key := formatFeedKey(exchange, ticker)
+
+	// retrieve the candlestick for this ticker
+	candlestick, ok := a.candlesticks[key]
+	if !ok {
+		return market.Candlestick{}, ErrTickerNotFound
+	}
+
+	// check that the candlestick contains price data
+	if candlestick.TradeCount == 0 {
+		return market.Candlestick{}, ErrNoPriceData
+	}
+
+	// check if this candlestick opens a new hour or a new day
+	last, err := market.GetLastByTicker(context.Background(), data.DB(),
+		strings.ToUpper(exchange), strings.ToUpper(ticker))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logrus.Error(err)
+	} else {
+		candlestick = candlestick.SetOpensAfter(last)
+	}
+
+	// save the candlestick, a backfill may have already stored it
+	if _, err := market.SaveCandlesticks(context.Background(), data.DB(),
+		[]market.Candlestick{candlestick}); err != nil {
+		logrus.Error(err)
+	} else {
+		logrus.Debugf("new candlestick: %v", candlestick)
+	}
+
+	// clear the candlestick data associated with this ticker
+	a.candlesticks[key] = newCandlestick(exchange, ticker, a.clock())
+
+	return candlestick, nil
+}
+
+// aggregate updates the current candlestick with a new trade. The side is the
+// side of the trade's taker if the platform reports it. Returns whether the
+// candlestick should be committed after aggregating the trade.
+func (a *aggregator) aggregate(exchange, ticker string, currentPrice,
+	size float64, side string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// format the key that will be used to get the candlestick associated with
+	// the specified ticker
+	key := formatFeedKey(exchange, ticker)
+
+	// retrieve the candlestick for this ticker
+	candlestick, ok := a.candlesticks[key]
+	if !ok {
+		// if the candlestick is not found, initialize it now
+		candlestick = newCandlestick(exchange, ticker, a.clock())
+	}
+
+	// add the trade to the volume
+	candlestick = candlestick.AddTrade(currentPrice, size, side)
+
+	// set the close price
+	candlestick = candlestick.SetClose(currentPrice)
+
+	if candlestick.Open == 0.00 {
+		// if the open price has not been set, set it now
+		candlestick = candlestick.SetOpen(currentPrice)
+	}
+
+	if candlestick.Low == 0.00 || candlestick.Low > currentPrice {
+		// if the current price is lower than our low price or the low price
+		// has not been set, set it now
+		candlestick = candlestick.SetLow(currentPrice)
+	}
+
+	if candlestick.High == 0.00 || candlestick.High < currentPrice {
+		// if the current price is higher than our high price or the high price
+		// has not been set, set it now
+		candlestick = candlestick.SetHigh(currentPrice)
+	}
+
+	// update the candlestick for this ticker
+	a.candlesticks[key] = candlestick
+
+	// check if we should commit this candlestick
+	return a.clock().After(candlestick.CreatedAt.Add(a.interval))
+}
+
+// load replaces the candlestick currently being aggregated for its security
+// with a complete candlestick, such as one read from a recording. Any
+// candlestick already holding price data is committed first. The candlestick
+// is aligned to the start of its minute like candlesticks built from trades.
+func (a *aggregator) load(candlestick market.Candlestick) {
+
+	candlestick.Exchange = strings.ToUpper(candlestick.Exchange)
+	candlestick.Ticker, candlestick.Quote = market.SplitTicker(
+		market.FormatTicker(candlestick.Ticker, candlestick.Quote))
+
+	if _, err := a.Commit(candlestick.Exchange,
+		candlestick.Symbol()); err != nil && err != ErrTickerNotFound &&
+		err != ErrNoPriceData {
+		logrus.Error(err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	candlestick.ID = 0
+	candlestick.CreatedAt = market.BucketStart(candlestick.CreatedAt,
+		market.Resolution1Minute)
+	a.candlesticks[formatFeedKey(candlestick.Exchange,
+		candlestick.Symbol())] = candlestick
+
+}
+
+// current lists each candlestick that contains price data.
+func (a *aggregator) current() []market.Candlestick {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	items := []market.Candlestick{}
+	for _, candlestick := range a.candlesticks {
+		if candlestick.TradeCount > 0 {
+			items = append(items, candlestick)
+		}
+	}
+
+	return items
+}
+
+// expired lists each candlestick that contains price data and has been
+// aggregating for longer than the interval.
+func (a *aggregator) expired() []market.Candlestick {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	items := []market.Candlestick{}
+	for _, candlestick := range a.candlesticks {
+		if candlestick.TradeCount > 0 &&
+			a.clock().After(candlestick.CreatedAt.Add(a.interval)) {
+			items = append(items, candlestick)
+		}
+	}
+
+	return items
+}
+
+// discard drops the candlesticks that are currently being aggregated. Feeds
+// discard candlesticks after reconnecting since trades were missed while the
+// connection was down, the missed interval is backfilled instead.
+func (a *aggregator) discard() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.candlesticks = map[string]market.Candlestick{}
+}
+
+// newCandlestick creates an empty candlestick for the supplied exchange and
+// ticker. The ticker may include a quote currency as described by
+// market.SplitTicker. The candlestick opens at the start of the minute
+// containing the supplied time, matching the candlesticks stored by a
+// backfill, so the same minute cannot be stored twice.
+func newCandlestick(exchange, ticker string,
+	createdAt time.Time) market.Candlestick {
+	base, quote := market.SplitTicker(ticker)
+	return market.Candlestick{
+		CreatedAt: market.BucketStart(createdAt, market.Resolution1Minute),
+		Exchange:  strings.ToUpper(exchange),
+		Ticker:    base,
+		Quote:     quote,
+	}
+}
+
+// remove drops the candlestick that is currently being aggregated for the
+// specified exchange and ticker. Feeds remove candlesticks when they are
+// unsubscribed from a security.
+func (a *aggregator) remove(exchange, ticker string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.candlesticks, formatFeedKey(exchange, ticker))
+}
+
+// formatFeedKey formats the supplied exchange and ticker into the format that
+// is used to track different securities in a feed. Tickers are normalized so a
+// security priced in the default quote currency has one key.
+func formatFeedKey(exchange, ticker string) string {
+	return strings.ToUpper(fmt.Sprintf("%s-%s", exchange,
+		market.FormatTicker(market.SplitTicker(ticker))))
+}
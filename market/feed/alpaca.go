@@ -0,0 +1,476 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/env"
+	"mojito/market"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// init registers the Alpaca feed.
+func init() {
+	Register(market.PlatformAlpaca, connectAlpacaFeed)
+	RegisterHistory(market.PlatformAlpaca, alpacaHistory)
+}
+
+// alpacaExchanges maps the exchange codes reported on Alpaca trades to the
+// exchanges tracked by mojito.
+var alpacaExchanges = map[string]market.ExchangeKey{
+	"V": market.ExchangeIEX,
+	"B": market.ExchangeNASDAQBX,
+	"X": market.ExchangeNASDAQPSX,
+	"C": market.ExchangeNYSENational,
+	"M": market.ExchangeNYSEChicago,
+}
+
+// alpacaFeed is used to stream price data from the Alpaca market data API.
+type alpacaFeed struct {
+	*aggregator
+	mutex      *sync.Mutex
+	session    *session
+	recorder   *recorder
+	securities map[string]bool
+}
+
+// alpacaAuthMessage is the payload used to authenticate with the Alpaca
+// market data API.
+type alpacaAuthMessage struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+}
+
+// alpacaSubscribeMessage is the payload used to subscribe to trades from the
+// Alpaca market data API.
+type alpacaSubscribeMessage struct {
+	Action string   `json:"action"`
+	Trades []string `json:"trades"`
+}
+
+// alpacaMessage is used to read control messages and trades from the Alpaca
+// market data API. Each websocket message contains an array of these.
+type alpacaMessage struct {
+	Type      string    `json:"T"`
+	Message   string    `json:"msg"`
+	Code      int       `json:"code"`
+	Symbol    string    `json:"S"`
+	TradeID   int64     `json:"i"`
+	Exchange  string    `json:"x"`
+	Price     float64   `json:"p"`
+	Size      float64   `json:"s"`
+	Timestamp time.Time `json:"t"`
+}
+
+// alpacaBarsResponse is used to read a page of historical bars from the Alpaca
+// market data API.
+type alpacaBarsResponse struct {
+	Bars []struct {
+		Timestamp time.Time `json:"t"`
+		Open      float64   `json:"o"`
+		High      float64   `json:"h"`
+		Low       float64   `json:"l"`
+		Close     float64   `json:"c"`
+		Volume    float64   `json:"v"`
+		Trades    int       `json:"n"`
+		VWAP      float64   `json:"vw"`
+	} `json:"bars"`
+	NextPageToken string `json:"next_page_token"`
+}
+
+func (a *alpacaFeed) AddSecurity(exchange, ticker string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := formatFeedKey(exchange, ticker)
+
+	if !a.securities[key] {
+
+		// subscribe to trades for the ticker, trades are reported for every
+		// exchange so the ticker may already be subscribed
+		if err := a.session.send(alpacaSubscribeMessage{
+			Action: "subscribe",
+			Trades: []string{strings.ToUpper(ticker)},
+		}); err != nil {
+			return err
+		}
+
+		a.securities[key] = true
+
+	}
+
+	return nil
+}
+
+func (a *alpacaFeed) RemoveSecurity(exchange, ticker string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := formatFeedKey(exchange, ticker)
+
+	if !a.securities[key] {
+		return nil
+	}
+
+	delete(a.securities, key)
+	a.remove(exchange, ticker)
+
+	// trades are reported for every exchange, only unsubscribe from the
+	// ticker once it is no longer tracked on any exchange
+	for _, tracked := range a.tickers() {
+		if tracked == strings.ToUpper(ticker) {
+			return nil
+		}
+	}
+
+	return a.session.send(alpacaSubscribeMessage{
+		Action: "unsubscribe",
+		Trades: []string{strings.ToUpper(ticker)},
+	})
+}
+
+func (a *alpacaFeed) Close() error {
+	return a.session.close()
+}
+
+// restart reconnects the feed and resubscribes to each security it tracks.
+func (a *alpacaFeed) restart() {
+	a.session.restart()
+}
+
+// subscribe subscribes the current connection of the feed to trades for each
+// ticker the feed tracks.
+func (a *alpacaFeed) subscribe() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	tickers := a.tickers()
+	if len(tickers) == 0 {
+		return nil
+	}
+
+	return a.session.send(alpacaSubscribeMessage{
+		Action: "subscribe",
+		Trades: tickers,
+	})
+}
+
+// tickers lists the tickers the feed is subscribed to.
+func (a *alpacaFeed) tickers() []string {
+
+	unique := map[string]bool{}
+	for key := range a.securities {
+		unique[key[strings.LastIndex(key, "-")+1:]] = true
+	}
+
+	tickers := []string{}
+	for ticker := range unique {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+
+	return tickers
+
+}
+
+// handle reads the trades and control messages in a message from the Alpaca
+// market data API.
+func (a *alpacaFeed) handle(data []byte) {
+
+	var messages []alpacaMessage
+	if err := json.Unmarshal(data, &messages); err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	for _, message := range messages {
+		switch message.Type {
+		case "t":
+			a.handleTrade(message)
+		case "error":
+			logrus.Errorf("alpaca feed error %d: %s", message.Code,
+				message.Message)
+		}
+	}
+
+}
+
+// handleTrade aggregates a trade reported by the Alpaca market data API. Trades
+// outside of regular market hours and trades for securities the feed does not
+// track are ignored.
+func (a *alpacaFeed) handleTrade(message alpacaMessage) {
+
+	exchange, ok := alpacaExchanges[message.Exchange]
+	if !ok || !marketOpen(message.Timestamp) {
+		return
+	}
+
+	a.mutex.Lock()
+	tracked := a.securities[formatFeedKey(string(exchange), message.Symbol)]
+	a.mutex.Unlock()
+
+	if !tracked {
+		return
+	}
+
+	a.recorder.record(Trade{
+		Time:     message.Timestamp,
+		Exchange: string(exchange),
+		Ticker:   message.Symbol,
+		TradeID:  message.TradeID,
+		Price:    message.Price,
+		Size:     message.Size,
+	})
+
+	// Alpaca does not report which side initiated a trade
+	if a.aggregate(string(exchange), message.Symbol, message.Price,
+		message.Size, "") {
+		if _, err := a.Commit(string(exchange), message.Symbol); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+}
+
+// flush commits candlesticks whose interval has ended even if no further
+// trades have been reported, so the final candlestick of each session is not
+// held until the market opens again.
+func (a *alpacaFeed) flush() {
+	for {
+		time.Sleep(time.Second)
+
+		if a.session.isClosed() {
+			return
+		}
+
+		for _, candlestick := range a.expired() {
+			if _, err := a.Commit(candlestick.Exchange,
+				candlestick.Symbol()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// dialAlpacaFeed connects and authenticates with the Alpaca market data API.
+func dialAlpacaFeed(baseURL string) (*websocket.Conn, error) {
+
+	key := env.GetString(alpacaAPIKeyVariable)
+	secret := env.GetString(alpacaSecretKeyVariable)
+	if key == "" || secret == "" {
+		return nil, errors.New("alpaca feed credentials are not configured")
+	}
+
+	// connect to the API websocket
+	conn, _, err := websocket.DefaultDialer.Dial(baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for the connection to be acknowledged, then authenticate
+	if err := readAlpacaControl(conn, "connected"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := conn.WriteJSON(alpacaAuthMessage{
+		Action: "auth",
+		Key:    key,
+		Secret: secret,
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := readAlpacaControl(conn, "authenticated"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+
+}
+
+// readAlpacaControl reads a message from the Alpaca market data API and checks
+// that it reports success with the expected message.
+func readAlpacaControl(conn *websocket.Conn, expected string) error {
+
+	var messages []alpacaMessage
+	if err := conn.ReadJSON(&messages); err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if message.Type == "error" {
+			return fmt.Errorf("alpaca feed error %d: %s", message.Code,
+				message.Message)
+		}
+		if message.Type == "success" && message.Message == expected {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("alpaca feed did not report %s", expected)
+
+}
+
+// connectAlpacaFeed connects to a feed of price data through the Alpaca market
+// data API.
+func connectAlpacaFeed(platform PlatformFeed) (Feed, error) {
+
+	feed := &alpacaFeed{
+		aggregator: newAggregator(platform.Interval),
+		mutex:      &sync.Mutex{},
+		securities: map[string]bool{},
+	}
+
+	// track each security in the platform spec
+	for _, security := range platform.Securities {
+		feed.securities[formatFeedKey(security.Exchange,
+			security.Symbol())] = true
+	}
+
+	feed.session = newSession(feed.monitor, func() (*websocket.Conn, error) {
+		return dialAlpacaFeed(platform.BaseURL)
+	})
+	feed.session.subscribe = feed.subscribe
+
+	// trades were missed while the connection was down, drop the partial
+	// candlesticks and backfill the missed interval
+	feed.session.onReconnect = func() {
+		feed.discard()
+		go backfillRecent(platform)
+	}
+
+	// connect to the API websocket and subscribe to trades
+	if err := feed.session.open(); err != nil {
+		return nil, err
+	}
+
+	feed.recorder = newRecorder(platform)
+
+	// trades are only reported during market hours
+	feed.idle = func(t time.Time) bool {
+		return !marketOpen(t)
+	}
+
+	go feed.flush()
+	go backfillRecent(platform)
+
+	// spawn a goroutine that continuously reads messages from the feed
+	go func() {
+		feed.session.read(feed.handle)
+		feed.recorder.close()
+	}()
+
+	return feed, nil
+}
+
+// alpacaHistory retrieves one minute candlesticks from the Alpaca historical
+// bars API. Only the IEX exchange is available without a market data
+// subscription, other exchanges are not backfilled.
+func alpacaHistory(ctx context.Context, platform PlatformFeed, exchange,
+	ticker string, start, end time.Time) ([]market.Candlestick, error) {
+
+	if market.ExchangeKey(strings.ToUpper(exchange)) != market.ExchangeIEX {
+		return nil, ErrNoHistory
+	}
+
+	header := http.Header{}
+	header.Set("APCA-API-KEY-ID", env.GetString(alpacaAPIKeyVariable))
+	header.Set("APCA-API-SECRET-KEY", env.GetString(alpacaSecretKeyVariable))
+
+	items := []market.Candlestick{}
+	for pageToken := ""; ; {
+
+		query := url.Values{}
+		query.Set("timeframe", "1Min")
+		query.Set("feed", "iex")
+		query.Set("limit", "10000")
+		query.Set("start", start.UTC().Format(time.RFC3339))
+		query.Set("end", end.UTC().Format(time.RFC3339))
+		if pageToken != "" {
+			query.Set("page_token", pageToken)
+		}
+
+		var page alpacaBarsResponse
+		if err := getHistory(ctx, fmt.Sprintf("%s/v2/stocks/%s/bars?%s",
+			strings.TrimSuffix(platform.HistoryURL, "/"),
+			strings.ToUpper(ticker), query.Encode()), header,
+			&page); err != nil {
+			return nil, err
+		}
+
+		// bars outside of regular market hours are ignored to match the feed
+		for _, bar := range page.Bars {
+			if !marketOpen(bar.Timestamp) {
+				continue
+			}
+			items = append(items, market.Candlestick{
+				CreatedAt: bar.Timestamp,
+				Open:      bar.Open,
+				High:      bar.High,
+				Low:       bar.Low,
+				Close:     bar.Close,
+
+				Volume:      bar.Volume,
+				QuoteVolume: bar.Volume * bar.VWAP,
+				TradeCount:  bar.Trades,
+			})
+		}
+
+		if page.NextPageToken == "" {
+			return items, nil
+		}
+		pageToken = page.NextPageToken
+
+	}
+
+}
+
+// marketOpen checks whether the supplied time falls within regular trading
+// hours on US stock exchanges, 9:30am to 4:00pm Eastern on weekdays.
+func marketOpen(t time.Time) bool {
+
+	t = t.In(easternTime)
+
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+
+	return minutes >= 9*60+30 && minutes < 16*60
+
+}
+
+// easternTime is the time zone in which US stock exchanges operate. Falls back
+// to a fixed offset if the time zone database is not available.
+var easternTime = func() *time.Location {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		logrus.Warn(err)
+		return time.FixedZone("EST", -5*60*60)
+	}
+	return location
+}()
+
+const (
+	// alpacaAPIKeyVariable defines an environment variable for the API key used
+	// to authenticate with the Alpaca market data API.
+	alpacaAPIKeyVariable = "MOJITO_ALPACA_API_KEY"
+	// alpacaSecretKeyVariable defines an environment variable for the secret
+	// key used to authenticate with the Alpaca market data API.
+	alpacaSecretKeyVariable = "MOJITO_ALPACA_SECRET_KEY"
+)
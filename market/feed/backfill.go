@@ -0,0 +1,353 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrNoHistory is returned when backfilling a platform feed that does not
+// have a historical price data source.
+var ErrNoHistory = errors.New("historical price data is not available for this feed")
+
+// HistoryFunc retrieves one minute candlesticks for the specified security
+// from the historical price data API of a platform. Candlesticks are returned
+// in chronological order and cover the interval from start up to end.
+type HistoryFunc func(ctx context.Context, platform PlatformFeed, exchange,
+	ticker string, start, end time.Time) ([]market.Candlestick, error)
+
+// histories keeps track of the historical price data source registered for
+// each platform.
+var histories = map[market.PlatformKey]HistoryFunc{}
+
+// backfillLookback is how far back gaps are detected when backfilling
+// automatically.
+var backfillLookback time.Duration
+
+// backfillMutex serializes backfill jobs so the same gap is never filled
+// twice.
+var backfillMutex = &sync.Mutex{}
+
+// historyClient is used to send requests to historical price data APIs.
+var historyClient = &http.Client{Timeout: 15 * time.Second}
+
+// RegisterHistory makes a historical price data source available for
+// backfilling platform feeds on the specified platform. Feed implementations
+// should call RegisterHistory from an init function.
+func RegisterHistory(key market.PlatformKey, history HistoryFunc) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	histories[key] = history
+}
+
+// BackfillLookback gets how far back gaps are detected when backfilling
+// automatically.
+func BackfillLookback() time.Duration {
+	return backfillLookback
+}
+
+// BackfillAll fills gaps in the stored candlesticks of every security tracked
+// by an enabled platform feed between the specified start and end time.
+// Returns the number of candlesticks inserted.
+func BackfillAll(ctx context.Context, db *gorm.DB, start,
+	end time.Time) (int, error) {
+
+	platforms, err := ListPlatform(ctx, db, ptrToBool(true))
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, platform := range platforms {
+		count, err := Backfill(ctx, db, platform, start, end)
+		total += count
+		if err == ErrNoHistory {
+			continue
+		} else if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+
+}
+
+// Backfill fills gaps in the stored candlesticks of each security tracked by
+// the supplied platform feed between the specified start and end time.
+// Returns the number of candlesticks inserted.
+func Backfill(ctx context.Context, db *gorm.DB, platform *PlatformFeed, start,
+	end time.Time) (int, error) {
+
+	if _, err := historyForPlatform(platform); err != nil {
+		return 0, err
+	}
+
+	// securities without historical price data are skipped
+	total := 0
+	for _, security := range platform.Securities {
+		count, err := backfillSecurity(ctx, db, platform, security.Exchange,
+			security.Symbol(), start, end)
+		total += count
+		if err != nil && err != ErrNoHistory {
+			return total, err
+		}
+	}
+
+	return total, nil
+
+}
+
+// BackfillSecurity fills gaps in the stored candlesticks of the specified
+// security between the specified start and end time. The security must be
+// tracked by an enabled platform feed. Returns the number of candlesticks
+// inserted.
+func BackfillSecurity(ctx context.Context, db *gorm.DB, exchange,
+	ticker string, start, end time.Time) (int, error) {
+
+	platform, err := platformForSecurity(ctx, db, exchange, ticker)
+	if err != nil {
+		return 0, err
+	}
+
+	return backfillSecurity(ctx, db, platform, exchange, ticker, start, end)
+
+}
+
+// CanBackfill checks whether the specified security can be backfilled. Returns
+// ErrTickerNotFound if the security is not tracked by an enabled platform feed
+// or ErrNoHistory if the feed has no historical price data source.
+func CanBackfill(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) error {
+
+	platform, err := platformForSecurity(ctx, db, exchange, ticker)
+	if err != nil {
+		return err
+	}
+
+	_, err = historyForPlatform(platform)
+	return err
+
+}
+
+// platformForSecurity retrieves the enabled platform feed that tracks the
+// specified security.
+func platformForSecurity(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) (*PlatformFeed, error) {
+
+	platforms, err := ListPlatform(ctx, db, ptrToBool(true))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, platform := range platforms {
+		for _, security := range platform.Securities {
+			if strings.EqualFold(security.Exchange, exchange) &&
+				formatFeedKey(exchange, security.Symbol()) ==
+					formatFeedKey(exchange, ticker) {
+				return platform, nil
+			}
+		}
+	}
+
+	return nil, ErrTickerNotFound
+
+}
+
+// backfillSecurity fills gaps in the stored candlesticks of a security tracked
+// by the supplied platform feed.
+func backfillSecurity(ctx context.Context, db *gorm.DB, platform *PlatformFeed,
+	exchange, ticker string, start, end time.Time) (int, error) {
+
+	history, err := historyForPlatform(platform)
+	if err != nil {
+		return 0, err
+	}
+
+	backfillMutex.Lock()
+	defer backfillMutex.Unlock()
+
+	exchange = strings.ToUpper(exchange)
+	ticker = market.FormatTicker(market.SplitTicker(ticker))
+
+	// retrieve the stored candlesticks in the date range and the candlestick
+	// preceding it, gaps are detected between consecutive candlesticks
+	items, err := market.ListByTicker(ctx, db, exchange, ticker,
+		market.Resolution1Minute, start.Add(-time.Nanosecond), end)
+	if err != nil {
+		return 0, err
+	}
+
+	last, err := market.GetLastBefore(ctx, db, exchange, ticker, start)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+
+	total := 0
+	for i := 0; i <= len(items); i++ {
+
+		// the gap begins after the previous candlestick ends and lasts until
+		// the next candlestick begins or the end of the range
+		gapStart, gapEnd := start, end
+		if i > 0 {
+			gapStart = items[i-1].CreatedAt.Add(time.Minute)
+		} else if last.ID != 0 && last.CreatedAt.Add(time.Minute).After(start) {
+			gapStart = last.CreatedAt.Add(time.Minute)
+		}
+		if i < len(items) {
+			gapEnd = items[i].CreatedAt
+		}
+
+		// historical candlesticks cover one minute, shorter gaps cannot be
+		// filled
+		if gapEnd.Sub(gapStart) < time.Minute {
+			continue
+		}
+
+		candlesticks, err := history(ctx, *platform, exchange, ticker,
+			gapStart.Truncate(time.Minute), gapEnd)
+		if err != nil {
+			return total, err
+		}
+
+		// only keep candlesticks that fit entirely inside the gap
+		previous := last
+		if i > 0 {
+			previous = items[i-1]
+		}
+
+		inserts := []market.Candlestick{}
+		for _, candlestick := range candlesticks {
+			if candlestick.CreatedAt.Before(gapStart) ||
+				candlestick.CreatedAt.Add(time.Minute).After(gapEnd) {
+				continue
+			}
+			candlestick.Exchange = exchange
+			candlestick.Ticker, candlestick.Quote = market.SplitTicker(ticker)
+			candlestick = candlestick.SetOpensAfter(previous)
+			inserts = append(inserts, candlestick)
+			previous = candlestick
+		}
+
+		if len(inserts) == 0 {
+			continue
+		}
+
+		inserted := 0
+		if err := db.Transaction(func(tx *gorm.DB) error {
+
+			var err error
+			if inserted, err = market.SaveCandlesticks(ctx, tx,
+				inserts); err != nil {
+				return err
+			}
+
+			if i == len(items) {
+				return nil
+			}
+
+			// the candlestick following the gap may no longer open a new hour
+			// or day
+			next := items[i].SetOpensAfter(previous)
+			if next.OpensHour == items[i].OpensHour &&
+				next.OpensDay == items[i].OpensDay {
+				return nil
+			}
+
+			return market.SaveCandlestick(ctx, tx, next)
+
+		}); err != nil {
+			return total, err
+		}
+
+		total += inserted
+
+	}
+
+	if total > 0 {
+		logrus.Infof("backfilled %d candlesticks for %s-%s", total, exchange,
+			ticker)
+	}
+
+	return total, nil
+
+}
+
+// historyForPlatform retrieves the historical price data source registered for
+// the platform of the supplied platform feed.
+func historyForPlatform(platform *PlatformFeed) (HistoryFunc, error) {
+
+	mutex.Lock()
+	history, ok := histories[platform.Platform.Key]
+	mutex.Unlock()
+
+	if !ok || platform.HistoryURL == "" {
+		return nil, ErrNoHistory
+	}
+
+	return history, nil
+
+}
+
+// backfillRecent fills gaps in the stored candlesticks of the supplied
+// platform feed over the lookback period, logging any errors. Feeds backfill
+// recent candlesticks when they connect or reconnect.
+func backfillRecent(platform PlatformFeed) {
+
+	end := time.Now()
+
+	if _, err := Backfill(context.Background(), data.DB(), &platform,
+		end.Add(-backfillLookback), end); err != nil && err != ErrNoHistory {
+		logrus.Error(err)
+	}
+
+}
+
+// getHistory sends a GET request to a historical price data API and decodes
+// the JSON response into out. Responses outside of the 2xx range are returned
+// as errors.
+func getHistory(ctx context.Context, url string, header http.Header,
+	out interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := historyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("history request failed with status %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, out)
+
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"mojito/data"
 	"mojito/market"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,21 +14,38 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
-// exchangeCoinbase is the name that will be used as the exchange name for any
-// candlesticks created through the Coinbase feed.
-const exchangeCoinbase = "COINBASE"
+// init registers the Coinbase feed.
+func init() {
+	Register(market.PlatformCoinbase, connectCoinbaseFeed)
+	RegisterHistory(market.PlatformCoinbase, coinbaseHistory)
+}
+
+const (
+	// exchangeCoinbase is the name that will be used as the exchange name for
+	// any candlesticks created through the Coinbase feed.
+	exchangeCoinbase = "COINBASE"
+	// coinbaseMaxCandles is the maximum number of candles returned by a single
+	// request to the Coinbase historical rates API.
+	coinbaseMaxCandles = 300
+	// coinbaseHistoryDelay is the time waited between requests to the Coinbase
+	// historical rates API to stay within its rate limit.
+	coinbaseHistoryDelay = 350 * time.Millisecond
+)
 
 // coinbaseFeed is used to stream price data from the Coinbase API.
 type coinbaseFeed struct {
-	mutex        *sync.Mutex
-	conn         *websocket.Conn
-	interval     time.Duration
-	candlesticks map[string]market.Candlestick
-	channels     map[string]chan market.Candlestick
-	close        bool
+	*aggregator
+	mutex    *sync.Mutex
+	session  *session
+	recorder *recorder
+	products map[string]bool
+
+	// the order book of each product is tracked if the platform feed is
+	// configured to track order books
+	orderBook bool
+	books     map[string]*OrderBook
 }
 
 // coinbaseSubscribeMessage is the payload used to subscribe to price data from
@@ -38,6 +56,13 @@ type coinbaseSubscribeMessage struct {
 	Channels   []string `json:"channels"`
 }
 
+// coinbaseErrorData is used to read an error reported by the Coinbase feed,
+// such as a subscribe request naming an unknown product.
+type coinbaseErrorData struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
 // coinbasePriceData is used to read price data from the Coinbase ticker feed.
 type coinbasePriceData struct {
 	Type      string    `json:"type"`
@@ -52,318 +77,381 @@ type coinbasePriceData struct {
 	BestAsk   string    `json:"best_ask"`
 }
 
-func (c *coinbaseFeed) GetChannel(exchange,
-	ticker string) (chan market.Candlestick, error) {
+// coinbaseLevel2Data is used to read order book snapshots and updates from the
+// Coinbase level2 feed. Snapshot levels are formatted as [price, size] and
+// updates as [side, price, size].
+type coinbaseLevel2Data struct {
+	Type      string      `json:"type"`
+	Time      time.Time   `json:"time"`
+	ProductID string      `json:"product_id"`
+	Bids      [][2]string `json:"bids"`
+	Asks      [][2]string `json:"asks"`
+	Changes   [][3]string `json:"changes"`
+}
+
+func (c *coinbaseFeed) AddSecurity(exchange, ticker string) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	key := formatCoinbaseFeedKey(exchange, ticker)
+	productID := coinbaseProductID(ticker)
 
-	// retrieve the channel for this ticker
-	channel, ok := c.channels[key]
-	if !ok {
-		// if the channel does not exist create it now
-		channel = make(chan market.Candlestick)
-		c.channels[key] = channel
+	if c.products[productID] {
+		// the feed is already subscribed to this security
+		return nil
 	}
 
-	return channel, nil
-}
+	// send the subscribe message
+	if err := c.session.send(coinbaseSubscribeMessage{
+		Type:       "subscribe",
+		ProductIDs: []string{productID},
+		Channels:   c.channels(),
+	}); err != nil {
+		return err
+	}
+
+	c.products[productID] = true
+	if c.orderBook {
+		c.books[productID] = newOrderBook(exchangeCoinbase,
+			market.FormatTicker(market.SplitTicker(ticker)))
+	}
 
-func (c *coinbaseFeed) AddSecurity(exchange,
-	ticker string) (chan market.Candlestick, error) {
-	key := formatCoinbaseFeedKey(exchange, ticker)
+	return nil
+}
 
+func (c *coinbaseFeed) RemoveSecurity(exchange, ticker string) error {
 	c.mutex.Lock()
-	if channel, ok := c.channels[key]; ok {
-		// if the channel for this security if it already exists
-		c.mutex.Unlock()
-		return channel, nil
-	}
 	defer c.mutex.Unlock()
 
-	// create the payload to subscribe to a new security
-	subscribeMessage := coinbaseSubscribeMessage{
-		Type:       "subscribe",
-		ProductIDs: []string{fmt.Sprintf("%s-USD", strings.ToUpper(ticker))},
-		Channels:   []string{"ticker"},
+	productID := coinbaseProductID(ticker)
+
+	if !c.products[productID] {
+		// the feed is not subscribed to this security
+		return nil
 	}
 
-	// send the subscribe message
-	if err := c.conn.WriteJSON(subscribeMessage); err != nil {
-		return nil, err
+	// send the unsubscribe message
+	if err := c.session.send(coinbaseSubscribeMessage{
+		Type:       "unsubscribe",
+		ProductIDs: []string{productID},
+		Channels:   c.channels(),
+	}); err != nil {
+		return err
 	}
 
-	// create a channel for the new candlesticks
-	channel := make(chan market.Candlestick)
-	c.channels[key] = channel
+	delete(c.products, productID)
+	delete(c.books, productID)
+	c.remove(exchange, ticker)
+
+	return nil
+}
+
+func (c *coinbaseFeed) Close() error {
+	return c.session.close()
+}
 
-	return channel, nil
+// restart reconnects the feed and resubscribes to each security it tracks.
+func (c *coinbaseFeed) restart() {
+	c.session.restart()
 }
 
-func (c *coinbaseFeed) Check(exchange,
-	ticker string) (market.Candlestick, error) {
+func (c *coinbaseFeed) OrderBook(exchange,
+	ticker string) (*OrderBook, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	// retrieve the candlestick for this ticker
-	candlestick, ok := c.candlesticks[formatCoinbaseFeedKey(exchange, ticker)]
-	if !ok {
-		return market.Candlestick{}, ErrTickerNotFound
+	book, ok := c.books[coinbaseProductID(ticker)]
+	if !ok || !strings.EqualFold(exchange, exchangeCoinbase) {
+		return nil, ErrOrderBookNotFound
 	}
 
-	// check that the candlestick contains data
-	if candlestick.Volume == 0 {
-		return market.Candlestick{}, ErrNoPriceData
-	}
+	return book, nil
+}
 
-	return candlestick, nil
+// channels lists the Coinbase channels the feed subscribes each product to.
+func (c *coinbaseFeed) channels() []string {
+	if c.orderBook {
+		return []string{"ticker", "level2"}
+	}
+	return []string{"ticker"}
 }
 
-func (c *coinbaseFeed) Commit(exchange,
-	ticker string) (market.Candlestick, error) {
+// subscribe subscribes the current connection of the feed to each product the
+// feed tracks. Each product is subscribed separately since Coinbase rejects a
+// subscribe request outright if any of its products is unknown.
+func (c *coinbaseFeed) subscribe() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	// format the key that will be used to get the candlestick associated with
-	// the specified ticker
-	key := formatCoinbaseFeedKey(exchange, ticker)
-
-	// retrieve the candlestick for this ticker
-	candlestick, ok := c.candlesticks[key]
-	if !ok {
-		return market.Candlestick{}, ErrTickerNotFound
-	}
-
-	// check that the candlestick contains price data
-	if candlestick.Volume == 0 {
-		return market.Candlestick{}, ErrNoPriceData
+	productIDList := []string{}
+	for productID := range c.products {
+		productIDList = append(productIDList, productID)
 	}
 
-	// check if this candlestick opens a new hour or a new day
-	last, err := market.GetLastByTicker(context.Background(), data.DB(),
-		strings.ToUpper(exchange), strings.ToUpper(ticker))
-	if err != nil && err != gorm.ErrRecordNotFound {
-		logrus.Error(err)
-	} else {
+	sort.Strings(productIDList)
 
-		if last.CreatedAt.Hour() != candlestick.CreatedAt.Hour() {
-			candlestick = candlestick.SetOpensHour(true)
+	for _, productID := range productIDList {
+		if err := c.session.send(coinbaseSubscribeMessage{
+			Type:       "subscribe",
+			ProductIDs: []string{productID},
+			Channels:   c.channels(),
+		}); err != nil {
+			return err
 		}
+	}
 
-		if last.CreatedAt.Day() != candlestick.CreatedAt.Day() {
-			candlestick = candlestick.SetOpensDay(true)
-		}
+	return nil
+}
 
-	}
+// handle aggregates price data from a message read from the Coinbase ticker
+// feed. Errors are logged, other messages that are not ticker messages are
+// ignored.
+func (c *coinbaseFeed) handle(message []byte) {
 
-	// save the candlestick
-	if err := market.SaveCandlestick(context.Background(), data.DB(),
-		candlestick); err != nil {
+	var priceData coinbasePriceData
+
+	// parse price data from the message
+	if err := json.Unmarshal(message, &priceData); err != nil {
 		logrus.Error(err)
-	} else {
-		logrus.Debugf("new candlestick: %v", candlestick)
+		return
 	}
 
-	// clear the candlestick data associated with this ticker
-	c.candlesticks[key] = market.Candlestick{
-		CreatedAt: time.Now(),
-		Exchange:  strings.ToUpper(exchange),
-		Ticker:    strings.ToUpper(ticker),
+	// order book messages update the order book of their product
+	if priceData.Type == "snapshot" || priceData.Type == "l2update" {
+		c.handleLevel2(message)
+		return
 	}
 
-	// send the candlestick to the candlestick channel if it exists
-	if channel, ok := c.channels[key]; ok {
-		channel <- candlestick
+	// report errors, such as a rejected subscription, rather than dropping
+	// them silently
+	if priceData.Type == "error" {
+		var errorData coinbaseErrorData
+		if err := json.Unmarshal(message, &errorData); err != nil {
+			logrus.Error(err)
+			return
+		}
+		logrus.Errorf("coinbase feed error: %s: %s", errorData.Message,
+			errorData.Reason)
+		return
 	}
 
-	// return the final candlestick data
-	return candlestick, nil
-}
-
-func (c *coinbaseFeed) Close() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.close = true
-	return nil
-}
-
-// aggregate updates the current candlestick will additional price data. Returns
-// whether the candlestick should be committed after aggregating the current
-// price data.
-func (c *coinbaseFeed) aggregate(exchange, ticker string,
-	priceData coinbasePriceData) bool {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	// skip any messages that aren't for ticker data
+	if priceData.Type != "ticker" {
+		return
+	}
 
-	// format the key that will be used to get the candlestick associated with
-	// the specified ticker
-	key := formatCoinbaseFeedKey(exchange, ticker)
+	// get the ticker from the price data, the quote currency is only included
+	// if it is not the default quote currency
+	ticker := market.FormatTicker(market.SplitTicker(priceData.ProductID))
 
 	// parse price from price data
 	currentPrice, err := strconv.ParseFloat(priceData.Price, 64)
 	if err != nil {
 		logrus.Errorf("%v: %v", err, priceData)
-		return false
+		return
 	}
 
-	// retrieve the candlestick for this ticker
-	candlestick, ok := c.candlesticks[key]
-	if !ok {
-		// if the candlestick is not found, initialize it now
-		candlestick = market.Candlestick{
-			CreatedAt: time.Now(),
-			Exchange:  strings.ToUpper(exchangeCoinbase),
-			Ticker:    strings.ToUpper(ticker),
+	// the ticker reports the size of the last trade and the side of its taker
+	size := parseOptionalFloat(priceData.LastSize)
+
+	c.recorder.record(Trade{
+		Time:     priceData.Time,
+		Exchange: exchangeCoinbase,
+		Ticker:   ticker,
+		TradeID:  int64(priceData.TradeID),
+		Sequence: priceData.Sequence,
+		Side:     priceData.Side,
+		Price:    currentPrice,
+		Size:     size,
+		BestBid:  parseOptionalFloat(priceData.BestBid),
+		BestAsk:  parseOptionalFloat(priceData.BestAsk),
+	})
+
+	// aggregate the price data and, if necessary, commit the current
+	// candlestick
+	if c.aggregate(exchangeCoinbase, ticker, currentPrice, size,
+		priceData.Side) {
+		if _, err := c.Commit(exchangeCoinbase, ticker); err != nil {
+			logrus.Error(err)
 		}
 	}
 
-	// increment the volume
-	candlestick = candlestick.Add(0, 0, 0, 0, 1)
+}
 
-	// set the close price
-	candlestick = candlestick.SetClose(currentPrice)
+// handleLevel2 applies an order book snapshot or update from the Coinbase
+// level2 feed to the order book of its product.
+func (c *coinbaseFeed) handleLevel2(message []byte) {
 
-	if candlestick.Open == 0.00 {
-		// if the open price has not been set, set it now
-		candlestick = candlestick.SetOpen(currentPrice)
+	var level2 coinbaseLevel2Data
+	if err := json.Unmarshal(message, &level2); err != nil {
+		logrus.Error(err)
+		return
 	}
 
-	if candlestick.Low == 0.00 || candlestick.Low > currentPrice {
-		// if the current price is lower than our low price or the low price
-		// has not been set, set it now
-		candlestick = candlestick.SetLow(currentPrice)
+	c.mutex.Lock()
+	book, ok := c.books[level2.ProductID]
+	c.mutex.Unlock()
+
+	if !ok {
+		return
 	}
 
-	if candlestick.High == 0.00 || candlestick.High < currentPrice {
-		// if the current price is higher than our high price or the high price
-		// has not been set, set it now
-		candlestick = candlestick.SetHigh(currentPrice)
+	if level2.Type == "snapshot" {
+		book.snapshot(parseCoinbaseLevels(level2.Bids),
+			parseCoinbaseLevels(level2.Asks), time.Now())
+		return
 	}
 
-	// update the candlestick for this ticker
-	c.candlesticks[key] = candlestick
+	for _, change := range level2.Changes {
+		book.update(change[0], parseOptionalFloat(change[1]),
+			parseOptionalFloat(change[2]), level2.Time)
+	}
 
-	// check if we should commit this candlestick
-	return time.Now().After(candlestick.CreatedAt.Add(c.interval))
 }
 
 // connectCoinbaseFeed connects to a feed of price data through the Coinbase
 // API.
-func connectCoinbaseFeed(platform platformFeed) (Feed, error) {
+func connectCoinbaseFeed(platform PlatformFeed) (Feed, error) {
 
-	productIDList := []string{}
+	feed := &coinbaseFeed{
+		aggregator: newAggregator(platform.Interval),
+		mutex:      &sync.Mutex{},
+		products:   map[string]bool{},
+		orderBook:  platform.OrderBook,
+		books:      map[string]*OrderBook{},
+	}
 
 	// build a list of Coinbase product ids from the platform spec
 	for _, security := range platform.Securities {
-		productID := fmt.Sprintf("%s-USD",
-			strings.ToUpper(security.Ticker))
-		productIDList = append(productIDList, productID)
+		productID := coinbaseProductID(security.Symbol())
+		feed.products[productID] = true
+		if feed.orderBook {
+			feed.books[productID] = newOrderBook(exchangeCoinbase,
+				security.Symbol())
+		}
 	}
 
-	// create the payload that will be used to initialize the connection to the
-	// Coinbase ticker feed
-	subscribeMessage := coinbaseSubscribeMessage{
-		Type:       "subscribe",
-		ProductIDs: productIDList,
-		Channels:   []string{"ticker"},
+	feed.session = newSession(feed.monitor, func() (*websocket.Conn, error) {
+		conn, _, err := websocket.DefaultDialer.Dial(platform.BaseURL, nil)
+		return conn, err
+	})
+	feed.session.subscribe = feed.subscribe
+
+	// trades were missed while the connection was down, drop the partial
+	// candlesticks and backfill the missed interval. Order books are cleared
+	// until Coinbase sends a new snapshot.
+	feed.session.onReconnect = func() {
+		feed.discard()
+		feed.mutex.Lock()
+		for _, book := range feed.books {
+			book.clear()
+		}
+		feed.mutex.Unlock()
+		go backfillRecent(platform)
 	}
 
-	// connect to the API websocket
-	conn, _, err := websocket.DefaultDialer.Dial(platform.BaseURL, nil)
-	if err != nil {
+	// connect to the API websocket and subscribe to the ticker feed
+	if err := feed.session.open(); err != nil {
 		return nil, err
 	}
 
-	// send the subscribe message
-	if err := conn.WriteJSON(subscribeMessage); err != nil {
-		conn.Close()
-		return nil, err
-	}
+	feed.recorder = newRecorder(platform)
 
-	feed := &coinbaseFeed{
-		mutex:        &sync.Mutex{},
-		conn:         conn,
-		interval:     platform.Interval,
-		candlesticks: map[string]market.Candlestick{},
-		channels:     map[string]chan market.Candlestick{},
-	}
+	go backfillRecent(platform)
 
 	// spawn a goroutine that continuously reads messages from the feed
 	go func() {
+		feed.session.read(feed.handle)
+		feed.recorder.close()
+	}()
 
-		for !feed.close {
-
-			// read a message from the feed
-			feed.mutex.Lock()
-			_, message, err := conn.ReadMessage()
-			feed.mutex.Unlock()
-			if err != nil {
-				logrus.Error(err)
-
-				for {
-					// if we run into an error attempting to read from the
-					// feed, close the existing connection and attempt to
-					// re-establish it after waiting for one minute
-					feed.mutex.Lock()
-					feed.conn.Close()
-					feed.mutex.Unlock()
-					time.Sleep(time.Minute)
-					if feed.close {
-						break
-					}
-
-					feed.mutex.Lock()
-					feed.conn, _, err = websocket.DefaultDialer.Dial(
-						platform.BaseURL, nil)
-					feed.mutex.Unlock()
-					if err != nil {
-						logrus.Error(err)
-					} else {
-						break
-					}
-				}
-
-				continue
-			}
+	return feed, nil
+}
 
-			var priceData coinbasePriceData
+// coinbaseHistory retrieves one minute candlesticks from the Coinbase
+// historical rates API. Coinbase only reports the base asset volume of each
+// candle, the other volume fields are left empty.
+func coinbaseHistory(ctx context.Context, platform PlatformFeed, exchange,
+	ticker string, start, end time.Time) ([]market.Candlestick, error) {
 
-			// parse price data from the message
-			if err := json.Unmarshal(message, &priceData); err != nil {
-				logrus.Error(err)
-			}
+	productID := coinbaseProductID(ticker)
 
-			// skip any messages that aren't for ticker data
-			if priceData.Type != "ticker" {
-				continue
-			}
+	items := []market.Candlestick{}
+	for pageStart := start; pageStart.Before(end); {
 
-			// get the ticker from the price data
-			ticker := strings.ToUpper(strings.Split(priceData.ProductID, "-")[0])
+		pageEnd := pageStart.Add(coinbaseMaxCandles * time.Minute)
+		if pageEnd.After(end) {
+			pageEnd = end
+		}
 
-			// aggregate the price data and, if necessary, commit the current
-			// candlestick
-			if feed.aggregate(exchangeCoinbase, ticker, priceData) {
-				_, err := feed.Commit(exchangeCoinbase, strings.ToUpper(ticker))
-				if err != nil {
-					logrus.Error(err)
-				}
-			}
+		query := url.Values{}
+		query.Set("granularity", "60")
+		query.Set("start", pageStart.UTC().Format(time.RFC3339))
+		query.Set("end", pageEnd.UTC().Format(time.RFC3339))
+
+		// each candle is formatted as [time, low, high, open, close, volume]
+		var candles [][6]float64
+		if err := getHistory(ctx, fmt.Sprintf("%s/products/%s/candles?%s",
+			strings.TrimSuffix(platform.HistoryURL, "/"), productID,
+			query.Encode()), nil, &candles); err != nil {
+			return nil, err
+		}
 
+		// candles are returned newest first
+		for i := len(candles) - 1; i >= 0; i-- {
+			candle := candles[i]
+			items = append(items, market.Candlestick{
+				CreatedAt: time.Unix(int64(candle[0]), 0),
+				Low:       candle[1],
+				High:      candle[2],
+				Open:      candle[3],
+				Close:     candle[4],
+				Volume:    candle[5],
+			})
 		}
 
-		feed.mutex.Lock()
-		conn.Close()
-		feed.mutex.Unlock()
+		pageStart = pageEnd
 
-	}()
+		if pageStart.Before(end) {
+			select {
+			case <-time.After(coinbaseHistoryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+	}
+
+	return items, nil
+
+}
+
+// coinbaseProductID gets the Coinbase product id of the supplied ticker, such
+// as BTC-USD. The ticker may include a quote currency as described by
+// market.SplitTicker.
+func coinbaseProductID(ticker string) string {
+	base, quote := market.SplitTicker(ticker)
+	return base + "-" + quote
+}
+
+// parseCoinbaseLevels parses the price levels of a Coinbase order book
+// snapshot.
+func parseCoinbaseLevels(levels [][2]string) []OrderBookLevel {
+
+	items := make([]OrderBookLevel, 0, len(levels))
+	for _, level := range levels {
+		items = append(items, OrderBookLevel{
+			Price: parseOptionalFloat(level[0]),
+			Size:  parseOptionalFloat(level[1]),
+		})
+	}
+
+	return items
 
-	return feed, nil
 }
 
-// formatCoinbaseFeedKey formats the supplied exchange and ticker into the
-// format that is used to track different securities in the Coinbase feed.
-func formatCoinbaseFeedKey(exchange, ticker string) string {
-	return strings.ToUpper(fmt.Sprintf("%s-%s", exchange, ticker))
+// parseOptionalFloat parses a number reported by the Coinbase API that may be
+// missing, returns zero if the number cannot be parsed.
+func parseOptionalFloat(value string) float64 {
+	number, _ := strconv.ParseFloat(value, 64)
+	return number
 }
@@ -0,0 +1,3 @@
+// Package delivery exposes an API for administering market data feeds and for
+// retrieving the order books tracked by them.
+package delivery
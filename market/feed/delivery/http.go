@@ -0,0 +1,201 @@
+package delivery
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market/feed"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// init registers the feed API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().GET(getOrderBookEndpoint, user.JWTAuthMiddleware(),
+		getOrderBook)
+	server.Router().GET(streamOrderBookEndpoint, user.JWTAuthMiddleware(),
+		streamOrderBook)
+
+	// bind admin endpoints
+	server.Router().POST(backfillEndpoint, user.JWTAdminMiddleware(), backfill)
+	server.Router().GET(feedHealthEndpoint, user.JWTAdminMiddleware(),
+		feedHealth)
+	server.Router().POST(createPlatformEndpoint, user.JWTAdminMiddleware(),
+		createPlatform)
+	server.Router().GET(listPlatformEndpoint, user.JWTAdminMiddleware(),
+		listPlatform)
+	server.Router().GET(getPlatformEndpoint, user.JWTAdminMiddleware(),
+		getPlatform)
+	server.Router().PUT(updatePlatformEndpoint, user.JWTAdminMiddleware(),
+		updatePlatform)
+	server.Router().DELETE(deletePlatformEndpoint, user.JWTAdminMiddleware(),
+		deletePlatform)
+	server.Router().POST(enablePlatformEndpoint, user.JWTAdminMiddleware(),
+		enablePlatform)
+	server.Router().POST(disablePlatformEndpoint, user.JWTAdminMiddleware(),
+		disablePlatform)
+	server.Router().POST(createSecurityEndpoint, user.JWTAdminMiddleware(),
+		createSecurity)
+	server.Router().PUT(updateSecurityEndpoint, user.JWTAdminMiddleware(),
+		updateSecurity)
+	server.Router().DELETE(deleteSecurityEndpoint, user.JWTAdminMiddleware(),
+		deleteSecurity)
+
+}
+
+const (
+	// backfillEndpoint the API endpoint used to fill gaps in stored
+	// candlesticks from exchange historical price data.
+	backfillEndpoint = "/feed/backfill"
+	// feedHealthEndpoint the API endpoint used to retrieve the connection state
+	// and activity of each connected feed.
+	feedHealthEndpoint = "/feed/health"
+	// maxBackfillRange the largest date range that may be backfilled at once.
+	maxBackfillRange = 31 * 24 * time.Hour
+)
+
+// backfillRunning records whether a backfill job started through the API is
+// running, only one may run at a time.
+var backfillRunning = struct {
+	sync.Mutex
+	running bool
+}{}
+
+// backfill starts a job that fills gaps in stored candlesticks from exchange
+// historical price data. The job runs in the background, the number of
+// candlesticks inserted is logged once it completes.
+func backfill(c *gin.Context) {
+
+	var req backfillRequest
+
+	// read request parameters, the request body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	// default to the automatic backfill lookback period
+	end := time.Now()
+	if req.End != nil {
+		end = *req.End
+	}
+
+	start := end.Add(-feed.BackfillLookback())
+	if req.Start != nil {
+		start = *req.Start
+	}
+
+	// validate request parameters
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "start date must be before end date",
+		})
+		return
+	}
+
+	if end.Sub(start) > maxBackfillRange {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "date range too large, at most 31 days may be backfilled",
+		})
+		return
+	}
+
+	if (req.Exchange == "") != (req.Ticker == "") {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker must be specified together",
+		})
+		return
+	}
+
+	if req.Exchange != "" {
+		if err := feed.CanBackfill(c, data.DB(), req.Exchange,
+			req.Ticker); err == feed.ErrTickerNotFound ||
+			err == feed.ErrNoHistory {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: err.Error(),
+			})
+			return
+		} else if err != nil {
+			logrus.Error(err)
+			c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+				ErrorMessage: httperror.InternalServerError,
+			})
+			return
+		}
+	}
+
+	backfillRunning.Lock()
+	defer backfillRunning.Unlock()
+
+	if backfillRunning.running {
+		c.JSON(http.StatusConflict, httperror.ErrorResponse{
+			ErrorMessage: "a backfill is already running",
+		})
+		return
+	}
+
+	backfillRunning.running = true
+
+	// the job outlives the request so it uses its own context
+	go func() {
+
+		defer func() {
+			backfillRunning.Lock()
+			backfillRunning.running = false
+			backfillRunning.Unlock()
+		}()
+
+		var count int
+		var err error
+		if req.Exchange != "" {
+			count, err = feed.BackfillSecurity(context.Background(), data.DB(),
+				req.Exchange, req.Ticker, start, end)
+		} else {
+			count, err = feed.BackfillAll(context.Background(), data.DB(),
+				start, end)
+		}
+
+		if err != nil {
+			logrus.Errorf("backfill failed after inserting %d candlesticks: %v",
+				count, err)
+			return
+		}
+
+		logrus.Infof("backfill inserted %d candlesticks", count)
+
+	}()
+
+	// respond with the job that was started
+	c.JSON(http.StatusAccepted, backfillResponse{
+		Exchange: strings.ToUpper(req.Exchange),
+		Ticker:   strings.ToUpper(req.Ticker),
+		Start:    start,
+		End:      end,
+	})
+
+}
+
+// feedHealth retrieves the connection state and activity of each connected
+// feed.
+func feedHealth(c *gin.Context) {
+
+	// respond with the health of each feed
+	c.JSON(http.StatusOK, feedHealthResponse{
+		StaleAfter: int(feed.StaleAfter() / time.Second),
+		Feeds:      feed.HealthAll(),
+	})
+
+}
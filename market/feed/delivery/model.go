@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"time"
+
+	"mojito/market/feed"
+)
+
+// backfillRequest is used to read a request to the backfill endpoint. All
+// fields are optional, if no security is specified every security tracked by
+// an enabled feed is backfilled.
+type backfillRequest struct {
+	Exchange string     `json:"exchange"`
+	Ticker   string     `json:"ticker"`
+	Start    *time.Time `json:"start"`
+	End      *time.Time `json:"end"`
+}
+
+// backfillResponse is used to format responses from the backfill endpoint. It
+// describes the backfill job that was started.
+type backfillResponse struct {
+	Exchange string    `json:"exchange,omitempty"`
+	Ticker   string    `json:"ticker,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+}
+
+// savePlatformRequest is used to read a request to the create and update
+// platform feed endpoints. The interval is read in nanoseconds, matching how
+// platform feeds are formatted in responses. Enabled and securities are only
+// read when creating a platform feed, use the enable, disable, and security
+// endpoints to change them afterwards.
+type savePlatformRequest struct {
+	Name       string                `json:"name"`
+	Platform   string                `json:"platform"`
+	BaseURL    string                `json:"base_url"`
+	HistoryURL string                `json:"history_url"`
+	Interval   time.Duration         `json:"interval"`
+	Record     string                `json:"record"`
+	OrderBook  bool                  `json:"order_book"`
+	Enabled    bool                  `json:"enabled"`
+	Securities []saveSecurityRequest `json:"securities"`
+}
+
+// saveSecurityRequest is used to read a security in a request to the platform
+// feed endpoints. The quote currency may also be supplied as part of the
+// ticker, such as BTC-EUR.
+type saveSecurityRequest struct {
+	Exchange string `json:"exchange"`
+	Ticker   string `json:"ticker"`
+	Quote    string `json:"quote"`
+}
+
+// feedHealthResponse is used to format responses from the feed health
+// endpoint. The stale after threshold is reported in seconds.
+type feedHealthResponse struct {
+	StaleAfter int           `json:"stale_after"`
+	Feeds      []feed.Health `json:"feeds"`
+}
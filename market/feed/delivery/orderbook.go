@@ -0,0 +1,180 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"mojito/httperror"
+	"mojito/market/feed"
+	"mojito/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// getOrderBookEndpoint the API endpoint used to retrieve the top of the
+	// order book of a security.
+	getOrderBookEndpoint = "/feed/orderbook"
+	// streamOrderBookEndpoint the API endpoint used to open a websocket that
+	// streams the top of the order book of a security as it changes.
+	streamOrderBookEndpoint = "/feed/orderbook/stream"
+	// defaultOrderBookDepth the number of price levels on each side of the
+	// order book returned if the request does not specify a depth.
+	defaultOrderBookDepth = 10
+	// maxOrderBookDepth the maximum number of price levels on each side of
+	// the order book that may be requested.
+	maxOrderBookDepth = 500
+	// orderBookStreamInterval how often the order book is checked for changes
+	// when streaming.
+	orderBookStreamInterval = 250 * time.Millisecond
+	// orderBookPingInterval how often the server pings the client to keep the
+	// connection alive.
+	orderBookPingInterval = 30 * time.Second
+	// orderBookPongWait how long the server waits for any message from the
+	// client before closing the connection.
+	orderBookPongWait = 60 * time.Second
+	// orderBookWriteWait how long the server waits to write a message.
+	orderBookWriteWait = 10 * time.Second
+)
+
+// getOrderBook retrieves the best bid and ask, spread, imbalance, and the price
+// levels on each side of the order book of a security up to the requested
+// depth.
+func getOrderBook(c *gin.Context) {
+
+	book, depth, ok := readOrderBook(c)
+	if !ok {
+		return
+	}
+
+	if !book.Ready() {
+		c.JSON(http.StatusServiceUnavailable, httperror.ErrorResponse{
+			ErrorMessage: "order book is not ready",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, book.Summary(depth))
+
+}
+
+// streamOrderBook upgrades the request to a websocket that streams the top of
+// the order book of a security up to the requested depth. A summary is sent
+// when the stream opens and whenever the order book changes, at most once per
+// stream interval.
+func streamOrderBook(c *gin.Context) {
+
+	book, depth, ok := readOrderBook(c)
+	if !ok {
+		return
+	}
+
+	conn, err := server.Upgrade(c)
+	if err != nil {
+		logrus.Debug(err)
+		return
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+
+	// read from the client so pongs and close messages are handled
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(orderBookPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(orderBookPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(orderBookPongWait))
+		}
+	}()
+
+	ticker := time.NewTicker(orderBookStreamInterval)
+	defer ticker.Stop()
+
+	lastPing := time.Now()
+	sequence := int64(-1)
+
+	for {
+
+		// send the order book if it has changed since it was last sent, order
+		// books are not sent until they are ready
+		if current := book.Sequence(); current != sequence && book.Ready() {
+			sequence = current
+			conn.SetWriteDeadline(time.Now().Add(orderBookWriteWait))
+			if err := conn.WriteJSON(book.Summary(depth)); err != nil {
+				logrus.Debug(err)
+				return
+			}
+		}
+
+		if time.Since(lastPing) >= orderBookPingInterval {
+			lastPing = time.Now()
+			conn.SetWriteDeadline(time.Now().Add(orderBookWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage,
+				nil); err != nil {
+				logrus.Debug(err)
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+	}
+
+}
+
+// readOrderBook gets the order book and depth specified by the exchange,
+// ticker, and depth query parameters. If the order book cannot be retrieved an
+// error response is written and false is returned.
+func readOrderBook(c *gin.Context) (*feed.OrderBook, int, bool) {
+
+	exchange, ticker := c.Query("exchange"), c.Query("ticker")
+	if exchange == "" || ticker == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker are required",
+		})
+		return nil, 0, false
+	}
+
+	depth := defaultOrderBookDepth
+	if value := c.Query("depth"); value != "" {
+		var err error
+		if depth, err = strconv.Atoi(value); err != nil || depth < 1 ||
+			depth > maxOrderBookDepth {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: "invalid depth",
+			})
+			return nil, 0, false
+		}
+	}
+
+	book, err := feed.GetOrderBook(exchange, ticker)
+	if err == feed.ErrExchangeNotFound || err == feed.ErrOrderBookNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: "order book not found",
+		})
+		return nil, 0, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, 0, false
+	}
+
+	return book, depth, true
+
+}
@@ -0,0 +1,616 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market"
+	"mojito/market/feed"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// createPlatformEndpoint the API endpoint used to create a new platform
+	// feed.
+	createPlatformEndpoint = "/feed/platform"
+	// listPlatformEndpoint the API endpoint used to retrieve all platform
+	// feeds.
+	listPlatformEndpoint = "/feed/platform"
+	// getPlatformEndpoint the API endpoint used to retrieve a platform feed.
+	getPlatformEndpoint = "/feed/platform/:id"
+	// updatePlatformEndpoint the API endpoint used to update a platform feed.
+	updatePlatformEndpoint = "/feed/platform/:id"
+	// deletePlatformEndpoint the API endpoint used to delete a platform feed.
+	deletePlatformEndpoint = "/feed/platform/:id"
+	// enablePlatformEndpoint the API endpoint used to enable and connect a
+	// platform feed.
+	enablePlatformEndpoint = "/feed/platform/:id/enable"
+	// disablePlatformEndpoint the API endpoint used to disable and disconnect
+	// a platform feed.
+	disablePlatformEndpoint = "/feed/platform/:id/disable"
+	// createSecurityEndpoint the API endpoint used to add a security to a
+	// platform feed.
+	createSecurityEndpoint = "/feed/platform/:id/security"
+	// updateSecurityEndpoint the API endpoint used to update a security of a
+	// platform feed.
+	updateSecurityEndpoint = "/feed/platform/:id/security/:security_id"
+	// deleteSecurityEndpoint the API endpoint used to remove a security from a
+	// platform feed.
+	deleteSecurityEndpoint = "/feed/platform/:id/security/:security_id"
+	// platformNotFound is an error message returned when the requested
+	// platform feed does not exist.
+	platformNotFound = "feed not found"
+	// securityNotFound is an error message returned when the requested
+	// security does not exist or belongs to another platform feed.
+	securityNotFound = "security not found"
+	// defaultInterval the interval covered by each candlestick if the interval
+	// is not specified.
+	defaultInterval = 60 * time.Second
+)
+
+// createPlatform creates a new platform feed along with its securities. The
+// feed is connected if it is enabled.
+func createPlatform(c *gin.Context) {
+
+	var req savePlatformRequest
+
+	item := &feed.PlatformFeed{}
+	if ok := readPlatformRequest(c, &req, item); !ok {
+		return
+	}
+
+	item.Enabled = req.Enabled
+
+	securities := []feed.PlatformFeedSecurity{}
+	for _, securityReq := range req.Securities {
+		security := feed.PlatformFeedSecurity{}
+		if ok := applySecurityRequest(c, securityReq, &security); !ok {
+			return
+		}
+		if ok := checkDuplicateSecurity(c, securities, security); !ok {
+			return
+		}
+		securities = append(securities, security)
+	}
+
+	if err := data.DB().Transaction(func(tx *gorm.DB) error {
+
+		if err := feed.SavePlatform(c, tx, item); err != nil {
+			return err
+		}
+
+		for i := range securities {
+			securities[i].PlatformFeedID = item.ID
+			if err := feed.SaveSecurity(c, tx, &securities[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	}); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	item.Securities = securities
+
+	applyPlatform(c, item)
+
+}
+
+// listPlatform retrieves all platform feeds.
+func listPlatform(c *gin.Context) {
+
+	items, err := feed.ListPlatform(c, data.DB(), nil)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with platform feeds
+	c.JSON(http.StatusOK, items)
+
+}
+
+// getPlatform retrieves a platform feed.
+func getPlatform(c *gin.Context) {
+
+	item, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	// respond with the platform feed
+	c.JSON(http.StatusOK, item)
+
+}
+
+// updatePlatform updates the configuration of a platform feed. A connected
+// feed is reconnected with the new configuration.
+func updatePlatform(c *gin.Context) {
+
+	item, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	previous := *item
+
+	var req savePlatformRequest
+	if ok := readPlatformRequest(c, &req, item); !ok {
+		return
+	}
+
+	if err := feed.SavePlatform(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// connected feeds are tracked by name, disconnect the feed under its old
+	// name if it was renamed
+	if previous.Name != item.Name {
+		feed.Disconnect(&previous)
+	}
+
+	applyPlatform(c, item)
+
+}
+
+// deletePlatform disconnects and deletes a platform feed.
+func deletePlatform(c *gin.Context) {
+
+	item, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	feed.Disconnect(item)
+
+	if err := feed.DeletePlatform(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the platform feed was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// enablePlatform enables and connects a platform feed.
+func enablePlatform(c *gin.Context) {
+	setPlatformEnabled(c, true)
+}
+
+// disablePlatform disables and disconnects a platform feed.
+func disablePlatform(c *gin.Context) {
+	setPlatformEnabled(c, false)
+}
+
+// setPlatformEnabled enables or disables the platform feed referenced by the
+// request path and applies the change to the running feed.
+func setPlatformEnabled(c *gin.Context, enabled bool) {
+
+	item, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	item.Enabled = enabled
+
+	if err := feed.SavePlatform(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	applyPlatform(c, item)
+
+}
+
+// createSecurity adds a security to a platform feed. A connected feed is
+// subscribed to the security.
+func createSecurity(c *gin.Context) {
+
+	platform, ok := readPlatform(c)
+	if !ok {
+		return
+	}
+
+	var req saveSecurityRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	item := feed.PlatformFeedSecurity{PlatformFeedID: platform.ID}
+	if ok := applySecurityRequest(c, req, &item); !ok {
+		return
+	}
+
+	if ok := checkDuplicateSecurity(c, platform.Securities, item); !ok {
+		return
+	}
+
+	if err := feed.SaveSecurity(c, data.DB(), &item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	platform.Securities = append(platform.Securities, item)
+
+	if err := feed.AddPlatformSecurity(platform, item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// respond with the new security
+	c.JSON(http.StatusOK, item)
+
+}
+
+// updateSecurity updates a security of a platform feed. A connected feed is
+// unsubscribed from the old security and subscribed to the new one.
+func updateSecurity(c *gin.Context) {
+
+	platform, item, ok := readSecurity(c)
+	if !ok {
+		return
+	}
+
+	previous := *item
+
+	var req saveSecurityRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	if ok := applySecurityRequest(c, req, item); !ok {
+		return
+	}
+
+	// the security may keep its own exchange and ticker
+	others := []feed.PlatformFeedSecurity{}
+	for _, security := range platform.Securities {
+		if security.ID != item.ID {
+			others = append(others, security)
+		}
+	}
+
+	if ok := checkDuplicateSecurity(c, others, *item); !ok {
+		return
+	}
+
+	if err := feed.SaveSecurity(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	platform.Securities = append(others, *item)
+
+	if !strings.EqualFold(previous.Exchange, item.Exchange) ||
+		previous.Symbol() != item.Symbol() {
+
+		if err := feed.RemovePlatformSecurity(platform, previous); err != nil {
+			logrus.Error(err)
+			c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+		if err := feed.AddPlatformSecurity(platform, *item); err != nil {
+			logrus.Error(err)
+			c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+				ErrorMessage: err.Error(),
+			})
+			return
+		}
+
+	}
+
+	// respond with the updated security
+	c.JSON(http.StatusOK, item)
+
+}
+
+// deleteSecurity removes a security from a platform feed. A connected feed is
+// unsubscribed from the security.
+func deleteSecurity(c *gin.Context) {
+
+	platform, item, ok := readSecurity(c)
+	if !ok {
+		return
+	}
+
+	if err := feed.DeleteSecurity(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	others := []feed.PlatformFeedSecurity{}
+	for _, security := range platform.Securities {
+		if security.ID != item.ID {
+			others = append(others, security)
+		}
+	}
+	platform.Securities = others
+
+	if err := feed.RemovePlatformSecurity(platform, *item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// respond with 200 - OK if the security was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// applyPlatform applies the configuration of a saved platform feed to the
+// running feeds and responds with the platform feed. If the feed cannot be
+// connected an error response is written instead.
+func applyPlatform(c *gin.Context, item *feed.PlatformFeed) {
+
+	if err := feed.Apply(item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadGateway, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// respond with the platform feed
+	c.JSON(http.StatusOK, item)
+
+}
+
+// readPlatform retrieves the platform feed referenced by the request path. If
+// the platform feed cannot be retrieved an error response is written and false
+// is returned.
+func readPlatform(c *gin.Context) (*feed.PlatformFeed, bool) {
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: platformNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the platform feed
+	item, err := feed.GetPlatformByID(c, data.DB(), uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: platformNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// readSecurity retrieves the platform feed and security referenced by the
+// request path. If either cannot be retrieved an error response is written and
+// false is returned.
+func readSecurity(c *gin.Context) (*feed.PlatformFeed,
+	*feed.PlatformFeedSecurity, bool) {
+
+	platform, ok := readPlatform(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("security_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: securityNotFound,
+		})
+		return nil, nil, false
+	}
+
+	// retrieve the security
+	item, err := feed.GetSecurityByID(c, data.DB(), platform.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: securityNotFound,
+		})
+		return nil, nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, nil, false
+	}
+
+	return platform, item, true
+
+}
+
+// readPlatformRequest reads and validates the platform feed configuration from
+// the request body and applies it to the supplied platform feed. If the request
+// is invalid an error response is written and false is returned.
+func readPlatformRequest(c *gin.Context, req *savePlatformRequest,
+	item *feed.PlatformFeed) bool {
+
+	// read request parameters
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return false
+	}
+
+	// validate request parameters
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "name is required",
+		})
+		return false
+	}
+
+	if req.BaseURL == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "base url is required",
+		})
+		return false
+	}
+
+	if req.Interval == 0 {
+		req.Interval = defaultInterval
+	} else if req.Interval < time.Minute || req.Interval%time.Minute != 0 {
+		// candlesticks open on the minute, a shorter interval would commit
+		// more than one candlestick for the same minute
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "interval must be a whole number of minutes",
+		})
+		return false
+	}
+
+	record := feed.RecordMode(strings.ToLower(req.Record))
+	if record != feed.RecordNone && record != feed.RecordFile &&
+		record != feed.RecordDatabase {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid record mode, expected file or database",
+		})
+		return false
+	}
+
+	// the name identifies the running feed so it must be unique
+	existing, err := feed.GetPlatformByName(c, data.DB(), req.Name)
+	if err == nil && existing.ID != item.ID {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "a feed with this name already exists",
+		})
+		return false
+	} else if err != nil && err != gorm.ErrRecordNotFound {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	platform, err := market.GetPlatformByKey(c, data.DB(),
+		market.PlatformKey(strings.ToLower(req.Platform)))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "unknown platform",
+		})
+		return false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	item.Name = req.Name
+	item.PlatformID = platform.ID
+	item.Platform = *platform
+	item.BaseURL = req.BaseURL
+	item.HistoryURL = req.HistoryURL
+	item.Interval = req.Interval
+	item.Record = record
+	item.OrderBook = req.OrderBook
+
+	return true
+
+}
+
+// applySecurityRequest validates a security from a request and applies it to
+// the supplied security. If the security is invalid an error response is
+// written and false is returned.
+func applySecurityRequest(c *gin.Context, req saveSecurityRequest,
+	item *feed.PlatformFeedSecurity) bool {
+
+	if req.Exchange == "" || req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange and ticker are required",
+		})
+		return false
+	}
+
+	// the quote currency may be supplied as part of the ticker
+	item.Exchange = strings.ToUpper(req.Exchange)
+	item.Ticker, item.Quote = market.SplitTicker(
+		market.FormatTicker(req.Ticker, req.Quote))
+
+	return true
+
+}
+
+// checkDuplicateSecurity checks that a security is not already tracked by a
+// platform feed. If it is an error response is written and false is returned.
+func checkDuplicateSecurity(c *gin.Context,
+	securities []feed.PlatformFeedSecurity,
+	item feed.PlatformFeedSecurity) bool {
+
+	for _, security := range securities {
+		if strings.EqualFold(security.Exchange, item.Exchange) &&
+			security.Symbol() == item.Symbol() {
+			c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+				ErrorMessage: "the feed already tracks this security",
+			})
+			return false
+		}
+	}
+
+	return true
+
+}
@@ -1,4 +1,57 @@
 // Package feed provides an interface for streaming market data. Feeds listen
 // for and aggregate real-time price data into candlesticks. The resulting
-// candlesticks are stored for future use.
+// candlesticks are stored for future use. Feed implementations register a
+// connector for their platform with Register, once the application calls Start
+// each enabled platform feed is connected using the connector registered for
+// its platform. When a feed connects or reconnects, gaps in the stored
+// candlesticks are backfilled from the historical price data API of its
+// platform. Platform feed securities may set a quote currency to track pairs
+// not priced in USD.
+//
+// The replay platform streams price data from a recording rather than an
+// exchange, so features downstream of a feed can be developed and tested
+// offline. A replay platform feed replaces the live feed serving the same
+// exchanges, candlesticks exported in the NDJSON format may be used as a
+// recording.
+//
+// Each platform feed may also record the individual trades it receives, either
+// to hourly gzip compressed NDJSON files or to the feed_trades table. Recording
+// files can be replayed by the replay platform.
+//
+// Feeds that stream from a websocket share a session that keeps the
+// connection alive with pings. When the connection fails the session
+// reconnects with jittered exponential backoff, from one second up to one
+// minute between attempts, and resubscribes to each security the feed tracks,
+// including securities added after the feed connected.
+//
+// Platform feeds may also track the level 2 order book of each security, the
+// total size resting at each price, if their platform supports it. Order books
+// are currently supported by the Coinbase feed. Use GetOrderBook to retrieve
+// the best bid and ask, spread, depth, and imbalance of an order book, such as
+// when choosing a limit price.
+//
+// Each feed reports its connection state, message rate, reconnects, and the
+// last candlestick committed for each security. Feeds that stop receiving
+// messages while connected are considered stale and are reconnected,
+// resubscribing to every security they track.
+//
+// Environment:
+//     MOJITO_ALPACA_API_KEY
+//         string - the API key used to authenticate with the Alpaca market data
+//                  API.
+//     MOJITO_ALPACA_SECRET_KEY
+//         string - the secret key used to authenticate with the Alpaca market
+//                  data API.
+//     MOJITO_BACKFILL_LOOKBACK
+//         int - the number of hours of price data checked for gaps when a feed
+//               connects or reconnects.
+//               Default: 24
+//     MOJITO_FEED_STALE_AFTER
+//         int - the number of seconds a connected feed may go without receiving
+//               a message before it is reported unhealthy and reconnected, zero
+//               disables staleness detection.
+//               Default: 120
+//     MOJITO_RECORD_DIR
+//         string - the directory that trade recording files are written to.
+//                  Default: recordings
 package feed
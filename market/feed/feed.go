@@ -1,29 +1,37 @@
 package feed
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"mojito/data"
 	"mojito/market"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
 // ErrNoPriceData is returned when a request is made for price data but the
-// current interval has no volume.
+// current interval has no trades.
 var ErrNoPriceData = errors.New("no price data for this interval")
 
 // ErrTickerNotFound is returned when a request is made for price data but the
 // specified exchange and ticker are not found in the feed.
 var ErrTickerNotFound = errors.New("ticker not found")
 
+// ErrExchangeNotFound is returned when a request is made for price data from an
+// exchange that is not served by any connected feed.
+var ErrExchangeNotFound = errors.New("exchange not found")
+
 // Feed encapsulates a stream of market data.
 type Feed interface {
-	// GetChannel retrieves a channel that will receive a candlestick every time
-	// the feed commits price data.
-	GetChannel(exchange, ticker string) (chan market.Candlestick, error)
-	// AddSecurity will subscribe the feed to a new ticker and return a channel
-	// that will receive a candlestick every time the feed commits price data.
-	AddSecurity(exchange, ticker string) (chan market.Candlestick, error)
+	// AddSecurity will subscribe the feed to a new ticker. Candlesticks
+	// committed for the ticker are published on the topic returned by Topic.
+	AddSecurity(exchange, ticker string) error
+	// RemoveSecurity will unsubscribe the feed from a ticker. The candlestick
+	// currently being aggregated for the ticker is discarded.
+	RemoveSecurity(exchange, ticker string) error
 	// Check retrieves the candlestick that is currently being aggregated.
 	Check(exchange, ticker string) (market.Candlestick, error)
 	// Commit saves the candlestick that is currently being aggregated and
@@ -32,52 +40,263 @@ type Feed interface {
 	// Close commits the current candlestick and stops listening for new price
 	// data.
 	Close() error
+	// Health reports the connection state and activity of the feed.
+	Health() Health
+}
+
+// ConnectorFunc establishes a connection to a platform feed.
+type ConnectorFunc func(platform PlatformFeed) (Feed, error)
+
+// UnknownPlatformError is returned when connecting to a platform feed for
+// which no connector has been registered.
+type UnknownPlatformError struct {
+	Platform market.PlatformKey
 }
 
+func (e *UnknownPlatformError) Error() string {
+	return fmt.Sprintf("feed is not defined for platform \"%s\"", e.Platform)
+}
+
+// connectors keeps track of the connector registered for each platform.
+var connectors = map[market.PlatformKey]ConnectorFunc{}
+
 // feeds keeps track of all feeds of price data.
 var feeds = map[string]Feed{}
 
+// exchanges maps each exchange to the name of the feed that serves it.
+var exchanges = map[string]string{}
+
+// tracked maps each security configured for a connected platform feed to the
+// name of the feed.
+var tracked = map[string]string{}
+
 // mutex is used to facilitate concurrent access to the map of feeds.
 var mutex = &sync.Mutex{}
 
+// ForExchange retrieves the connected feed that serves price data for the
+// specified exchange.
+func ForExchange(exchange string) (Feed, error) {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	name, ok := exchanges[strings.ToUpper(exchange)]
+	if !ok {
+		return nil, ErrExchangeNotFound
+	}
+
+	feed, ok := feeds[name]
+	if !ok {
+		return nil, ErrExchangeNotFound
+	}
+
+	return feed, nil
+
+}
+
+// Tracks determines whether the specified exchange and ticker is one of the
+// securities configured for a connected platform feed.
+func Tracks(exchange, ticker string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	_, ok := tracked[formatFeedKey(exchange, ticker)]
+	return ok
+}
+
+// Register makes a feed implementation available for connecting to platform
+// feeds on the specified platform. Feed implementations should call Register
+// from an init function so every connector is registered before Start.
+func Register(key market.PlatformKey, connector ConnectorFunc) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	connectors[key] = connector
+}
+
+// Start connects each enabled platform feed and begins reconnecting feeds that
+// stop receiving messages. Start should be called once by the application
+// after all packages are initialized.
+func Start() {
+
+	// retrieve all enabled platforms
+	platforms, err := ListPlatform(context.Background(), data.DB(),
+		ptrToBool(true))
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// initialize the feed for each platform
+	for _, platform := range platforms {
+		var unknown *UnknownPlatformError
+		if _, err := Connect(platform); errors.As(err, &unknown) {
+			logrus.Error(err)
+		} else if err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	// reconnect feeds that stop receiving messages
+	if staleAfter > 0 {
+		go watchStale()
+	}
+
+}
+
 // Connect establishes a new connection to the specified platform, if an
 // existing connection already exists it will be closed and replaced by the new
-// connection. If no platform integration exists this function will log a
-// warning and return nil for the feed and error.
-func Connect(platform *platformFeed) (Feed, error) {
+// connection. The existing connection is kept if the new connection cannot be
+// established. If no connector has been registered for the platform this
+// function returns an UnknownPlatformError.
+func Connect(platform *PlatformFeed) (Feed, error) {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// look up the connector based on the platform key
+	connector, ok := connectors[platform.Platform.Key]
+	if !ok {
+		return nil, &UnknownPlatformError{Platform: platform.Platform.Key}
+	}
+
+	feed, err := connector(*platform)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, ok := feeds[platform.Name]; ok {
+		// if a connection to this feed already exists, close the feed so it
+		// is replaced by the new connection
+		if err := existing.Close(); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	// add the feed to the map of feeds
+	feeds[platform.Name] = feed
+
+	// record which exchanges are served by the feed
+	routeExchanges(platform)
+
+	return feed, nil
+
+}
+
+// Apply brings the connection to the supplied platform feed in line with its
+// configuration. Enabled platform feeds are connected, replacing any existing
+// connection, while disabled platform feeds are disconnected.
+func Apply(platform *PlatformFeed) error {
+
+	if !platform.Enabled {
+		Disconnect(platform)
+		return nil
+	}
+
+	_, err := Connect(platform)
+	return err
+
+}
+
+// Disconnect closes the connection to the supplied platform feed, if any, and
+// stops routing requests for its exchanges to it.
+func Disconnect(platform *PlatformFeed) {
 
 	mutex.Lock()
 	defer mutex.Unlock()
 
 	if feed, ok := feeds[platform.Name]; ok {
-		// if a connection to this feed already exists, close the feed and
-		// remove it from the map of feeds
 		if err := feed.Close(); err != nil {
 			logrus.Error(err)
-			delete(feeds, platform.Name)
 		}
+		delete(feeds, platform.Name)
 	}
 
-	var feed Feed
-	var err error
+	for exchange, name := range exchanges {
+		if name == platform.Name {
+			delete(exchanges, exchange)
+		}
+	}
 
-	// connect to the appropriate feed based on the platform name
-	switch platform.Platform.Key {
-	case market.PlatformCoinbase:
-		feed, err = connectCoinbaseFeed(*platform)
-		if err != nil {
-			return nil, err
+	for key, name := range tracked {
+		if name == platform.Name {
+			delete(tracked, key)
 		}
-	default:
-		logrus.Warnf("feed \"%s\" is not defined", platform.Name)
 	}
 
-	if feed != nil {
-		// if we were able to initialize the feed, add it to the map of feeds
-		feeds[platform.Name] = feed
+}
+
+// AddPlatformSecurity subscribes the connected feed for the supplied platform
+// feed to a security. The security should already be included in the
+// securities of the platform feed. Does nothing if the platform feed is not
+// connected.
+func AddPlatformSecurity(platform *PlatformFeed,
+	security PlatformFeedSecurity) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	feed, ok := feeds[platform.Name]
+	if !ok {
+		return nil
+	}
+
+	if err := feed.AddSecurity(security.Exchange,
+		security.Symbol()); err != nil {
+		return err
 	}
 
-	return feed, nil
+	routeExchanges(platform)
+
+	return nil
+
+}
+
+// RemovePlatformSecurity unsubscribes the connected feed for the supplied
+// platform feed from a security. The security should already be removed from
+// the securities of the platform feed. Does nothing if the platform feed is not
+// connected.
+func RemovePlatformSecurity(platform *PlatformFeed,
+	security PlatformFeedSecurity) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	feed, ok := feeds[platform.Name]
+	if !ok {
+		return nil
+	}
+
+	if err := feed.RemoveSecurity(security.Exchange,
+		security.Symbol()); err != nil {
+		return err
+	}
+
+	routeExchanges(platform)
+
+	return nil
+
+}
+
+// routeExchanges records which exchanges and securities are served by the
+// supplied platform feed, replacing any previously recorded for it. Callers
+// must hold the mutex.
+func routeExchanges(platform *PlatformFeed) {
+
+	for exchange, name := range exchanges {
+		if name == platform.Name {
+			delete(exchanges, exchange)
+		}
+	}
+
+	for key, name := range tracked {
+		if name == platform.Name {
+			delete(tracked, key)
+		}
+	}
+
+	for _, security := range platform.Securities {
+		exchanges[strings.ToUpper(security.Exchange)] = platform.Name
+		tracked[formatFeedKey(security.Exchange,
+			security.Symbol())] = platform.Name
+	}
 
 }
 
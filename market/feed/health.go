@@ -0,0 +1,237 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConnectionState describes the connection between a feed and its platform.
+type ConnectionState string
+
+// Define feed connection states.
+const (
+	StateConnected    ConnectionState = "connected"    // the feed is receiving messages
+	StateReconnecting ConnectionState = "reconnecting" // the connection was lost and is being re-established
+	StateClosed       ConnectionState = "closed"       // the feed has stopped listening for messages
+)
+
+// rateWindow is the period over which the message rate of a feed is measured.
+const rateWindow = 10 * time.Second
+
+// staleAfter is how long a connected feed may go without receiving a message
+// before it is considered stale and reconnected. Zero disables staleness
+// detection.
+var staleAfter time.Duration
+
+// restarter is implemented by feeds that can re-establish their connection in
+// place, resubscribing to every security they track.
+type restarter interface {
+	restart()
+}
+
+// Health reports the state of a feed.
+type Health struct {
+	Name              string           `json:"name"`
+	State             ConnectionState  `json:"state"`
+	ConnectedAt       time.Time        `json:"connected_at"`
+	LastMessageAt     time.Time        `json:"last_message_at"`
+	MessagesPerSecond float64          `json:"messages_per_second"`
+	Reconnects        int              `json:"reconnects"`
+	Stale             bool             `json:"stale"`
+	Healthy           bool             `json:"healthy"`
+	Securities        []SecurityHealth `json:"securities"`
+}
+
+// SecurityHealth reports when a feed last committed a candlestick for a
+// security.
+type SecurityHealth struct {
+	Exchange     string    `json:"exchange"`
+	Ticker       string    `json:"ticker"`
+	LastCommitAt time.Time `json:"last_commit_at"`
+}
+
+// monitor tracks the health of a feed. Feed implementations report messages,
+// reconnects, and state changes to the monitor of their aggregator, commits are
+// reported by the aggregator itself. Health is measured against the wall clock
+// even for feeds that supply their own clock.
+type monitor struct {
+	mutex       *sync.Mutex
+	state       ConnectionState
+	connectedAt time.Time
+	lastMessage time.Time
+	reconnects  int
+	commits     map[string]SecurityHealth
+
+	// messages are counted over consecutive windows to measure the rate
+	windowStart time.Time
+	windowCount int
+	rate        float64
+
+	// idle reports times at which the feed is not expected to receive
+	// messages, such as outside of market hours
+	idle func(time.Time) bool
+}
+
+// newMonitor creates a monitor for a feed that has just connected.
+func newMonitor() *monitor {
+	now := time.Now()
+	return &monitor{
+		mutex:       &sync.Mutex{},
+		state:       StateConnected,
+		connectedAt: now,
+		windowStart: now,
+		commits:     map[string]SecurityHealth{},
+	}
+}
+
+// received records that the feed received a message.
+func (m *monitor) received() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	m.lastMessage = now
+	m.windowCount++
+
+	if elapsed := now.Sub(m.windowStart); elapsed >= rateWindow {
+		m.rate = float64(m.windowCount) / elapsed.Seconds()
+		m.windowStart, m.windowCount = now, 0
+	}
+}
+
+// setState records a change to the connection state of the feed.
+func (m *monitor) setState(state ConnectionState) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = state
+}
+
+// reconnected records that the feed re-established its connection.
+func (m *monitor) reconnected() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = StateConnected
+	m.connectedAt = time.Now()
+	m.reconnects++
+}
+
+// committed records that the feed committed a candlestick.
+func (m *monitor) committed(exchange, ticker string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.commits[formatFeedKey(exchange, ticker)] = SecurityHealth{
+		Exchange:     exchange,
+		Ticker:       ticker,
+		LastCommitAt: time.Now(),
+	}
+}
+
+// Health reports the state of the feed.
+func (m *monitor) Health() Health {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+
+	// the rate of the current window is reported once it is complete, or if
+	// no messages arrive to complete it
+	rate := m.rate
+	if elapsed := now.Sub(m.windowStart); elapsed >= rateWindow {
+		rate = float64(m.windowCount) / elapsed.Seconds()
+	}
+
+	// a connected feed is stale if it has not received a message within the
+	// threshold, measured from when it connected if it has received none
+	last := m.lastMessage
+	if last.Before(m.connectedAt) {
+		last = m.connectedAt
+	}
+	stale := m.state == StateConnected && staleAfter > 0 &&
+		now.Sub(last) > staleAfter && (m.idle == nil || !m.idle(now))
+
+	securities := []SecurityHealth{}
+	for _, security := range m.commits {
+		securities = append(securities, security)
+	}
+	sort.Slice(securities, func(i, j int) bool {
+		if securities[i].Exchange != securities[j].Exchange {
+			return securities[i].Exchange < securities[j].Exchange
+		}
+		return securities[i].Ticker < securities[j].Ticker
+	})
+
+	return Health{
+		State:             m.state,
+		ConnectedAt:       m.connectedAt,
+		LastMessageAt:     m.lastMessage,
+		MessagesPerSecond: rate,
+		Reconnects:        m.reconnects,
+		Stale:             stale,
+		Healthy:           m.state == StateConnected && !stale,
+		Securities:        securities,
+	}
+}
+
+// HealthAll reports the state of each connected feed, sorted by name.
+func HealthAll() []Health {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	items := []Health{}
+	for name, feed := range feeds {
+		health := feed.Health()
+		health.Name = name
+		items = append(items, health)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name < items[j].Name
+	})
+
+	return items
+
+}
+
+// StaleAfter gets how long a connected feed may go without receiving a message
+// before it is reconnected. Zero means staleness detection is disabled.
+func StaleAfter() time.Duration {
+	return staleAfter
+}
+
+// watchStale periodically reconnects feeds that have gone stale. Feeds are
+// reconnected in place so they keep every security subscribed since they
+// connected, feeds that cannot reconnect in place are only reported unhealthy.
+// Runs until the process exits.
+func watchStale() {
+
+	interval := staleAfter / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	for {
+
+		time.Sleep(interval)
+
+		mutex.Lock()
+		stale := map[string]restarter{}
+		for name, feed := range feeds {
+			if r, ok := feed.(restarter); ok && feed.Health().Stale {
+				stale[name] = r
+			}
+		}
+		mutex.Unlock()
+
+		for name, r := range stale {
+			logrus.Warnf("feed %s has not received a message in %v, reconnecting",
+				name, staleAfter)
+			r.restart()
+		}
+
+	}
+
+}
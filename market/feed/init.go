@@ -1,21 +1,29 @@
 package feed
 
 import (
-	"context"
 	"mojito/data"
+	"mojito/env"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm/clause"
 )
 
-// init migrates the package model and connects to any configured platform
-// feeds.
+// init migrates the package model and reads the feed configuration. Platform
+// feeds are connected by Start.
 func init() {
 
+	backfillLookback = time.Duration(env.GetIntSafe(backfillLookbackVariable,
+		24)) * time.Hour
+	recordDirectory = env.GetStringSafe(recordDirectoryVariable, "recordings")
+	staleAfter = time.Duration(env.GetIntSafe(staleAfterVariable,
+		120)) * time.Second
+
 	// migrate the package model
 	data.DB().AutoMigrate(
-		platformFeed{},
-		platformFeedSecurity{},
+		PlatformFeed{},
+		PlatformFeedSecurity{},
+		Trade{},
 	)
 
 	// load mock data if the server is configured to use it
@@ -39,18 +47,17 @@ func init() {
 
 	}
 
-	// retrieve all enabled platforms
-	platforms, err := ListPlatform(context.Background(), data.DB(),
-		ptrToBool(true))
-	if err != nil {
-		logrus.Fatal(err)
-	}
-
-	// initialize the feed for each platform
-	for _, platform := range platforms {
-		if _, err := Connect(platform); err != nil {
-			logrus.Fatal(err)
-		}
-	}
-
 }
+
+const (
+	// backfillLookbackVariable defines an environment variable for the number
+	// of hours of price data checked for gaps when feeds connect.
+	backfillLookbackVariable = "MOJITO_BACKFILL_LOOKBACK"
+	// recordDirectoryVariable defines an environment variable for the
+	// directory trade recordings are written to.
+	recordDirectoryVariable = "MOJITO_RECORD_DIR"
+	// staleAfterVariable defines an environment variable for the number of
+	// seconds a feed may go without receiving a message before it is
+	// reconnected.
+	staleAfterVariable = "MOJITO_FEED_STALE_AFTER"
+)
@@ -9,8 +9,8 @@ import (
 
 /* Data Types */
 
-// platformFeed stores configuration needed to connect to a feed of market data.
-type platformFeed struct {
+// PlatformFeed stores configuration needed to connect to a feed of market data.
+type PlatformFeed struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -19,42 +19,114 @@ type platformFeed struct {
 	PlatformID uint            `json:"platform_id"`
 	Platform   market.Platform `json:"platform"`
 
-	Name     string        `gorm:"index" json:"name"`
-	Enabled  bool          `json:"enabled"`
-	BaseURL  string        `json:"base_url"`
-	Interval time.Duration `json:"interval"`
+	Name       string        `gorm:"index" json:"name"`
+	Enabled    bool          `json:"enabled"`
+	BaseURL    string        `json:"base_url"`
+	HistoryURL string        `json:"history_url"` // the base URL used to backfill historical price data
+	Interval   time.Duration `json:"interval"`
+	Record     RecordMode    `json:"record"`     // where individual trades are recorded, if anywhere
+	OrderBook  bool          `json:"order_book"` // whether the order book of each security is tracked, if the platform supports it
 
-	Securities []platformFeedSecurity `json:"securities"`
+	Securities []PlatformFeedSecurity `json:"securities"`
 }
 
-// platformFeedSecurity stores information about the price data we want to
+// PlatformFeedSecurity stores information about the price data we want to
 // retrieve in a platform feed. Securities defined in this table will be
 // subscribed to when the feed is initialized.
-type platformFeedSecurity struct {
+type PlatformFeedSecurity struct {
 	ID             uint `gorm:"primarykey" json:"id"`
 	PlatformFeedID uint `json:"platform_feed_id"`
 
 	Exchange string `json:"exchange"`
 	Ticker   string `json:"ticker"`
+	Quote    string `json:"quote"` // the quote currency, defaults to market.DefaultQuote
+}
+
+// Symbol gets the ticker of this security including its quote currency, as
+// formatted by market.FormatTicker.
+func (s PlatformFeedSecurity) Symbol() string {
+	return market.FormatTicker(s.Ticker, s.Quote)
+}
+
+// RecordMode refers to where a platform feed records individual trades.
+type RecordMode string
+
+// Define supported record modes.
+const (
+	RecordNone     RecordMode = ""         // trades are not recorded
+	RecordFile     RecordMode = "file"     // trades are written to rotating compressed files
+	RecordDatabase RecordMode = "database" // trades are inserted into the feed_trades table
+)
+
+// Trade stores an individual trade reported by a platform feed. Fields that a
+// platform does not report are left empty.
+type Trade struct {
+	ID   uint      `gorm:"primarykey" json:"-"`
+	Time time.Time `gorm:"index" json:"time"`
+
+	Exchange string  `gorm:"index" json:"exchange"`
+	Ticker   string  `gorm:"index" json:"ticker"`
+	Quote    string  `gorm:"index" json:"quote"`
+	TradeID  int64   `json:"trade_id"`
+	Sequence int64   `json:"sequence"`
+	Side     string  `json:"side"`
+	Price    float64 `json:"price"`
+	Size     float64 `json:"size"`
+	BestBid  float64 `json:"best_bid"`
+	BestAsk  float64 `json:"best_ask"`
+}
+
+// TableName gets the name of the table recorded trades are stored in, kept
+// apart from the trades simulated by backtests.
+func (Trade) TableName() string {
+	return "feed_trades"
 }
 
 /* Mock Data */
 
-var mockFeedPlatforms = []platformFeed{
+var mockFeedPlatforms = []PlatformFeed{
 	{
 		ID:         1,
 		PlatformID: 1,
 		Enabled:    true,
 		BaseURL:    "wss://ws-feed.pro.coinbase.com",
+		HistoryURL: "https://api.pro.coinbase.com",
+		Interval:   60 * time.Second,
+	},
+	{
+		ID:         2,
+		PlatformID: 2,
+		Name:       "alpaca",
+		BaseURL:    "wss://stream.data.alpaca.markets/v2/iex",
+		HistoryURL: "https://data.alpaca.markets",
+		Interval:   60 * time.Second,
+	},
+	{
+		ID:         3,
+		PlatformID: 3,
+		Name:       "replay",
+		BaseURL:    "file:replay.ndjson?speed=60&loop=true",
 		Interval:   60 * time.Second,
 	},
 }
 
-var mockFeedPlatformSecurities = []platformFeedSecurity{
+var mockFeedPlatformSecurities = []PlatformFeedSecurity{
 	{
 		ID:             1,
 		PlatformFeedID: 1,
 		Exchange:       exchangeCoinbase,
 		Ticker:         "BTC",
 	},
+	{
+		ID:             2,
+		PlatformFeedID: 2,
+		Exchange:       string(market.ExchangeIEX),
+		Ticker:         "AAPL",
+	},
+	{
+		ID:             3,
+		PlatformFeedID: 3,
+		Exchange:       exchangeCoinbase,
+		Ticker:         "BTC",
+	},
 }
@@ -0,0 +1,325 @@
+package feed
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrOrderBookNotFound is returned when a request is made for the order book
+// of a security that the feed is not tracking an order book for.
+var ErrOrderBookNotFound = errors.New("order book not found")
+
+// OrderBookFeed is implemented by feeds that can track the order book of each
+// security they are subscribed to.
+type OrderBookFeed interface {
+	// OrderBook gets the order book of the specified security.
+	OrderBook(exchange, ticker string) (*OrderBook, error)
+}
+
+// OrderBookLevel is the total size of the orders resting at a price.
+type OrderBookLevel struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// OrderBookSummary describes the top of an order book at a point in time.
+type OrderBookSummary struct {
+	Exchange  string           `json:"exchange"`
+	Ticker    string           `json:"ticker"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	BestBid   float64          `json:"best_bid"`
+	BestAsk   float64          `json:"best_ask"`
+	Spread    float64          `json:"spread"`
+	Imbalance float64          `json:"imbalance"`
+	Bids      []OrderBookLevel `json:"bids"`
+	Asks      []OrderBookLevel `json:"asks"`
+}
+
+// OrderBook is an in-memory level 2 order book, the total size resting at
+// each price on each side of the book. Order books are kept up to date by the
+// feed that tracks them and are safe for concurrent use.
+type OrderBook struct {
+	mutex     *sync.Mutex
+	exchange  string
+	ticker    string
+	bids      *bookSide
+	asks      *bookSide
+	ready     bool
+	sequence  int64
+	updatedAt time.Time
+}
+
+// newOrderBook creates an empty order book for the supplied security. The
+// order book is not ready until a snapshot has been loaded.
+func newOrderBook(exchange, ticker string) *OrderBook {
+	return &OrderBook{
+		mutex:    &sync.Mutex{},
+		exchange: exchange,
+		ticker:   ticker,
+		bids:     newBookSide(true),
+		asks:     newBookSide(false),
+	}
+}
+
+// snapshot replaces the contents of the order book.
+func (b *OrderBook) snapshot(bids, asks []OrderBookLevel, t time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.bids = newBookSide(true)
+	b.bids.load(bids)
+	b.asks = newBookSide(false)
+	b.asks.load(asks)
+
+	b.ready = true
+	b.sequence++
+	b.updatedAt = t
+}
+
+// update sets the size resting at a price on one side of the order book, a
+// size of zero removes the price level. Buy updates apply to bids, any other
+// side applies to asks.
+func (b *OrderBook) update(side string, price, size float64, t time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if side == "buy" {
+		b.bids.set(price, size)
+	} else {
+		b.asks.set(price, size)
+	}
+
+	b.sequence++
+	b.updatedAt = t
+}
+
+// clear empties the order book, it is not ready again until a new snapshot is
+// loaded. Order books are cleared when the feed loses its connection.
+func (b *OrderBook) clear() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.bids = newBookSide(true)
+	b.asks = newBookSide(false)
+	b.ready = false
+	b.sequence++
+}
+
+// Ready checks whether the order book has been loaded and is being kept up to
+// date.
+func (b *OrderBook) Ready() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.ready
+}
+
+// Sequence gets a number that changes each time the order book is updated.
+func (b *OrderBook) Sequence() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.sequence
+}
+
+// BestBid gets the highest price a buyer is bidding, zero if there are no
+// bids.
+func (b *OrderBook) BestBid() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.bids.best()
+}
+
+// BestAsk gets the lowest price a seller is asking, zero if there are no asks.
+func (b *OrderBook) BestAsk() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.asks.best()
+}
+
+// Spread gets the difference between the best ask and the best bid, zero if
+// either side of the order book is empty.
+func (b *OrderBook) Spread() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return spread(b.bids.best(), b.asks.best())
+}
+
+// Depth gets up to the supplied number of price levels on each side of the
+// order book, best prices first. Bids are sorted from highest to lowest price
+// and asks from lowest to highest.
+func (b *OrderBook) Depth(levels int) (bids, asks []OrderBookLevel) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.bids.top(levels), b.asks.top(levels)
+}
+
+// Imbalance compares the size resting on each side of the order book over the
+// supplied number of price levels. Ranges from -1, when only asks are
+// resting, to 1, when only bids are resting.
+func (b *OrderBook) Imbalance(levels int) float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return imbalance(b.bids.top(levels), b.asks.top(levels))
+}
+
+// Summary describes the top of the order book over the supplied number of
+// price levels.
+func (b *OrderBook) Summary(levels int) OrderBookSummary {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bids, asks := b.bids.top(levels), b.asks.top(levels)
+	bestBid, bestAsk := b.bids.best(), b.asks.best()
+
+	return OrderBookSummary{
+		Exchange:  b.exchange,
+		Ticker:    b.ticker,
+		UpdatedAt: b.updatedAt,
+		BestBid:   bestBid,
+		BestAsk:   bestAsk,
+		Spread:    spread(bestBid, bestAsk),
+		Imbalance: imbalance(bids, asks),
+		Bids:      bids,
+		Asks:      asks,
+	}
+}
+
+// GetOrderBook gets the order book of the specified security from the feed
+// serving its exchange.
+func GetOrderBook(exchange, ticker string) (*OrderBook, error) {
+
+	feed, err := ForExchange(exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	books, ok := feed.(OrderBookFeed)
+	if !ok {
+		return nil, ErrOrderBookNotFound
+	}
+
+	return books.OrderBook(exchange, ticker)
+
+}
+
+// bookSide stores the price levels on one side of an order book. Prices are
+// kept sorted from best to worst as the book is updated so the top of the book
+// can be read without sorting every level.
+type bookSide struct {
+	bids   bool
+	prices []float64
+	sizes  map[float64]float64
+}
+
+// newBookSide creates an empty set of bids or asks.
+func newBookSide(bids bool) *bookSide {
+	return &bookSide{
+		bids:  bids,
+		sizes: map[float64]float64{},
+	}
+}
+
+// load adds the supplied price levels to an empty side of the book, levels
+// without size are ignored.
+func (s *bookSide) load(levels []OrderBookLevel) {
+
+	for _, level := range levels {
+		if level.Size <= 0 {
+			continue
+		}
+		if _, ok := s.sizes[level.Price]; !ok {
+			s.prices = append(s.prices, level.Price)
+		}
+		s.sizes[level.Price] = level.Size
+	}
+
+	sort.Slice(s.prices, func(i, j int) bool {
+		return s.better(s.prices[i], s.prices[j])
+	})
+
+}
+
+// set sets the size resting at a price, a size of zero removes the price level.
+func (s *bookSide) set(price, size float64) {
+
+	_, exists := s.sizes[price]
+	i := sort.Search(len(s.prices), func(i int) bool {
+		return !s.better(s.prices[i], price)
+	})
+
+	switch {
+	case size > 0 && exists:
+		s.sizes[price] = size
+	case size > 0:
+		s.sizes[price] = size
+		s.prices = append(s.prices, 0)
+		copy(s.prices[i+1:], s.prices[i:])
+		s.prices[i] = price
+	case exists:
+		delete(s.sizes, price)
+		s.prices = append(s.prices[:i], s.prices[i+1:]...)
+	}
+
+}
+
+// best gets the highest bid or the lowest ask, zero if the side is empty.
+func (s *bookSide) best() float64 {
+	if len(s.prices) == 0 {
+		return 0
+	}
+	return s.prices[0]
+}
+
+// top lists up to the supplied number of the best price levels, every level if
+// the limit is negative.
+func (s *bookSide) top(limit int) []OrderBookLevel {
+
+	if limit < 0 || limit > len(s.prices) {
+		limit = len(s.prices)
+	}
+
+	items := make([]OrderBookLevel, limit)
+	for i, price := range s.prices[:limit] {
+		items[i] = OrderBookLevel{Price: price, Size: s.sizes[price]}
+	}
+
+	return items
+
+}
+
+// better checks whether the first price is better than the second, higher for
+// bids and lower for asks.
+func (s *bookSide) better(a, b float64) bool {
+	if s.bids {
+		return a > b
+	}
+	return a < b
+}
+
+// spread gets the difference between the best ask and best bid, zero if either
+// is missing.
+func spread(bestBid, bestAsk float64) float64 {
+	if bestBid == 0 || bestAsk == 0 {
+		return 0
+	}
+	return bestAsk - bestBid
+}
+
+// imbalance compares the total size of the supplied bids and asks.
+func imbalance(bids, asks []OrderBookLevel) float64 {
+
+	var bidSize, askSize float64
+	for _, level := range bids {
+		bidSize += level.Size
+	}
+	for _, level := range asks {
+		askSize += level.Size
+	}
+
+	if bidSize+askSize == 0 {
+		return 0
+	}
+
+	return (bidSize - askSize) / (bidSize + askSize)
+
+}
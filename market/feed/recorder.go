@@ -0,0 +1,257 @@
+package feed
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordDirectory is the directory trade recordings are written to.
+var recordDirectory string
+
+const (
+	// recorderBufferSize is the number of trades that may be queued for
+	// recording before further trades are dropped.
+	recorderBufferSize = 4096
+	// recorderBatchSize is the largest number of trades written at once.
+	recorderBatchSize = 500
+	// recorderFlushInterval is how often queued trades are written.
+	recorderFlushInterval = time.Second
+)
+
+// recorder writes the individual trades reported by a feed to rotating
+// compressed files or the feed_trades table. Trades are queued and written in
+// batches so recording never holds up the feed. A nil recorder records
+// nothing.
+type recorder struct {
+	mode   RecordMode
+	name   string
+	mutex  *sync.Mutex
+	trades chan Trade
+	done   chan struct{}
+	closed bool
+
+	// the file currently being written, files are rotated every hour
+	hour time.Time
+	file *os.File
+	gzip *gzip.Writer
+}
+
+// tradeRecord is used to format a trade in a recording file. The type matches
+// the trade records read by the replay feed.
+type tradeRecord struct {
+	Type string `json:"type"`
+	Trade
+}
+
+// newRecorder creates a recorder for the supplied platform feed. Returns nil
+// if the platform feed does not record trades.
+func newRecorder(platform PlatformFeed) *recorder {
+
+	if platform.Record == RecordNone {
+		return nil
+	}
+
+	name := platform.Name
+	if name == "" {
+		name = string(platform.Platform.Key)
+	}
+
+	r := &recorder{
+		mode:   platform.Record,
+		name:   name,
+		mutex:  &sync.Mutex{},
+		trades: make(chan Trade, recorderBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+
+}
+
+// record queues a trade to be recorded. The trade is dropped if the queue is
+// full.
+func (r *recorder) record(trade Trade) {
+
+	if r == nil {
+		return
+	}
+
+	// the ticker may include the quote currency, it is stored separately
+	trade.Exchange = strings.ToUpper(trade.Exchange)
+	trade.Ticker, trade.Quote = market.SplitTicker(
+		market.FormatTicker(trade.Ticker, trade.Quote))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.trades <- trade:
+	default:
+		logrus.Warnf("recorder %s is full, dropping trade", r.name)
+	}
+
+}
+
+// close writes any queued trades and stops the recorder.
+func (r *recorder) close() {
+
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.trades)
+	r.mutex.Unlock()
+
+	<-r.done
+
+}
+
+// run writes queued trades in batches until the recorder is closed.
+func (r *recorder) run() {
+
+	defer close(r.done)
+
+	ticker := time.NewTicker(recorderFlushInterval)
+	defer ticker.Stop()
+
+	batch := []Trade{}
+	for {
+		select {
+		case trade, ok := <-r.trades:
+			if !ok {
+				r.write(batch)
+				r.closeFile()
+				return
+			}
+			if batch = append(batch, trade); len(batch) >= recorderBatchSize {
+				r.write(batch)
+				batch = []Trade{}
+			}
+		case <-ticker.C:
+			r.write(batch)
+			batch = []Trade{}
+		}
+	}
+
+}
+
+// write records a batch of trades, logging any errors.
+func (r *recorder) write(batch []Trade) {
+
+	if len(batch) == 0 {
+		return
+	}
+
+	var err error
+	switch r.mode {
+	case RecordDatabase:
+		err = SaveTrades(context.Background(), data.DB(), batch)
+	case RecordFile:
+		err = r.writeFile(batch)
+	default:
+		err = fmt.Errorf("unknown record mode \"%s\"", r.mode)
+	}
+
+	if err != nil {
+		logrus.Error(err)
+	}
+
+}
+
+// writeFile appends a batch of trades to the recording file for the hour in
+// which each trade occurred. Each file is a gzip compressed stream of newline
+// delimited JSON, a file reopened after a restart gains an additional gzip
+// member which readers handle transparently.
+func (r *recorder) writeFile(batch []Trade) error {
+
+	for _, trade := range batch {
+
+		if hour := trade.Time.UTC().Truncate(time.Hour); r.file == nil ||
+			!hour.Equal(r.hour) {
+			if err := r.rotate(hour); err != nil {
+				return err
+			}
+		}
+
+		line, err := json.Marshal(tradeRecord{Type: "trade", Trade: trade})
+		if err != nil {
+			return err
+		}
+
+		if _, err := r.gzip.Write(append(line, '\n')); err != nil {
+			return err
+		}
+
+	}
+
+	// flush so the recording can be read while it is being written
+	return r.gzip.Flush()
+
+}
+
+// rotate closes the current recording file and opens the file for the
+// specified hour.
+func (r *recorder) rotate(hour time.Time) error {
+
+	r.closeFile()
+
+	if err := os.MkdirAll(recordDirectory, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(recordDirectory, fmt.Sprintf("%s-%s.ndjson.gz",
+		r.name, hour.Format("20060102-15")))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	r.hour, r.file, r.gzip = hour, file, gzip.NewWriter(file)
+
+	return nil
+
+}
+
+// closeFile closes the current recording file, if any.
+func (r *recorder) closeFile() {
+
+	if r.file == nil {
+		return
+	}
+
+	if err := r.gzip.Close(); err != nil {
+		logrus.Error(err)
+	}
+
+	if err := r.file.Close(); err != nil {
+		logrus.Error(err)
+	}
+
+	r.file, r.gzip = nil, nil
+
+}
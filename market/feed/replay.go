@@ -0,0 +1,454 @@
+package feed
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/market"
+
+	"github.com/sirupsen/logrus"
+)
+
+// init registers the replay feed.
+func init() {
+	Register(market.PlatformReplay, connectReplayFeed)
+}
+
+// replayFeed is used to stream price data from a recording. The base URL of
+// the platform feed names the recording and may set the following query
+// parameters:
+//     speed - how many times faster than real time the recording is replayed,
+//             zero replays the recording as fast as possible. Default: 1
+//     loop  - whether the recording restarts once it ends. Default: false
+//
+// Recordings are newline delimited JSON and may be gzip compressed if the file
+// name ends in .gz. Each line holds a Coinbase ticker message, a trade record
+// with type "trade", or a candlestick as exported in the NDJSON format. The
+// times in the recording drive the clock of the feed, so candlesticks are
+// committed with their recorded times.
+type replayFeed struct {
+	*aggregator
+	mutex      *sync.Mutex
+	securities map[string]bool
+	close      bool
+
+	// the recording is replayed against a virtual clock that starts at the
+	// time of the first record
+	recordStart time.Time
+	wallStart   time.Time
+	speed       float64
+}
+
+// replayRecord is used to read a line of a recording. The fields used depend
+// on the type of record.
+type replayRecord struct {
+	Type string `json:"type"`
+
+	// ticker messages and trade records
+	Time      time.Time       `json:"time"`
+	ProductID string          `json:"product_id"`
+	Price     json.RawMessage `json:"price"`
+	LastSize  json.RawMessage `json:"last_size"`
+	Size      json.RawMessage `json:"size"`
+	Side      string          `json:"side"`
+
+	// trade records and candlesticks
+	Exchange string `json:"exchange"`
+	Ticker   string `json:"ticker"`
+	Quote    string `json:"quote"`
+
+	// candlesticks
+	CreatedAt time.Time `json:"created_at"`
+	Open      float64   `json:"open"`
+	Close     float64   `json:"close"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Volume    float64   `json:"volume"`
+
+	QuoteVolume float64 `json:"quote_volume"`
+	TradeCount  int     `json:"trade_count"`
+	BuyVolume   float64 `json:"buy_volume"`
+	SellVolume  float64 `json:"sell_volume"`
+}
+
+// replayClockStep is the longest the replay feed sleeps before checking for
+// candlesticks whose interval has ended.
+const replayClockStep = time.Second
+
+func (r *replayFeed) AddSecurity(exchange, ticker string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.securities[formatFeedKey(exchange, ticker)] = true
+	return nil
+}
+
+func (r *replayFeed) RemoveSecurity(exchange, ticker string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.securities, formatFeedKey(exchange, ticker))
+	r.remove(exchange, ticker)
+	return nil
+}
+
+func (r *replayFeed) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.close = true
+	return nil
+}
+
+// now gets the current time on the virtual clock of the recording.
+func (r *replayFeed) now() time.Time {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.recordStart.IsZero() {
+		return time.Now()
+	}
+
+	if r.speed == 0 {
+		return r.recordStart
+	}
+
+	elapsed := float64(time.Since(r.wallStart)) * r.speed
+	return r.recordStart.Add(time.Duration(elapsed))
+}
+
+// closed checks whether the feed has been closed.
+func (r *replayFeed) closed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.close
+}
+
+// wait blocks until the virtual clock reaches the supplied time, committing
+// candlesticks whose interval ends in the meantime. Returns false if the feed
+// is closed while waiting.
+func (r *replayFeed) wait(t time.Time) bool {
+
+	// when replaying as fast as possible the clock follows the records
+	if r.speed == 0 {
+		r.mutex.Lock()
+		r.recordStart = t
+		r.mutex.Unlock()
+		r.flush()
+		return !r.closed()
+	}
+
+	for {
+
+		if r.closed() {
+			return false
+		}
+
+		r.flush()
+
+		remaining := t.Sub(r.now())
+		if remaining <= 0 {
+			return true
+		}
+
+		sleep := time.Duration(float64(remaining) / r.speed)
+		if sleep > replayClockStep {
+			sleep = replayClockStep
+		}
+		time.Sleep(sleep)
+
+	}
+
+}
+
+// flush commits each candlestick whose interval has ended on the virtual
+// clock.
+func (r *replayFeed) flush() {
+	for _, candlestick := range r.expired() {
+		if _, err := r.Commit(candlestick.Exchange,
+			candlestick.Symbol()); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+// play replays a recording once. The offset is added to each recorded time.
+// Returns the times of the first and last records replayed.
+func (r *replayFeed) play(path string, offset time.Duration) (first,
+	last time.Time, err error) {
+
+	reader, err := openRecording(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		r.received()
+
+		var record replayRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			logrus.Error(err)
+			continue
+		}
+
+		exchange, ticker, recordTime, ok := record.security()
+		if !ok {
+			continue
+		}
+		recordTime = recordTime.Add(offset)
+
+		r.mutex.Lock()
+		tracked := r.securities[formatFeedKey(exchange, ticker)]
+		if r.recordStart.IsZero() {
+			r.recordStart, r.wallStart = recordTime, time.Now()
+		}
+		r.mutex.Unlock()
+
+		if !tracked {
+			continue
+		}
+
+		if !r.wait(recordTime) {
+			return first, last, nil
+		}
+
+		if first.IsZero() {
+			first = recordTime
+		}
+		last = recordTime
+
+		if record.Type == "ticker" || record.Type == "trade" {
+
+			price, err := parseReplayFloat(record.Price)
+			if err != nil {
+				logrus.Errorf("%v: %s", err, line)
+				continue
+			}
+
+			// ticker messages report the size of the last trade
+			sizeData := record.Size
+			if record.Type == "ticker" {
+				sizeData = record.LastSize
+			}
+
+			size, err := parseReplayFloat(sizeData)
+			if err != nil {
+				size = 0
+			}
+
+			if r.aggregate(exchange, ticker, price, size, record.Side) {
+				if _, err := r.Commit(exchange, ticker); err != nil {
+					logrus.Error(err)
+				}
+			}
+
+			continue
+
+		}
+
+		r.load(market.Candlestick{
+			CreatedAt: recordTime,
+			Exchange:  exchange,
+			Ticker:    ticker,
+			Open:      record.Open,
+			Close:     record.Close,
+			High:      record.High,
+			Low:       record.Low,
+			Volume:    record.Volume,
+
+			QuoteVolume: record.QuoteVolume,
+			TradeCount:  record.TradeCount,
+			BuyVolume:   record.BuyVolume,
+			SellVolume:  record.SellVolume,
+		})
+
+	}
+
+	return first, last, scanner.Err()
+
+}
+
+// security gets the security and time of a record. Returns false if the
+// record does not hold price data.
+func (r replayRecord) security() (string, string, time.Time, bool) {
+
+	switch {
+	case r.Type == "ticker" && r.ProductID != "":
+		return exchangeCoinbase,
+			market.FormatTicker(market.SplitTicker(r.ProductID)), r.Time, true
+	case r.Type == "trade" && r.Exchange != "" && r.Ticker != "":
+		return strings.ToUpper(r.Exchange), market.FormatTicker(r.Ticker,
+			r.Quote), r.Time, true
+	case r.Type == "" && r.Exchange != "" && r.Ticker != "" &&
+		!r.CreatedAt.IsZero():
+		return strings.ToUpper(r.Exchange), market.FormatTicker(r.Ticker,
+			r.Quote), r.CreatedAt, true
+	}
+
+	return "", "", time.Time{}, false
+
+}
+
+// parseReplayFloat reads a number from a record, numbers may be formatted as
+// JSON numbers or strings.
+func parseReplayFloat(value json.RawMessage) (float64, error) {
+	return strconv.ParseFloat(strings.Trim(string(value), "\""), 64)
+}
+
+// openRecording opens a recording for reading, decompressing it if the file
+// name ends in .gz.
+func openRecording(path string) (io.ReadCloser, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, file}, nil
+
+}
+
+// parseReplayURL reads the recording path and replay options from the base
+// URL of a platform feed.
+func parseReplayURL(baseURL string) (path string, speed float64, loop bool,
+	err error) {
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	if u.Scheme != "" && u.Scheme != "file" {
+		return "", 0, false, errors.New("replay feed expects a file url")
+	}
+
+	path = u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+
+	if path == "" {
+		return "", 0, false, errors.New("replay feed recording not specified")
+	}
+
+	speed = 1
+	if value := u.Query().Get("speed"); value != "" {
+		if speed, err = strconv.ParseFloat(value, 64); err != nil || speed < 0 {
+			return "", 0, false, errors.New("invalid replay speed")
+		}
+	}
+
+	if value := u.Query().Get("loop"); value != "" {
+		if loop, err = strconv.ParseBool(value); err != nil {
+			return "", 0, false, errors.New("invalid replay loop flag")
+		}
+	}
+
+	return path, speed, loop, nil
+
+}
+
+// connectReplayFeed connects to a feed of price data replayed from a
+// recording.
+func connectReplayFeed(platform PlatformFeed) (Feed, error) {
+
+	path, speed, loop, err := parseReplayURL(platform.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// check that the recording can be read before replaying it
+	reader, err := openRecording(path)
+	if err != nil {
+		return nil, err
+	}
+	reader.Close()
+
+	feed := &replayFeed{
+		aggregator: newAggregator(platform.Interval),
+		mutex:      &sync.Mutex{},
+		securities: map[string]bool{},
+		speed:      speed,
+	}
+	feed.clock = feed.now
+
+	// the pace of a recording is set by its records, gaps between them do not
+	// mean the feed has stopped
+	feed.idle = func(time.Time) bool {
+		return true
+	}
+
+	// track each security in the platform spec
+	for _, security := range platform.Securities {
+		feed.securities[formatFeedKey(security.Exchange,
+			security.Symbol())] = true
+	}
+
+	// spawn a goroutine that replays the recording
+	go func() {
+
+		var offset time.Duration
+		for {
+
+			first, last, err := feed.play(path, offset)
+			if err != nil {
+				logrus.Error(err)
+				return
+			}
+
+			if !loop || feed.closed() || last.IsZero() {
+				break
+			}
+
+			// shift the next pass so its records follow the last record of
+			// this pass
+			offset += last.Sub(first) + platform.Interval
+
+		}
+
+		// commit the final candlesticks of the recording
+		feed.mutex.Lock()
+		feed.close = true
+		feed.mutex.Unlock()
+		feed.setState(StateClosed)
+
+		for _, candlestick := range feed.current() {
+			if _, err := feed.Commit(candlestick.Exchange,
+				candlestick.Symbol()); err != nil {
+				logrus.Error(err)
+			}
+		}
+
+	}()
+
+	return feed, nil
+}
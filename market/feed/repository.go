@@ -9,11 +9,11 @@ import (
 // ListPlatform retrieves all feed platforms, takes an optional flag that can be
 // used to filter by enabled platforms.
 func ListPlatform(ctx context.Context, db *gorm.DB,
-	enabled *bool) ([]*platformFeed, error) {
+	enabled *bool) ([]*PlatformFeed, error) {
 
-	var items []*platformFeed
+	var items []*PlatformFeed
 
-	res := db.Preload("Platform").Preload("Securities").Model(&platformFeed{})
+	res := db.Preload("Platform").Preload("Securities").Model(&PlatformFeed{})
 
 	if enabled != nil {
 		res = res.Where("enabled = ?", *enabled)
@@ -26,3 +26,95 @@ func ListPlatform(ctx context.Context, db *gorm.DB,
 	return items, nil
 
 }
+
+// GetPlatformByID retrieves a platform feed record by id along with its
+// platform and securities.
+func GetPlatformByID(ctx context.Context, db *gorm.DB,
+	id uint) (*PlatformFeed, error) {
+
+	var item PlatformFeed
+
+	if err := db.Preload("Platform").Preload("Securities").
+		Model(&PlatformFeed{}).
+		Where("id = ?", id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// GetPlatformByName retrieves a platform feed record by name.
+func GetPlatformByName(ctx context.Context, db *gorm.DB,
+	name string) (*PlatformFeed, error) {
+
+	var item PlatformFeed
+
+	if err := db.Model(&PlatformFeed{}).
+		Where("name = ?", name).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// SavePlatform inserts or updates the supplied platform feed record. The
+// securities of the platform feed are saved separately.
+func SavePlatform(ctx context.Context, db *gorm.DB, item *PlatformFeed) error {
+	return db.Omit("Platform", "Securities").Save(item).Error
+}
+
+// DeletePlatform deletes the supplied platform feed record along with its
+// securities.
+func DeletePlatform(ctx context.Context, db *gorm.DB, item *PlatformFeed) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("platform_feed_id = ?", item.ID).
+			Delete(&PlatformFeedSecurity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(item).Error
+	})
+}
+
+// GetSecurityByID retrieves a platform feed security record by id. The security
+// must belong to the specified platform feed.
+func GetSecurityByID(ctx context.Context, db *gorm.DB, platformFeedID,
+	id uint) (*PlatformFeedSecurity, error) {
+
+	var item PlatformFeedSecurity
+
+	if err := db.Model(&PlatformFeedSecurity{}).
+		Where("platform_feed_id = ? AND id = ?", platformFeedID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// SaveSecurity inserts or updates the supplied platform feed security record.
+func SaveSecurity(ctx context.Context, db *gorm.DB,
+	item *PlatformFeedSecurity) error {
+	return db.Save(item).Error
+}
+
+// DeleteSecurity deletes the supplied platform feed security record.
+func DeleteSecurity(ctx context.Context, db *gorm.DB,
+	item *PlatformFeedSecurity) error {
+	return db.Delete(item).Error
+}
+
+// SaveTrades inserts the supplied trade records.
+func SaveTrades(ctx context.Context, db *gorm.DB, items []Trade) error {
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	return db.Create(&items).Error
+
+}
@@ -0,0 +1,308 @@
+package feed
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// sessionPingInterval how often a session pings the platform to keep the
+	// connection alive.
+	sessionPingInterval = 30 * time.Second
+	// sessionPongWait how long a session waits for any message from the
+	// platform before treating the connection as failed.
+	sessionPongWait = 60 * time.Second
+	// sessionWriteWait how long a session waits to write a message.
+	sessionWriteWait = 10 * time.Second
+	// sessionMinBackoff the longest a session waits before its first attempt
+	// to reconnect.
+	sessionMinBackoff = time.Second
+	// sessionMaxBackoff the longest a session waits between attempts to
+	// reconnect.
+	sessionMaxBackoff = time.Minute
+)
+
+// errSessionClosed is returned when a connection is attached to a session that
+// has been closed.
+var errSessionClosed = errors.New("feed session closed")
+
+// session manages the websocket connection of a feed. When reading from the
+// connection fails the session reconnects with jittered exponential backoff,
+// resubscribes to each security the feed tracks, and notifies the feed so it
+// can backfill the interval that was missed. The connection is kept alive with
+// pings, a connection that stops responding is treated as failed.
+type session struct {
+	monitor *monitor
+	mutex   *sync.Mutex
+	conn    *websocket.Conn
+	done    chan struct{}
+	closed  bool
+
+	// dial opens a new connection to the platform, authenticating if the
+	// platform requires it
+	dial func() (*websocket.Conn, error)
+	// subscribe subscribes the current connection to each security the feed
+	// tracks, typically by sending messages with send
+	subscribe func() error
+	// onReconnect is called once the connection has been re-established and
+	// resubscribed, before any further messages are read
+	onReconnect func()
+}
+
+// newSession creates a session that reports its connection state to the
+// supplied monitor. The session is not connected until open is called.
+func newSession(monitor *monitor,
+	dial func() (*websocket.Conn, error)) *session {
+	return &session{
+		monitor: monitor,
+		mutex:   &sync.Mutex{},
+		done:    make(chan struct{}),
+		dial:    dial,
+	}
+}
+
+// open establishes the initial connection of the session and subscribes it.
+func (s *session) open() error {
+
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+
+	return s.attach(conn)
+
+}
+
+// attach makes the supplied connection the current connection of the session
+// and subscribes it. The connection is closed if it cannot be subscribed.
+func (s *session) attach(conn *websocket.Conn) error {
+
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		conn.Close()
+		return errSessionClosed
+	}
+	s.conn = conn
+	s.mutex.Unlock()
+
+	// any message from the platform, including a pong, shows the connection
+	// is still alive
+	conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+	})
+
+	go s.ping(conn)
+
+	// the connection is attached before subscribing so securities added in
+	// the meantime are either sent on this connection or included here
+	if s.subscribe != nil {
+		if err := s.subscribe(); err != nil {
+			s.detach()
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// detach closes the current connection of the session. Messages sent while
+// the session is detached are dropped, the feed resubscribes once the session
+// reconnects.
+func (s *session) detach() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// ping periodically pings the platform over the supplied connection until the
+// connection is closed.
+func (s *session) ping(conn *websocket.Conn) {
+
+	ticker := time.NewTicker(sessionPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// control messages may be written concurrently with other
+			// messages, writing fails once the connection is closed
+			if err := conn.WriteControl(websocket.PingMessage, nil,
+				time.Now().Add(sessionWriteWait)); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+
+}
+
+// restart closes the current connection so the session reconnects and
+// resubscribes as it does when reading fails. Used to recover connections that
+// stop delivering messages without failing.
+func (s *session) restart() {
+	s.monitor.setState(StateReconnecting)
+	s.detach()
+}
+
+// send writes a JSON message to the current connection.
+func (s *session) send(message interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		// the session is reconnecting and will resubscribe
+		return nil
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(sessionWriteWait))
+	return s.conn.WriteJSON(message)
+}
+
+// close closes the session, interrupting any pending read or reconnect.
+func (s *session) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	close(s.done)
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// isClosed checks whether the session has been closed.
+func (s *session) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+// read continuously reads messages from the session and passes them to the
+// supplied handler, reconnecting whenever reading fails. Runs until the session
+// is closed.
+func (s *session) read(handle func(message []byte)) {
+
+	for {
+
+		s.mutex.Lock()
+		closed, conn := s.closed, s.conn
+		s.mutex.Unlock()
+
+		if closed {
+			break
+		}
+
+		// the connection was closed by restart between reads
+		if conn == nil {
+			if !s.reconnect() {
+				break
+			}
+			continue
+		}
+
+		// the session is not locked while waiting so messages can be sent
+		// and the session can be closed
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+
+			if s.isClosed() {
+				break
+			}
+
+			logrus.Error(err)
+
+			if !s.reconnect() {
+				break
+			}
+
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(sessionPongWait))
+		s.monitor.received()
+
+		handle(message)
+
+	}
+
+	s.detach()
+	s.monitor.setState(StateClosed)
+
+}
+
+// reconnect closes the current connection and attempts to re-establish it,
+// waiting longer after each failed attempt. Returns false if the session is
+// closed before it reconnects.
+func (s *session) reconnect() bool {
+
+	s.monitor.setState(StateReconnecting)
+	s.detach()
+
+	for attempt := 0; ; attempt++ {
+
+		delay := backoff(attempt)
+		logrus.Debugf("reconnecting feed in %v", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			return false
+		}
+
+		conn, err := s.dial()
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+
+		if err := s.attach(conn); err != nil {
+			if err == errSessionClosed {
+				return false
+			}
+			logrus.Error(err)
+			continue
+		}
+
+		s.monitor.reconnected()
+
+		if s.onReconnect != nil {
+			s.onReconnect()
+		}
+
+		return true
+
+	}
+
+}
+
+// backoff gets how long to wait before the supplied reconnect attempt. The
+// wait doubles with each attempt up to a limit, and is jittered so feeds that
+// lose their connections together do not reconnect together.
+func backoff(attempt int) time.Duration {
+
+	delay := sessionMaxBackoff
+	if attempt < 16 && sessionMinBackoff<<uint(attempt) < sessionMaxBackoff {
+		delay = sessionMinBackoff << uint(attempt)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+}
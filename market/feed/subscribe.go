@@ -0,0 +1,122 @@
+package feed
+
+import (
+	"sync"
+
+	"mojito/market"
+	"mojito/pubsub"
+
+	"github.com/sirupsen/logrus"
+)
+
+// subscriberBufferSize is the number of candlesticks that may be queued for a
+// subscriber before further candlesticks are dropped.
+const subscriberBufferSize = 16
+
+// subscribers counts the subscribers to each security that the feed was
+// subscribed to on demand rather than by its configuration.
+var subscribers = struct {
+	mutex *sync.Mutex
+	count map[string]int
+}{
+	mutex: &sync.Mutex{},
+	count: map[string]int{},
+}
+
+// Topic gets the pubsub topic on which candlesticks committed for the
+// specified exchange and ticker are published. The ticker may include a quote
+// currency as described by market.SplitTicker.
+func Topic(exchange, ticker string) string {
+	return "candlestick:" + formatFeedKey(exchange, ticker)
+}
+
+// Subscribe retrieves a channel that will receive a candlestick every time the
+// feed serving the specified exchange commits price data for the specified
+// ticker. The feed is subscribed to the ticker if it is not one of the
+// securities configured for the feed, and unsubscribed once the last
+// subscriber cancels. Any number of subscribers may receive candlesticks for
+// the same ticker, candlesticks are dropped for subscribers that do not keep
+// up. Returns a function that must be called to cancel the subscription.
+func Subscribe(exchange, ticker string) (<-chan market.Candlestick, func(),
+	error) {
+
+	feed, err := ForExchange(exchange)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := acquire(feed, exchange, ticker)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subscription := pubsub.Subscribe(Topic(exchange, ticker),
+		subscriberBufferSize, pubsub.PolicyDrop)
+
+	// deliver candlesticks on a typed channel until the subscription ends
+	channel := make(chan market.Candlestick)
+	go func() {
+		defer close(channel)
+		for message := range subscription.C {
+			select {
+			case channel <- message.(market.Candlestick):
+			case <-subscription.Done():
+				return
+			}
+		}
+	}()
+
+	once := &sync.Once{}
+	return channel, func() {
+		once.Do(func() {
+			subscription.Unsubscribe()
+			release()
+		})
+	}, nil
+
+}
+
+// acquire subscribes the feed to the specified security on behalf of a new
+// subscriber unless the security is configured for the feed. Returns a function
+// that unsubscribes the feed once every subscriber has released it.
+func acquire(feed Feed, exchange, ticker string) (func(), error) {
+
+	if Tracks(exchange, ticker) {
+		return func() {}, nil
+	}
+
+	key := formatFeedKey(exchange, ticker)
+
+	subscribers.mutex.Lock()
+	defer subscribers.mutex.Unlock()
+
+	if subscribers.count[key] == 0 {
+		if err := feed.AddSecurity(exchange, ticker); err != nil {
+			return nil, err
+		}
+	}
+	subscribers.count[key]++
+
+	return func() {
+
+		subscribers.mutex.Lock()
+		defer subscribers.mutex.Unlock()
+
+		subscribers.count[key]--
+		if subscribers.count[key] > 0 {
+			return
+		}
+		delete(subscribers.count, key)
+
+		// the security may have been configured for the feed in the meantime
+		if Tracks(exchange, ticker) {
+			return
+		}
+
+		if err := feed.RemoveSecurity(exchange, ticker); err != nil {
+			logrus.Error(err)
+		}
+
+	}, nil
+
+}
@@ -0,0 +1,5 @@
+// Package indicator provides technical indicators that operate on series of
+// candlesticks. Indicators are computed incrementally so the same
+// implementation can be used over stored candlesticks or candlesticks streamed
+// from a market data feed.
+package indicator
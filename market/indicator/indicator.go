@@ -0,0 +1,277 @@
+package indicator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"mojito/market"
+)
+
+// Name refers to a specific technical indicator.
+type Name string
+
+// Define supported indicators.
+const (
+	NameSMA        Name = "sma"
+	NameEMA        Name = "ema"
+	NameWMA        Name = "wma"
+	NameRSI        Name = "rsi"
+	NameMACD       Name = "macd"
+	NameBollinger  Name = "bollinger"
+	NameATR        Name = "atr"
+	NameStochastic Name = "stochastic"
+	NameOBV        Name = "obv"
+	NameVWAP       Name = "vwap"
+)
+
+// ErrUnknownIndicator is returned when an indicator is requested that is not
+// defined.
+var ErrUnknownIndicator = errors.New("unknown indicator")
+
+// Value stores the output of an indicator for a single candlestick. Indicators
+// that produce a single line store it under the "value" key, indicators that
+// produce several lines store each line under its own key.
+type Value map[string]float64
+
+// Point associates an indicator value with the candlestick it was computed
+// from.
+type Point struct {
+	CreatedAt time.Time `json:"created_at"`
+	Value     Value     `json:"value"`
+}
+
+// Indicator computes a technical indicator incrementally over a series of
+// candlesticks.
+type Indicator interface {
+	// Update adds the next candlestick in the series to the indicator and
+	// returns the indicator value. Returns false if not enough candlesticks
+	// have been supplied to compute a value.
+	Update(candlestick market.Candlestick) (Value, bool)
+	// Lookback gets the number of candlesticks that must be supplied before
+	// the indicator produces its first value.
+	Lookback() int
+}
+
+// MaxPeriod the largest integer parameter accepted by an indicator. Integer
+// parameters size the windows indicators keep and the number of candlesticks
+// retrieved to prime them, so they must be bounded.
+const MaxPeriod = 1000
+
+// Params stores the parameters used to configure an indicator, such as the
+// number of periods it is computed over.
+type Params map[string]float64
+
+// Int retrieves the specified parameter as an integer, returning the supplied
+// default value if the parameter is not set. The parameter must be a positive
+// integer no greater than MaxPeriod.
+func (p Params) Int(key string, defaultVal int) (int, error) {
+	val, ok := p[key]
+	if !ok {
+		return defaultVal, nil
+	}
+	if val < 1 || val != math.Trunc(val) {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	if val > MaxPeriod {
+		return 0, fmt.Errorf("%s must be at most %d", key, MaxPeriod)
+	}
+	return int(val), nil
+}
+
+// Float64 retrieves the specified parameter as a float64, returning the
+// supplied default value if the parameter is not set.
+func (p Params) Float64(key string, defaultVal float64) (float64, error) {
+	val, ok := p[key]
+	if !ok {
+		return defaultVal, nil
+	}
+	if val <= 0 {
+		return 0, fmt.Errorf("%s must be greater than zero", key)
+	}
+	return val, nil
+}
+
+// ParamKeys lists the parameters accepted by any indicator.
+var ParamKeys = []string{"period", "fast", "slow", "signal", "deviations", "k",
+	"d"}
+
+// New creates the specified indicator configured with the supplied parameters.
+// Parameters that are not supplied take the conventional default for the
+// indicator.
+func New(name Name, params Params) (Indicator, error) {
+
+	switch Name(strings.ToLower(string(name))) {
+	case NameSMA:
+		period, err := params.Int("period", 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewSMA(period), nil
+	case NameEMA:
+		period, err := params.Int("period", 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewEMA(period), nil
+	case NameWMA:
+		period, err := params.Int("period", 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewWMA(period), nil
+	case NameRSI:
+		period, err := params.Int("period", 14)
+		if err != nil {
+			return nil, err
+		}
+		return NewRSI(period), nil
+	case NameMACD:
+		fast, err := params.Int("fast", 12)
+		if err != nil {
+			return nil, err
+		}
+		slow, err := params.Int("slow", 26)
+		if err != nil {
+			return nil, err
+		}
+		signal, err := params.Int("signal", 9)
+		if err != nil {
+			return nil, err
+		}
+		if fast >= slow {
+			return nil, errors.New("fast must be less than slow")
+		}
+		return NewMACD(fast, slow, signal), nil
+	case NameBollinger:
+		period, err := params.Int("period", 20)
+		if err != nil {
+			return nil, err
+		}
+		deviations, err := params.Float64("deviations", 2)
+		if err != nil {
+			return nil, err
+		}
+		return NewBollinger(period, deviations), nil
+	case NameATR:
+		period, err := params.Int("period", 14)
+		if err != nil {
+			return nil, err
+		}
+		return NewATR(period), nil
+	case NameStochastic:
+		k, err := params.Int("k", 14)
+		if err != nil {
+			return nil, err
+		}
+		d, err := params.Int("d", 3)
+		if err != nil {
+			return nil, err
+		}
+		return NewStochastic(k, d), nil
+	case NameOBV:
+		return NewOBV(), nil
+	case NameVWAP:
+		return NewVWAP(), nil
+	}
+
+	return nil, ErrUnknownIndicator
+
+}
+
+// Lines lists the keys of the values produced by the specified indicator.
+func Lines(name Name) ([]string, error) {
+
+	switch Name(strings.ToLower(string(name))) {
+	case NameSMA, NameEMA, NameWMA, NameRSI, NameATR, NameOBV, NameVWAP:
+		return []string{"value"}, nil
+	case NameMACD:
+		return []string{"macd", "signal", "histogram"}, nil
+	case NameBollinger:
+		return []string{"upper", "middle", "lower"}, nil
+	case NameStochastic:
+		return []string{"k", "d"}, nil
+	}
+
+	return nil, ErrUnknownIndicator
+
+}
+
+// Compute runs the supplied indicator over a series of candlesticks and
+// returns a point for every candlestick that produced a value.
+func Compute(indicator Indicator,
+	candlesticks []market.Candlestick) []Point {
+
+	points := []Point{}
+
+	for _, candlestick := range candlesticks {
+		if value, ok := indicator.Update(candlestick); ok {
+			points = append(points, Point{
+				CreatedAt: candlestick.CreatedAt,
+				Value:     value,
+			})
+		}
+	}
+
+	return points
+
+}
+
+// window stores the most recent values in a series up to a fixed size.
+type window struct {
+	values []float64
+	size   int
+	sum    float64
+}
+
+// newWindow creates a window that holds the specified number of values.
+func newWindow(size int) *window {
+	return &window{
+		values: make([]float64, 0, size),
+		size:   size,
+	}
+}
+
+// push adds a value to the window, evicting the oldest value if the window is
+// full.
+func (w *window) push(value float64) {
+	if len(w.values) == w.size {
+		w.sum -= w.values[0]
+		w.values = w.values[1:]
+	}
+	w.values = append(w.values, value)
+	w.sum += value
+}
+
+// full checks whether the window holds as many values as it can.
+func (w *window) full() bool {
+	return len(w.values) == w.size
+}
+
+// mean gets the average of the values in the window.
+func (w *window) mean() float64 {
+	if len(w.values) == 0 {
+		return 0
+	}
+	return w.sum / float64(len(w.values))
+}
+
+// max gets the largest value in the window.
+func (w *window) max() float64 {
+	result := math.Inf(-1)
+	for _, value := range w.values {
+		result = math.Max(result, value)
+	}
+	return result
+}
+
+// min gets the smallest value in the window.
+func (w *window) min() float64 {
+	result := math.Inf(1)
+	for _, value := range w.values {
+		result = math.Min(result, value)
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"mojito/market"
+)
+
+// tolerance the largest difference allowed between a computed value and a
+// reference value. Reference values are published rounded to two decimal
+// places.
+const tolerance = 0.01
+
+// bar creates a candlestick opening the supplied number of minutes after the
+// unix epoch.
+func bar(minute int, high, low, close, volume float64) market.Candlestick {
+	return market.Candlestick{
+		CreatedAt: time.Unix(int64(minute)*60, 0).UTC(),
+		Open:      close,
+		High:      high,
+		Low:       low,
+		Close:     close,
+		Volume:    volume,
+	}
+}
+
+// closes creates a series of one minute candlesticks from the supplied closing
+// prices.
+func closes(prices ...float64) []market.Candlestick {
+	items := []market.Candlestick{}
+	for i, price := range prices {
+		items = append(items, bar(i, price, price, price, 0))
+	}
+	return items
+}
+
+// bars creates a series of one minute candlesticks from the supplied high,
+// low, and closing prices.
+func bars(prices ...[3]float64) []market.Candlestick {
+	items := []market.Candlestick{}
+	for i, price := range prices {
+		items = append(items, bar(i, price[0], price[1], price[2], 0))
+	}
+	return items
+}
+
+// checkLines runs the supplied indicator over a series of candlesticks and
+// compares each line of the values produced to the expected values. The
+// indicator must produce its first value after exactly as many candlesticks as
+// its lookback.
+func checkLines(t *testing.T, indicator Indicator,
+	candlesticks []market.Candlestick, expected map[string][]float64) {
+
+	t.Helper()
+
+	points := Compute(indicator, candlesticks)
+	if skipped := len(candlesticks) - len(points); skipped !=
+		indicator.Lookback() {
+		t.Fatalf("expected lookback %d, got %d", skipped, indicator.Lookback())
+	}
+
+	for key, values := range expected {
+		if len(points) != len(values) {
+			t.Fatalf("expected %d values, got %d", len(values), len(points))
+		}
+		for i, point := range points {
+			if math.Abs(point.Value[key]-values[i]) > tolerance {
+				t.Fatalf("expected %s %.4f at value %d, got %.4f", key,
+					values[i], i, point.Value[key])
+			}
+		}
+	}
+
+}
+
+func TestLookback(t *testing.T) {
+
+	// a series that rises and falls so every indicator has a range to work
+	// with
+	prices := []float64{}
+	for i := 0; i < 100; i++ {
+		prices = append(prices, 100+10*math.Sin(float64(i)/5))
+	}
+
+	tests := []struct {
+		name     Name
+		params   Params
+		lookback int
+	}{
+		{NameSMA, nil, 19},
+		{NameEMA, nil, 19},
+		{NameWMA, nil, 19},
+		{NameRSI, nil, 14},
+		{NameMACD, nil, 33},
+		{NameMACD, Params{"fast": 3, "slow": 6, "signal": 4}, 8},
+		{NameBollinger, nil, 19},
+		{NameATR, nil, 13},
+		{NameStochastic, nil, 15},
+		{NameStochastic, Params{"k": 5, "d": 1}, 4},
+		{NameOBV, nil, 0},
+		{NameVWAP, nil, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.name), func(t *testing.T) {
+
+			indicator, err := New(test.name, test.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if indicator.Lookback() != test.lookback {
+				t.Fatalf("expected lookback %d, got %d", test.lookback,
+					indicator.Lookback())
+			}
+
+			points := Compute(indicator, closes(prices...))
+			if skipped := len(prices) - len(points); skipped != test.lookback {
+				t.Fatalf("expected first value after %d candlesticks, got %d",
+					test.lookback, skipped)
+			}
+
+			lines, err := Lines(test.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range lines {
+				if _, ok := points[0].Value[line]; !ok {
+					t.Fatalf("expected line %s, got %v", line, points[0].Value)
+				}
+			}
+
+		})
+	}
+
+}
@@ -0,0 +1,125 @@
+package indicator
+
+import "mojito/market"
+
+// SMA computes the simple moving average of closing prices.
+type SMA struct {
+	window *window
+}
+
+// NewSMA creates a simple moving average over the specified number of periods.
+func NewSMA(period int) *SMA {
+	return &SMA{window: newWindow(period)}
+}
+
+// Update adds the next candlestick to the moving average.
+func (s *SMA) Update(candlestick market.Candlestick) (Value, bool) {
+	s.window.push(candlestick.Close)
+	if !s.window.full() {
+		return nil, false
+	}
+	return Value{"value": s.window.mean()}, true
+}
+
+// Lookback gets the number of candlesticks needed before the moving average
+// produces a value.
+func (s *SMA) Lookback() int {
+	return s.window.size - 1
+}
+
+// EMA computes the exponential moving average of closing prices.
+type EMA struct {
+	ema *ema
+}
+
+// NewEMA creates an exponential moving average over the specified number of
+// periods.
+func NewEMA(period int) *EMA {
+	return &EMA{ema: newEMA(period)}
+}
+
+// Update adds the next candlestick to the moving average.
+func (e *EMA) Update(candlestick market.Candlestick) (Value, bool) {
+	value, ok := e.ema.update(candlestick.Close)
+	if !ok {
+		return nil, false
+	}
+	return Value{"value": value}, true
+}
+
+// Lookback gets the number of candlesticks needed before the moving average
+// produces a value.
+func (e *EMA) Lookback() int {
+	return e.ema.period - 1
+}
+
+// WMA computes the linearly weighted moving average of closing prices, the
+// most recent price carries the greatest weight.
+type WMA struct {
+	window *window
+}
+
+// NewWMA creates a weighted moving average over the specified number of
+// periods.
+func NewWMA(period int) *WMA {
+	return &WMA{window: newWindow(period)}
+}
+
+// Update adds the next candlestick to the moving average.
+func (w *WMA) Update(candlestick market.Candlestick) (Value, bool) {
+	w.window.push(candlestick.Close)
+	if !w.window.full() {
+		return nil, false
+	}
+
+	var sum, weights float64
+	for i, value := range w.window.values {
+		sum += value * float64(i+1)
+		weights += float64(i + 1)
+	}
+
+	return Value{"value": sum / weights}, true
+}
+
+// Lookback gets the number of candlesticks needed before the moving average
+// produces a value.
+func (w *WMA) Lookback() int {
+	return w.window.size - 1
+}
+
+// ema computes an exponential moving average over a series of values. The
+// average is seeded with the simple average of the first period values.
+type ema struct {
+	period int
+	alpha  float64
+	seed   *window
+	value  float64
+	ready  bool
+}
+
+// newEMA creates an exponential moving average over the specified number of
+// periods.
+func newEMA(period int) *ema {
+	return &ema{
+		period: period,
+		alpha:  2 / float64(period+1),
+		seed:   newWindow(period),
+	}
+}
+
+// update adds the next value to the moving average.
+func (e *ema) update(value float64) (float64, bool) {
+	if e.ready {
+		e.value += e.alpha * (value - e.value)
+		return e.value, true
+	}
+
+	e.seed.push(value)
+	if !e.seed.full() {
+		return 0, false
+	}
+
+	e.value = e.seed.mean()
+	e.ready = true
+	return e.value, true
+}
@@ -0,0 +1,61 @@
+package indicator
+
+import "testing"
+
+// movingPrices the closing prices used by the StockCharts ChartSchool moving
+// average example.
+var movingPrices = []float64{22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23,
+	22.43, 22.24, 22.29, 22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75,
+	23.83, 23.95, 23.63, 23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68,
+	23.10, 22.40, 22.17}
+
+func TestMovingAverages(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		indicator Indicator
+		prices    []float64
+		expected  []float64
+	}{
+		{
+			name:      "sma",
+			indicator: NewSMA(10),
+			prices:    movingPrices,
+			expected: []float64{22.22, 22.21, 22.23, 22.26, 22.31, 22.42,
+				22.61, 22.77, 22.91, 23.08, 23.21, 23.38, 23.53, 23.65,
+				23.71, 23.69, 23.61, 23.51, 23.43, 23.28, 23.13},
+		},
+		{
+			// the average is seeded with the simple average of the first ten
+			// prices
+			name:      "ema",
+			indicator: NewEMA(10),
+			prices:    movingPrices,
+			expected: []float64{22.22, 22.21, 22.24, 22.27, 22.33, 22.52,
+				22.80, 22.97, 23.13, 23.28, 23.34, 23.43, 23.51, 23.54,
+				23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92},
+		},
+		{
+			// (1*1 + 2*2 + 3*3) / 6, the average of a linear series lags it
+			// by two thirds of a period
+			name:      "wma",
+			indicator: NewWMA(3),
+			prices:    []float64{1, 2, 3, 4, 5},
+			expected:  []float64{2.3333, 3.3333, 4.3333},
+		},
+		{
+			name:      "wma single period",
+			indicator: NewWMA(1),
+			prices:    []float64{1, 4, 2},
+			expected:  []float64{1, 4, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, test.indicator, closes(test.prices...),
+				map[string][]float64{"value": test.expected})
+		})
+	}
+
+}
@@ -0,0 +1,158 @@
+package indicator
+
+import (
+	"math"
+
+	"mojito/market"
+)
+
+// RSI computes the relative strength index of closing prices using Wilder's
+// smoothing.
+type RSI struct {
+	period    int
+	count     int
+	prevClose float64
+	avgGain   float64
+	avgLoss   float64
+}
+
+// NewRSI creates a relative strength index over the specified number of
+// periods.
+func NewRSI(period int) *RSI {
+	return &RSI{period: period}
+}
+
+// Update adds the next candlestick to the relative strength index.
+func (r *RSI) Update(candlestick market.Candlestick) (Value, bool) {
+	r.count++
+	if r.count == 1 {
+		r.prevClose = candlestick.Close
+		return nil, false
+	}
+
+	change := candlestick.Close - r.prevClose
+	r.prevClose = candlestick.Close
+
+	gain, loss := math.Max(change, 0), math.Max(-change, 0)
+
+	if r.count <= r.period+1 {
+		// accumulate the simple average of the first period changes
+		r.avgGain += gain / float64(r.period)
+		r.avgLoss += loss / float64(r.period)
+		if r.count <= r.period {
+			return nil, false
+		}
+	} else {
+		r.avgGain = (r.avgGain*float64(r.period-1) + gain) / float64(r.period)
+		r.avgLoss = (r.avgLoss*float64(r.period-1) + loss) / float64(r.period)
+	}
+
+	if r.avgLoss == 0 {
+		return Value{"value": 100}, true
+	}
+
+	rs := r.avgGain / r.avgLoss
+	return Value{"value": 100 - 100/(1+rs)}, true
+}
+
+// Lookback gets the number of candlesticks needed before the relative strength
+// index produces a value.
+func (r *RSI) Lookback() int {
+	return r.period
+}
+
+// MACD computes the moving average convergence divergence of closing prices.
+// Values contain the "macd" line, the "signal" line, and the "histogram".
+type MACD struct {
+	fast   *ema
+	slow   *ema
+	signal *ema
+}
+
+// NewMACD creates a moving average convergence divergence from the specified
+// fast, slow, and signal periods.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{
+		fast:   newEMA(fast),
+		slow:   newEMA(slow),
+		signal: newEMA(signal),
+	}
+}
+
+// Update adds the next candlestick to the moving average convergence
+// divergence.
+func (m *MACD) Update(candlestick market.Candlestick) (Value, bool) {
+	fast, fastOK := m.fast.update(candlestick.Close)
+	slow, slowOK := m.slow.update(candlestick.Close)
+	if !fastOK || !slowOK {
+		return nil, false
+	}
+
+	macd := fast - slow
+	signal, ok := m.signal.update(macd)
+	if !ok {
+		return nil, false
+	}
+
+	return Value{
+		"macd":      macd,
+		"signal":    signal,
+		"histogram": macd - signal,
+	}, true
+}
+
+// Lookback gets the number of candlesticks needed before the moving average
+// convergence divergence produces a value.
+func (m *MACD) Lookback() int {
+	return m.slow.period - 1 + m.signal.period - 1
+}
+
+// Stochastic computes the stochastic oscillator. Values contain the "k" line,
+// the position of the close within the recent high-low range, and the "d"
+// line, the simple moving average of the "k" line.
+type Stochastic struct {
+	highs *window
+	lows  *window
+	d     *window
+}
+
+// NewStochastic creates a stochastic oscillator from the specified %K and %D
+// periods.
+func NewStochastic(k, d int) *Stochastic {
+	return &Stochastic{
+		highs: newWindow(k),
+		lows:  newWindow(k),
+		d:     newWindow(d),
+	}
+}
+
+// Update adds the next candlestick to the stochastic oscillator.
+func (s *Stochastic) Update(candlestick market.Candlestick) (Value, bool) {
+	s.highs.push(candlestick.High)
+	s.lows.push(candlestick.Low)
+	if !s.highs.full() {
+		return nil, false
+	}
+
+	high, low := s.highs.max(), s.lows.min()
+
+	// if the price has not moved over the period place the close in the
+	// middle of the range
+	k := 50.0
+	if high != low {
+		k = 100 * (candlestick.Close - low) / (high - low)
+	}
+
+	s.d.push(k)
+	if !s.d.full() {
+		return nil, false
+	}
+
+	return Value{"k": k, "d": s.d.mean()}, true
+}
+
+// Lookback gets the number of candlesticks needed before the stochastic
+// oscillator produces a value.
+func (s *Stochastic) Lookback() int {
+	return s.highs.size - 1 + s.d.size - 1
+}
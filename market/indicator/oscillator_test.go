@@ -0,0 +1,164 @@
+package indicator
+
+import "testing"
+
+// rsiPrices the closing prices used by the StockCharts ChartSchool relative
+// strength index example.
+var rsiPrices = []float64{44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264,
+	45.0955, 45.4245, 45.8433, 46.0826, 45.8931, 46.0328, 45.6140, 46.2820,
+	46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521,
+	45.7137, 46.4528, 45.7835, 45.3548, 44.0288, 44.1783, 44.2181, 44.5672,
+	43.4205, 42.6628, 43.1314}
+
+func TestRSI(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		period   int
+		prices   []float64
+		expected []float64
+	}{
+		{
+			// the first value averages the first fourteen changes, later
+			// values use Wilder's smoothing
+			name:   "reference",
+			period: 14,
+			prices: rsiPrices,
+			expected: []float64{70.53, 66.32, 66.55, 69.41, 66.36, 57.97,
+				62.93, 63.26, 56.06, 62.38, 54.71, 50.42, 39.99, 41.46,
+				41.87, 45.46, 37.30, 33.08, 37.77},
+		},
+		{
+			name:     "only gains",
+			period:   2,
+			prices:   []float64{1, 2, 3, 4},
+			expected: []float64{100, 100},
+		},
+		{
+			name:     "only losses",
+			period:   2,
+			prices:   []float64{4, 3, 2, 1},
+			expected: []float64{0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewRSI(test.period), closes(test.prices...),
+				map[string][]float64{"value": test.expected})
+		})
+	}
+
+}
+
+func TestMACD(t *testing.T) {
+
+	// an exponential moving average seeded with a simple average lags a linear
+	// series by half of one less than its period
+	linear := []float64{}
+	for i := 1; i <= 40; i++ {
+		linear = append(linear, float64(i))
+	}
+
+	tests := []struct {
+		name     string
+		fast     int
+		slow     int
+		signal   int
+		prices   []float64
+		expected map[string][]float64
+	}{
+		{
+			// the fast and slow averages lag by 5.5 and 12.5, the first value
+			// follows 25 candlesticks to seed the slow average and 8 to seed
+			// the signal line
+			name:   "linear",
+			fast:   12,
+			slow:   26,
+			signal: 9,
+			prices: linear,
+			expected: map[string][]float64{
+				"macd":      {7, 7, 7, 7, 7, 7, 7},
+				"signal":    {7, 7, 7, 7, 7, 7, 7},
+				"histogram": {0, 0, 0, 0, 0, 0, 0},
+			},
+		},
+		{
+			// the fast average follows the price, the slow average is seeded
+			// with 2 then moves two thirds of the way to each price; the
+			// signal line is seeded with the average of the first two macd
+			// values 1 and 0
+			name:   "signal seeded after the slow average",
+			fast:   1,
+			slow:   2,
+			signal: 2,
+			prices: []float64{1, 3, 2, 6, 4},
+			expected: map[string][]float64{
+				"macd":      {0, 1.3333, -0.2222},
+				"signal":    {0.5, 1.0556, 0.2037},
+				"histogram": {-0.5, 0.2778, -0.4259},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewMACD(test.fast, test.slow, test.signal),
+				closes(test.prices...), test.expected)
+		})
+	}
+
+}
+
+func TestStochastic(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		k        int
+		d        int
+		prices   [][3]float64
+		expected map[string][]float64
+	}{
+		{
+			// %K is 75, 33.33, 100, 0, and 33.33 from the third candlestick,
+			// %D averages the last three
+			name: "three period",
+			k:    3,
+			d:    3,
+			prices: [][3]float64{
+				{10, 8, 9},
+				{11, 9, 10},
+				{12, 9, 11},
+				{12, 10, 10},
+				{13, 11, 13},
+				{12, 10, 10},
+				{11, 11, 11},
+			},
+			expected: map[string][]float64{
+				"k": {100, 0, 33.3333},
+				"d": {69.4444, 44.4444, 44.4444},
+			},
+		},
+		{
+			name: "flat range",
+			k:    2,
+			d:    1,
+			prices: [][3]float64{
+				{5, 5, 5},
+				{5, 5, 5},
+			},
+			expected: map[string][]float64{
+				"k": {50},
+				"d": {50},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewStochastic(test.k, test.d), bars(test.prices...),
+				test.expected)
+		})
+	}
+
+}
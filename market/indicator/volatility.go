@@ -0,0 +1,97 @@
+package indicator
+
+import (
+	"math"
+
+	"mojito/market"
+)
+
+// Bollinger computes Bollinger Bands over closing prices. Values contain the
+// "middle" band, a simple moving average, and the "upper" and "lower" bands
+// offset from the middle band by a number of standard deviations.
+type Bollinger struct {
+	window     *window
+	deviations float64
+}
+
+// NewBollinger creates Bollinger Bands over the specified number of periods
+// with bands the specified number of standard deviations from the average.
+func NewBollinger(period int, deviations float64) *Bollinger {
+	return &Bollinger{
+		window:     newWindow(period),
+		deviations: deviations,
+	}
+}
+
+// Update adds the next candlestick to the Bollinger Bands.
+func (b *Bollinger) Update(candlestick market.Candlestick) (Value, bool) {
+	b.window.push(candlestick.Close)
+	if !b.window.full() {
+		return nil, false
+	}
+
+	mean := b.window.mean()
+
+	var variance float64
+	for _, value := range b.window.values {
+		variance += (value - mean) * (value - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(b.window.values)))
+
+	return Value{
+		"upper":  mean + b.deviations*stddev,
+		"middle": mean,
+		"lower":  mean - b.deviations*stddev,
+	}, true
+}
+
+// Lookback gets the number of candlesticks needed before the Bollinger Bands
+// produce a value.
+func (b *Bollinger) Lookback() int {
+	return b.window.size - 1
+}
+
+// ATR computes the average true range using Wilder's smoothing.
+type ATR struct {
+	period    int
+	count     int
+	prevClose float64
+	value     float64
+}
+
+// NewATR creates an average true range over the specified number of periods.
+func NewATR(period int) *ATR {
+	return &ATR{period: period}
+}
+
+// Update adds the next candlestick to the average true range.
+func (a *ATR) Update(candlestick market.Candlestick) (Value, bool) {
+	trueRange := candlestick.High - candlestick.Low
+	if a.count > 0 {
+		trueRange = math.Max(trueRange, math.Max(
+			math.Abs(candlestick.High-a.prevClose),
+			math.Abs(candlestick.Low-a.prevClose)))
+	}
+
+	a.count++
+	a.prevClose = candlestick.Close
+
+	if a.count <= a.period {
+		// accumulate the simple average of the first period true ranges
+		a.value += trueRange / float64(a.period)
+		if a.count < a.period {
+			return nil, false
+		}
+	} else {
+		a.value = (a.value*float64(a.period-1) + trueRange) /
+			float64(a.period)
+	}
+
+	return Value{"value": a.value}, true
+}
+
+// Lookback gets the number of candlesticks needed before the average true
+// range produces a value.
+func (a *ATR) Lookback() int {
+	return a.period - 1
+}
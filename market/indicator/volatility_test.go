@@ -0,0 +1,100 @@
+package indicator
+
+import "testing"
+
+// atrPrices the high, low, and closing prices used by the StockCharts
+// ChartSchool average true range example.
+var atrPrices = [][3]float64{
+	{48.70, 47.79, 48.16}, {48.72, 48.14, 48.61}, {48.90, 48.39, 48.75},
+	{48.87, 48.37, 48.63}, {48.82, 48.24, 48.74}, {49.05, 48.64, 49.03},
+	{49.20, 48.94, 49.07}, {49.35, 48.86, 49.32}, {49.92, 49.50, 49.91},
+	{50.19, 49.87, 50.13}, {50.12, 49.20, 49.53}, {49.66, 48.90, 49.50},
+	{49.88, 49.43, 49.75}, {50.19, 49.73, 50.03}, {50.36, 49.26, 50.31},
+	{50.57, 50.09, 50.52}, {50.65, 50.30, 50.41}, {50.43, 49.21, 49.34},
+	{49.63, 48.98, 49.37}, {50.33, 49.61, 50.23}, {50.29, 49.20, 49.24},
+	{50.17, 49.43, 49.93}, {49.32, 48.08, 48.43}, {48.50, 47.64, 48.18},
+	{48.32, 41.55, 46.57}, {46.80, 44.28, 45.41}, {47.80, 47.31, 47.77},
+	{48.39, 47.20, 47.72}, {48.66, 47.90, 48.62}, {48.79, 47.73, 47.85},
+}
+
+func TestBollinger(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		period     int
+		deviations float64
+		prices     []float64
+		expected   map[string][]float64
+	}{
+		{
+			// the population standard deviation of the series is 2
+			name:       "population standard deviation",
+			period:     8,
+			deviations: 2,
+			prices:     []float64{2, 4, 4, 4, 5, 5, 7, 9},
+			expected: map[string][]float64{
+				"upper":  {9},
+				"middle": {5},
+				"lower":  {1},
+			},
+		},
+		{
+			name:       "rolling window",
+			period:     2,
+			deviations: 1.5,
+			prices:     []float64{1, 3, 3, 7},
+			expected: map[string][]float64{
+				"upper":  {3.5, 3, 8},
+				"middle": {2, 3, 5},
+				"lower":  {0.5, 3, 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewBollinger(test.period, test.deviations),
+				closes(test.prices...), test.expected)
+		})
+	}
+
+}
+
+func TestATR(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		period   int
+		prices   [][3]float64
+		expected []float64
+	}{
+		{
+			// the first value averages the first fourteen true ranges, later
+			// values use Wilder's smoothing
+			name:   "reference",
+			period: 14,
+			prices: atrPrices,
+			expected: []float64{0.56, 0.59, 0.59, 0.57, 0.62, 0.62, 0.64,
+				0.67, 0.69, 0.78, 0.78, 1.21, 1.30, 1.38, 1.37, 1.34, 1.32},
+		},
+		{
+			// the true range includes gaps from the previous close
+			name:   "gaps",
+			period: 2,
+			prices: [][3]float64{
+				{11, 9, 10},
+				{16, 14, 15},
+				{8, 7, 7},
+			},
+			expected: []float64{4, 6},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewATR(test.period), bars(test.prices...),
+				map[string][]float64{"value": test.expected})
+		})
+	}
+
+}
@@ -0,0 +1,83 @@
+package indicator
+
+import (
+	"time"
+
+	"mojito/market"
+)
+
+// OBV computes the on-balance volume, a running total of volume that is added
+// on candlesticks that close higher and subtracted on candlesticks that close
+// lower.
+type OBV struct {
+	count     int
+	prevClose float64
+	value     float64
+}
+
+// NewOBV creates an on-balance volume indicator.
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Update adds the next candlestick to the on-balance volume.
+func (o *OBV) Update(candlestick market.Candlestick) (Value, bool) {
+	if o.count > 0 {
+		if candlestick.Close > o.prevClose {
+			o.value += candlestick.Volume
+		} else if candlestick.Close < o.prevClose {
+			o.value -= candlestick.Volume
+		}
+	}
+
+	o.count++
+	o.prevClose = candlestick.Close
+
+	return Value{"value": o.value}, true
+}
+
+// Lookback gets the number of candlesticks needed before the on-balance volume
+// produces a value.
+func (o *OBV) Lookback() int {
+	return 0
+}
+
+// VWAP computes the volume weighted average price. The average is computed
+// from the typical price of each candlestick and resets at the start of each
+// day.
+type VWAP struct {
+	day         time.Time
+	totalPrice  float64
+	totalVolume float64
+}
+
+// NewVWAP creates a volume weighted average price indicator.
+func NewVWAP() *VWAP {
+	return &VWAP{}
+}
+
+// Update adds the next candlestick to the volume weighted average price.
+func (v *VWAP) Update(candlestick market.Candlestick) (Value, bool) {
+	day := market.BucketStart(candlestick.CreatedAt, market.Resolution1Day)
+	if !day.Equal(v.day) {
+		v.day = day
+		v.totalPrice = 0
+		v.totalVolume = 0
+	}
+
+	typical := (candlestick.High + candlestick.Low + candlestick.Close) / 3
+	v.totalPrice += typical * candlestick.Volume
+	v.totalVolume += candlestick.Volume
+
+	if v.totalVolume == 0 {
+		return Value{"value": typical}, true
+	}
+
+	return Value{"value": v.totalPrice / v.totalVolume}, true
+}
+
+// Lookback gets the number of candlesticks needed before the volume weighted
+// average price produces a value.
+func (v *VWAP) Lookback() int {
+	return 0
+}
@@ -0,0 +1,62 @@
+package indicator
+
+import (
+	"testing"
+
+	"mojito/market"
+)
+
+func TestOBV(t *testing.T) {
+
+	// volume is added when the close rises, subtracted when it falls, and
+	// ignored when it is unchanged
+	candlesticks := []market.Candlestick{
+		bar(0, 10, 10, 10, 100),
+		bar(1, 11, 11, 11, 200),
+		bar(2, 11, 11, 11, 300),
+		bar(3, 9, 9, 9, 400),
+		bar(4, 12, 12, 12, 500),
+	}
+
+	checkLines(t, NewOBV(), candlesticks, map[string][]float64{
+		"value": {0, 200, 200, -200, 300},
+	})
+
+}
+
+func TestVWAP(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		candlesticks []market.Candlestick
+		expected     []float64
+	}{
+		{
+			// typical prices 10 and 12 weighted by volumes 100 and 300, the
+			// average restarts on the next day
+			name: "resets each day",
+			candlesticks: []market.Candlestick{
+				bar(0, 12, 8, 10, 100),
+				bar(1, 14, 10, 12, 300),
+				bar(24*60, 20, 18, 19, 50),
+			},
+			expected: []float64{10, 11.5, 19},
+		},
+		{
+			name: "no volume",
+			candlesticks: []market.Candlestick{
+				bar(0, 12, 8, 10, 0),
+				bar(1, 15, 9, 12, 0),
+			},
+			expected: []float64{10, 12},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkLines(t, NewVWAP(), test.candlesticks,
+				map[string][]float64{"value": test.expected})
+		})
+	}
+
+}
@@ -1,19 +1,65 @@
 package market
 
 import (
+	"context"
+
 	"mojito/data"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm/clause"
 )
 
+// securityIndex the name of the unique index on the security and open time of
+// each candlestick.
+const securityIndex = "idx_candlesticks_security"
+
 // init migrates the package model.
 func init() {
+
+	// candlesticks stored before trade volume was tracked hold the trade
+	// count in the volume column
+	migrateVolume := data.DB().Migrator().HasTable(&Candlestick{}) &&
+		!data.DB().Migrator().HasColumn(&Candlestick{}, "trade_count")
+
+	// candlesticks stored before quote currencies were tracked are priced in
+	// the default quote currency
+	migrateQuote := data.DB().Migrator().HasTable(&Candlestick{}) &&
+		!data.DB().Migrator().HasColumn(&Candlestick{}, "quote")
+
+	// the unique index is not created while duplicate candlesticks are
+	// stored, the platforms are migrated separately so they are unaffected
 	data.DB().AutoMigrate(
 		Candlestick{},
+	)
+
+	data.DB().AutoMigrate(
 		Platform{},
 	)
 
+	if migrateVolume {
+		if err := data.DB().Migrator().AlterColumn(&Candlestick{},
+			"Volume"); err != nil {
+			logrus.Fatal(err)
+		}
+		if err := data.DB().Exec("UPDATE candlesticks " +
+			"SET trade_count = volume, volume = 0").Error; err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	if migrateQuote {
+		if err := data.DB().Exec("UPDATE candlesticks SET quote = ?",
+			DefaultQuote).Error; err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	// candlesticks stored before duplicates were rejected may repeat a
+	// security and open time, duplicates are only removed when requested
+	if !data.DB().Migrator().HasIndex(&Candlestick{}, securityIndex) {
+		checkDuplicates()
+	}
+
 	if !data.UseMockData() {
 		return
 	}
@@ -36,3 +82,26 @@ func init() {
 	}
 
 }
+
+// checkDuplicates creates the unique index on the security and open time of
+// each candlestick if no duplicates are stored, otherwise logs how to remove
+// them.
+func checkDuplicates() {
+
+	count, err := CountDuplicates(context.Background(), data.DB())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if count > 0 {
+		logrus.Warnf("%d duplicate candlesticks are stored, run "+
+			"`candlestick dedupe` to remove them", count)
+		return
+	}
+
+	if err := data.DB().Migrator().CreateIndex(&Candlestick{},
+		securityIndex); err != nil {
+		logrus.Fatal(err)
+	}
+
+}
@@ -1,5 +1,11 @@
 package market
 
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
 // PlatformKey refers to a specific platform for retrieving market data and
 // facilitating trades.
 type PlatformKey string
@@ -8,6 +14,7 @@ type PlatformKey string
 const (
 	PlatformCoinbase PlatformKey = "coinbase"
 	PlatformAlpaca   PlatformKey = "alpaca"
+	PlatformReplay   PlatformKey = "replay"
 )
 
 // ExchangeKey refers to a specific exchange for trading securities.
@@ -22,3 +29,80 @@ const (
 	ExchangeNYSENational ExchangeKey = "NYSE_NATIONAL"
 	ExchangeNYSEChicago  ExchangeKey = "NYSE_CHICAGO"
 )
+
+// Define the sides a trade may be initiated from.
+const (
+	TradeSideBuy  = "buy"
+	TradeSideSell = "sell"
+)
+
+// DefaultQuote is the currency a security is priced in if no quote currency is
+// specified.
+const DefaultQuote = "USD"
+
+// SplitTicker splits a ticker into its base asset and the quote currency it is
+// priced in. Currency pairs are written as BASE-QUOTE, such as BTC-EUR, while
+// tickers without a quote currency are priced in the default quote currency.
+func SplitTicker(ticker string) (string, string) {
+	ticker = strings.ToUpper(ticker)
+	if i := strings.LastIndex(ticker, "-"); i > 0 && i < len(ticker)-1 {
+		return ticker[:i], ticker[i+1:]
+	}
+	return ticker, DefaultQuote
+}
+
+// FormatTicker formats a base asset and quote currency as a ticker. The quote
+// currency is omitted if it is the default quote currency so the ticker of a
+// security priced in the default currency does not change.
+func FormatTicker(base, quote string) string {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	if quote == "" || quote == DefaultQuote {
+		return base
+	}
+	return base + "-" + quote
+}
+
+// Resolution refers to the interval of time covered by a single candlestick.
+type Resolution string
+
+// Define supported candlestick resolutions.
+const (
+	Resolution1Minute  Resolution = "1m"
+	Resolution5Minute  Resolution = "5m"
+	Resolution15Minute Resolution = "15m"
+	Resolution1Hour    Resolution = "1h"
+	Resolution4Hour    Resolution = "4h"
+	Resolution1Day     Resolution = "1d"
+	Resolution1Week    Resolution = "1w"
+)
+
+// resolutionDurations maps each supported resolution to the interval of time
+// it covers.
+var resolutionDurations = map[Resolution]time.Duration{
+	Resolution1Minute:  time.Minute,
+	Resolution5Minute:  5 * time.Minute,
+	Resolution15Minute: 15 * time.Minute,
+	Resolution1Hour:    time.Hour,
+	Resolution4Hour:    4 * time.Hour,
+	Resolution1Day:     24 * time.Hour,
+	Resolution1Week:    7 * 24 * time.Hour,
+}
+
+// ErrInvalidResolution is returned when a resolution is requested that is not
+// supported.
+var ErrInvalidResolution = errors.New("invalid resolution")
+
+// ParseResolution validates the supplied string as a candlestick resolution.
+func ParseResolution(resolution string) (Resolution, error) {
+	r := Resolution(strings.ToLower(resolution))
+	if _, ok := resolutionDurations[r]; !ok {
+		return "", ErrInvalidResolution
+	}
+	return r, nil
+}
+
+// Duration gets the interval of time covered by a candlestick at this
+// resolution.
+func (r Resolution) Duration() time.Duration {
+	return resolutionDurations[r]
+}
@@ -9,20 +9,27 @@ import (
 /* Data Types */
 
 // Candlestick stores price data for a specific ticker over an interval of time.
+// Only one candlestick may be stored for each security and open time.
 type Candlestick struct {
 	ID        uint      `gorm:"primarykey" json:"id"`
-	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	CreatedAt time.Time `gorm:"index;uniqueIndex:idx_candlesticks_security,priority:4" json:"created_at"`
 
 	OpensDay  bool `gorm:"index" json:"-"`
 	OpensHour bool `gorm:"index" json:"-"`
 
-	Exchange string  `gorm:"index" json:"exchange"`
-	Ticker   string  `gorm:"index" json:"ticker"`
+	Exchange string  `gorm:"index;uniqueIndex:idx_candlesticks_security,priority:1" json:"exchange"`
+	Ticker   string  `gorm:"index;uniqueIndex:idx_candlesticks_security,priority:2" json:"ticker"`
+	Quote    string  `gorm:"index;uniqueIndex:idx_candlesticks_security,priority:3" json:"quote"` // the currency prices are quoted in
 	Open     float64 `json:"open"`
 	Close    float64 `json:"close"`
 	High     float64 `json:"high"`
 	Low      float64 `json:"low"`
-	Volume   int     `json:"volume"`
+
+	Volume      float64 `json:"volume"`       // the quantity of the base asset traded
+	QuoteVolume float64 `json:"quote_volume"` // the notional value traded in the quote asset
+	TradeCount  int     `json:"trade_count"`  // the number of trades
+	BuyVolume   float64 `json:"buy_volume"`   // base asset volume of trades initiated by buyers
+	SellVolume  float64 `json:"sell_volume"`  // base asset volume of trades initiated by sellers
 }
 
 // Platform stores descriptive information about a platform that can be used for
@@ -73,6 +80,12 @@ var mockPlatforms = []Platform{
 		SignupLink:  "https://app.alpaca.markets/signup",
 		HasPriceAPI: true,
 	},
+	{
+		ID:          3,
+		Key:         PlatformReplay,
+		Name:        "Replay",
+		Description: "Replays recorded market data from a file for offline development.",
+	},
 }
 
 var mockExchanges = []Exchange{
@@ -0,0 +1,343 @@
+package pattern
+
+import (
+	"math"
+
+	"mojito/market"
+)
+
+const (
+	// trendPeriod the number of candlesticks before a pattern used to measure
+	// the preceding trend and the typical body size.
+	trendPeriod = 10
+	// dojiBodyRatio the largest body of a doji as a fraction of its range.
+	dojiBodyRatio = 0.1
+	// hammerShadowRatio the smallest lower shadow of a hammer as a fraction of
+	// its range.
+	hammerShadowRatio = 0.6
+	// starBodyRatio the largest body of the middle candlestick of a star as a
+	// fraction of the body of the first candlestick.
+	starBodyRatio = 0.3
+	// trendWeight how much the preceding trend contributes to the confidence
+	// of a reversal pattern.
+	trendWeight = 0.3
+)
+
+// detector checks whether the last candlestick in a series completes a
+// pattern. The returned match carries the pattern, its direction, confidence,
+// and when the pattern started.
+type detector func(history []market.Candlestick) (Match, bool)
+
+// detectors lists the detector of every supported pattern.
+var detectors = []detector{detectDoji, detectHammer, detectEngulfing,
+	detectStar, detectThree, detectInsideBar}
+
+// detectDoji finds candlesticks that open and close at nearly the same price,
+// showing indecision.
+func detectDoji(history []market.Candlestick) (Match, bool) {
+
+	c := history[len(history)-1]
+
+	if span(c) <= 0 {
+		return Match{}, false
+	}
+
+	ratio := body(c) / span(c)
+	if ratio > dojiBodyRatio {
+		return Match{}, false
+	}
+
+	return Match{
+		StartedAt:  c.CreatedAt,
+		Pattern:    NameDoji,
+		Direction:  DirectionNeutral,
+		Confidence: 1 - ratio/dojiBodyRatio/2,
+	}, true
+
+}
+
+// detectHammer finds candlesticks after a decline with a small body near the
+// high and a long lower shadow, showing buyers rejected lower prices.
+func detectHammer(history []market.Candlestick) (Match, bool) {
+
+	c := history[len(history)-1]
+
+	if span(c) <= 0 || trend(history, 1) >= 0 {
+		return Match{}, false
+	}
+
+	ratio := lowerShadow(c) / span(c)
+	if ratio < hammerShadowRatio || lowerShadow(c) < 2*body(c) ||
+		upperShadow(c) > body(c) {
+		return Match{}, false
+	}
+
+	return Match{
+		StartedAt:  c.CreatedAt,
+		Pattern:    NameHammer,
+		Direction:  DirectionBullish,
+		Confidence: 0.5 + (ratio-hammerShadowRatio)/(1-hammerShadowRatio)/2,
+	}, true
+
+}
+
+// detectEngulfing finds candlesticks whose body engulfs the body of the
+// previous candlestick in the opposite direction.
+func detectEngulfing(history []market.Candlestick) (Match, bool) {
+
+	if len(history) < 2 {
+		return Match{}, false
+	}
+
+	p, c := history[len(history)-2], history[len(history)-1]
+
+	if body(c) <= body(p) {
+		return Match{}, false
+	}
+
+	match := Match{StartedAt: p.CreatedAt}
+	shape := 0.5 + math.Min(body(c)/body(p)-1, 1)/2
+
+	switch {
+	case bearish(p) && bullish(c) && c.Open <= p.Close && c.Close >= p.Open:
+		match.Pattern, match.Direction = NameBullishEngulfing, DirectionBullish
+		match.Confidence = reversal(shape, history, 2, -1)
+	case bullish(p) && bearish(c) && c.Open >= p.Close && c.Close <= p.Open:
+		match.Pattern, match.Direction = NameBearishEngulfing, DirectionBearish
+		match.Confidence = reversal(shape, history, 2, 1)
+	default:
+		return Match{}, false
+	}
+
+	return match, true
+
+}
+
+// detectStar finds a long candlestick followed by a candlestick with a small
+// body beyond its close and a candlestick in the opposite direction that
+// closes past the midpoint of the first.
+func detectStar(history []market.Candlestick) (Match, bool) {
+
+	if len(history) < 3 {
+		return Match{}, false
+	}
+
+	a, b, c := history[len(history)-3], history[len(history)-2],
+		history[len(history)-1]
+
+	average := averageBody(history, 3)
+	if body(a) == 0 || body(a) < average || body(b) > starBodyRatio*body(a) {
+		return Match{}, false
+	}
+
+	middle := (b.Open + b.Close) / 2
+	match := Match{StartedAt: a.CreatedAt}
+
+	switch {
+	case bearish(a) && middle <= a.Close && bullish(c):
+		penetration := (c.Close - a.Close) / body(a)
+		if penetration <= 0.5 {
+			return Match{}, false
+		}
+		match.Pattern, match.Direction = NameMorningStar, DirectionBullish
+		match.Confidence = reversal(math.Min(penetration, 1), history, 3, -1)
+	case bullish(a) && middle >= a.Close && bearish(c):
+		penetration := (a.Close - c.Close) / body(a)
+		if penetration <= 0.5 {
+			return Match{}, false
+		}
+		match.Pattern, match.Direction = NameEveningStar, DirectionBearish
+		match.Confidence = reversal(math.Min(penetration, 1), history, 3, 1)
+	default:
+		return Match{}, false
+	}
+
+	return match, true
+
+}
+
+// detectThree finds three long candlesticks in the same direction that each
+// open within the body of the previous candlestick and close beyond it.
+func detectThree(history []market.Candlestick) (Match, bool) {
+
+	if len(history) < 3 {
+		return Match{}, false
+	}
+
+	items := history[len(history)-3:]
+	average := averageBody(history, 3)
+
+	// the confidence grows as the bodies fill more of each candlestick's range
+	shape := 0.0
+	for _, c := range items {
+		if span(c) <= 0 || body(c) < average/2 {
+			return Match{}, false
+		}
+		shape += body(c) / span(c) / 3
+	}
+
+	match := Match{StartedAt: items[0].CreatedAt}
+
+	switch {
+	case advancing(items):
+		match.Pattern, match.Direction = NameThreeWhiteSoldiers,
+			DirectionBullish
+		match.Confidence = reversal(shape, history, 3, -1)
+	case declining(items):
+		match.Pattern, match.Direction = NameThreeBlackCrows, DirectionBearish
+		match.Confidence = reversal(shape, history, 3, 1)
+	default:
+		return Match{}, false
+	}
+
+	return match, true
+
+}
+
+// detectInsideBar finds candlesticks whose range falls within the range of the
+// previous candlestick, showing consolidation.
+func detectInsideBar(history []market.Candlestick) (Match, bool) {
+
+	if len(history) < 2 {
+		return Match{}, false
+	}
+
+	p, c := history[len(history)-2], history[len(history)-1]
+
+	if span(c) <= 0 || span(c) >= span(p) || c.High > p.High ||
+		c.Low < p.Low {
+		return Match{}, false
+	}
+
+	return Match{
+		StartedAt:  p.CreatedAt,
+		Pattern:    NameInsideBar,
+		Direction:  DirectionNeutral,
+		Confidence: 0.5 + (1-span(c)/span(p))/2,
+	}, true
+
+}
+
+// advancing checks whether each candlestick is bullish, opens within the body
+// of the previous candlestick, and closes above it.
+func advancing(items []market.Candlestick) bool {
+	for i, c := range items {
+		if !bullish(c) {
+			return false
+		}
+		if i > 0 && (c.Open < items[i-1].Open || c.Open > items[i-1].Close ||
+			c.Close <= items[i-1].Close) {
+			return false
+		}
+	}
+	return true
+}
+
+// declining checks whether each candlestick is bearish, opens within the body
+// of the previous candlestick, and closes below it.
+func declining(items []market.Candlestick) bool {
+	for i, c := range items {
+		if !bearish(c) {
+			return false
+		}
+		if i > 0 && (c.Open > items[i-1].Open || c.Open < items[i-1].Close ||
+			c.Close >= items[i-1].Close) {
+			return false
+		}
+	}
+	return true
+}
+
+// reversal weighs the shape of a reversal pattern against the trend before its
+// first candlestick. The trend counts fully when it runs in the expected
+// direction and half when it cannot be measured.
+func reversal(shape float64, history []market.Candlestick, length,
+	expected int) float64 {
+
+	score := 0.0
+	switch trend(history, length) {
+	case expected:
+		score = 1
+	case 0:
+		score = 0.5
+	}
+
+	return (1-trendWeight)*shape + trendWeight*score
+
+}
+
+// trend gets the direction of the candlesticks before a pattern of the
+// supplied length: 1 if the last close before the pattern is above the average
+// close, -1 if it is below, or 0 if there are not enough candlesticks.
+func trend(history []market.Candlestick, length int) int {
+
+	before := history[:len(history)-length]
+	if len(before) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, c := range before {
+		mean += c.Close / float64(len(before))
+	}
+
+	last := before[len(before)-1].Close
+	if last > mean {
+		return 1
+	} else if last < mean {
+		return -1
+	}
+
+	return 0
+
+}
+
+// averageBody gets the average body of the candlesticks before a pattern of
+// the supplied length, zero if there are none.
+func averageBody(history []market.Candlestick, length int) float64 {
+
+	before := history[:len(history)-length]
+	if len(before) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, c := range before {
+		total += body(c)
+	}
+
+	return total / float64(len(before))
+
+}
+
+// body gets the difference between the open and close of a candlestick.
+func body(c market.Candlestick) float64 {
+	return math.Abs(c.Close - c.Open)
+}
+
+// span gets the difference between the high and low of a candlestick.
+func span(c market.Candlestick) float64 {
+	return c.High - c.Low
+}
+
+// upperShadow gets the difference between the high and the top of the body of
+// a candlestick.
+func upperShadow(c market.Candlestick) float64 {
+	return c.High - math.Max(c.Open, c.Close)
+}
+
+// lowerShadow gets the difference between the bottom of the body and the low
+// of a candlestick.
+func lowerShadow(c market.Candlestick) float64 {
+	return math.Min(c.Open, c.Close) - c.Low
+}
+
+// bullish checks whether a candlestick closed above its open.
+func bullish(c market.Candlestick) bool {
+	return c.Close > c.Open
+}
+
+// bearish checks whether a candlestick closed below its open.
+func bearish(c market.Candlestick) bool {
+	return c.Close < c.Open
+}
@@ -0,0 +1,224 @@
+package pattern
+
+import (
+	"testing"
+
+	"mojito/market"
+)
+
+// advance creates a series of steadily rising candlesticks starting at the
+// supplied minute and price.
+func advance(minute, count int, price float64) []market.Candlestick {
+	items := []market.Candlestick{}
+	for i := 0; i < count; i++ {
+		items = append(items, candle(minute+i, price, price+2.5, price-0.5,
+			price+2))
+		price += 2
+	}
+	return items
+}
+
+// series joins the supplied candlesticks into a single history.
+func series(parts ...[]market.Candlestick) []market.Candlestick {
+	items := []market.Candlestick{}
+	for _, part := range parts {
+		items = append(items, part...)
+	}
+	return items
+}
+
+func TestDetectors(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		detect    detector
+		history   []market.Candlestick
+		pattern   Name // empty if no pattern should be detected
+		direction Direction
+		started   int // the minute the pattern should start at
+	}{
+		{
+			name:   "doji",
+			detect: detectDoji,
+			history: []market.Candlestick{
+				candle(0, 100, 105, 95, 100.2),
+			},
+			pattern:   NameDoji,
+			direction: DirectionNeutral,
+		},
+		{
+			name:   "doji with a long body",
+			detect: detectDoji,
+			history: []market.Candlestick{
+				candle(0, 100, 105, 95, 104),
+			},
+		},
+		{
+			name:   "hammer after a decline",
+			detect: detectHammer,
+			history: series(decline(0, 12, 1000), []market.Candlestick{
+				candle(12, 974, 974.1, 971, 973.5),
+			}),
+			pattern:   NameHammer,
+			direction: DirectionBullish,
+			started:   12,
+		},
+		{
+			name:   "hammer after an advance",
+			detect: detectHammer,
+			history: series(advance(0, 12, 1000), []market.Candlestick{
+				candle(12, 1022, 1022.1, 1019, 1021.5),
+			}),
+		},
+		{
+			name:   "bullish engulfing after a decline",
+			detect: detectEngulfing,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 981, 975, 976),
+				candle(11, 975, 983, 974, 982),
+			}),
+			pattern:   NameBullishEngulfing,
+			direction: DirectionBullish,
+			started:   10,
+		},
+		{
+			name:   "bearish engulfing after an advance",
+			detect: detectEngulfing,
+			history: series(advance(0, 10, 1000), []market.Candlestick{
+				candle(10, 1020, 1025, 1019, 1024),
+				candle(11, 1025, 1026, 1016, 1018),
+			}),
+			pattern:   NameBearishEngulfing,
+			direction: DirectionBearish,
+			started:   10,
+		},
+		{
+			name:   "engulfing with a smaller body",
+			detect: detectEngulfing,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 981, 975, 976),
+				candle(11, 975, 980, 974, 978),
+			}),
+		},
+		{
+			name:   "morning star after a decline",
+			detect: detectStar,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 981, 969, 970),
+				candle(11, 968, 969, 966, 967.5),
+				candle(12, 968, 979, 967, 978),
+			}),
+			pattern:   NameMorningStar,
+			direction: DirectionBullish,
+			started:   10,
+		},
+		{
+			name:   "evening star after an advance",
+			detect: detectStar,
+			history: series(advance(0, 10, 1000), []market.Candlestick{
+				candle(10, 1020, 1031, 1019, 1030),
+				candle(11, 1032, 1034, 1031, 1032.5),
+				candle(12, 1032, 1033, 1021, 1022),
+			}),
+			pattern:   NameEveningStar,
+			direction: DirectionBearish,
+			started:   10,
+		},
+		{
+			name:   "star closing short of the midpoint",
+			detect: detectStar,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 981, 969, 970),
+				candle(11, 968, 969, 966, 967.5),
+				candle(12, 968, 974, 967, 973),
+			}),
+		},
+		{
+			name:   "three white soldiers after a decline",
+			detect: detectThree,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 984.5, 979.5, 984),
+				candle(11, 982, 988.5, 981.5, 988),
+				candle(12, 986, 992.5, 985.5, 992),
+			}),
+			pattern:   NameThreeWhiteSoldiers,
+			direction: DirectionBullish,
+			started:   10,
+		},
+		{
+			name:   "three black crows after an advance",
+			detect: detectThree,
+			history: series(advance(0, 10, 1000), []market.Candlestick{
+				candle(10, 1020, 1020.5, 1015.5, 1016),
+				candle(11, 1018, 1018.5, 1011.5, 1012),
+				candle(12, 1014, 1014.5, 1007.5, 1008),
+			}),
+			pattern:   NameThreeBlackCrows,
+			direction: DirectionBearish,
+			started:   10,
+		},
+		{
+			name:   "three soldiers opening above the previous body",
+			detect: detectThree,
+			history: series(decline(0, 10, 1000), []market.Candlestick{
+				candle(10, 980, 984.5, 979.5, 984),
+				candle(11, 985, 988.5, 984.5, 988),
+				candle(12, 986, 992.5, 985.5, 992),
+			}),
+		},
+		{
+			name:   "inside bar",
+			detect: detectInsideBar,
+			history: []market.Candlestick{
+				candle(0, 100, 110, 90, 105),
+				candle(1, 102, 106, 95, 104),
+			},
+			pattern:   NameInsideBar,
+			direction: DirectionNeutral,
+		},
+		{
+			name:   "inside bar breaking the previous high",
+			detect: detectInsideBar,
+			history: []market.Candlestick{
+				candle(0, 100, 110, 90, 105),
+				candle(1, 102, 111, 95, 104),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			match, ok := test.detect(test.history)
+
+			if test.pattern == "" {
+				if ok {
+					t.Fatalf("expected no pattern, got %s", match.Pattern)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("expected %s, got no pattern", test.pattern)
+			}
+			if match.Pattern != test.pattern {
+				t.Fatalf("expected %s, got %s", test.pattern, match.Pattern)
+			}
+			if match.Direction != test.direction {
+				t.Fatalf("expected %s direction, got %s", test.direction,
+					match.Direction)
+			}
+			started := candle(test.started, 0, 0, 0, 0).CreatedAt
+			if !match.StartedAt.Equal(started) {
+				t.Fatalf("expected pattern to start at %v, got %v", started,
+					match.StartedAt)
+			}
+			if match.Confidence <= 0 || match.Confidence > 1 {
+				t.Fatalf("expected confidence between 0 and 1, got %v",
+					match.Confidence)
+			}
+
+		})
+	}
+
+}
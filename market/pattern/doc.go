@@ -0,0 +1,12 @@
+// Package pattern recognizes classic candlestick patterns in series of
+// candlesticks. Like indicators, patterns are scanned incrementally so the same
+// implementation can be used over stored candlesticks or candlesticks streamed
+// from a market data feed.
+//
+// Each match records the candlestick the pattern completed on, whether the
+// pattern is bullish, bearish, or neutral, and a confidence between 0 and 1
+// that grows with how closely the candlesticks fit the textbook shape of the
+// pattern. Reversal patterns are more reliable after a move in the opposite
+// direction so their confidence also reflects the preceding trend; hammers are
+// only reported after a decline.
+package pattern
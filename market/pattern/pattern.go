@@ -0,0 +1,169 @@
+package pattern
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"mojito/market"
+)
+
+// Name refers to a specific candlestick pattern.
+type Name string
+
+// Define supported patterns.
+const (
+	NameDoji               Name = "doji"
+	NameHammer             Name = "hammer"
+	NameBullishEngulfing   Name = "bullish_engulfing"
+	NameBearishEngulfing   Name = "bearish_engulfing"
+	NameMorningStar        Name = "morning_star"
+	NameEveningStar        Name = "evening_star"
+	NameThreeWhiteSoldiers Name = "three_white_soldiers"
+	NameThreeBlackCrows    Name = "three_black_crows"
+	NameInsideBar          Name = "inside_bar"
+)
+
+// Names lists every supported pattern.
+var Names = []Name{NameDoji, NameHammer, NameBullishEngulfing,
+	NameBearishEngulfing, NameMorningStar, NameEveningStar,
+	NameThreeWhiteSoldiers, NameThreeBlackCrows, NameInsideBar}
+
+// Direction is the price movement a pattern anticipates.
+type Direction string
+
+// Define pattern directions.
+const (
+	DirectionBullish Direction = "bullish"
+	DirectionBearish Direction = "bearish"
+	DirectionNeutral Direction = "neutral" // indecision or consolidation
+)
+
+// ErrUnknownPattern is returned when a pattern is requested that is not
+// defined.
+var ErrUnknownPattern = errors.New("unknown pattern")
+
+// Match records a pattern found in a series of candlesticks.
+type Match struct {
+	StartedAt  time.Time `json:"started_at"` // when the first candlestick of the pattern opened
+	CreatedAt  time.Time `json:"created_at"` // when the candlestick that completed the pattern opened
+	Pattern    Name      `json:"pattern"`
+	Direction  Direction `json:"direction"`
+	Confidence float64   `json:"confidence"` // how closely the candlesticks fit the pattern, from 0 to 1
+	Close      float64   `json:"close"`      // the close of the candlestick that completed the pattern
+}
+
+// ParseName validates the supplied pattern name.
+func ParseName(value string) (Name, error) {
+	name := Name(strings.ToLower(strings.TrimSpace(value)))
+	for _, item := range Names {
+		if name == item {
+			return name, nil
+		}
+	}
+	return "", ErrUnknownPattern
+}
+
+// ParseNames validates a comma separated list of pattern names. An empty list
+// selects every pattern.
+func ParseNames(value string) ([]Name, error) {
+
+	if strings.TrimSpace(value) == "" {
+		return Names, nil
+	}
+
+	names := []Name{}
+	for _, item := range strings.Split(value, ",") {
+		name, err := ParseName(item)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+
+}
+
+// Scanner looks for patterns in a series of candlesticks.
+type Scanner struct {
+	patterns map[Name]bool
+	history  []market.Candlestick
+	size     int
+}
+
+// NewScanner creates a scanner that looks for the supplied patterns, or for
+// every pattern if none are supplied.
+func NewScanner(names ...Name) (*Scanner, error) {
+
+	if len(names) == 0 {
+		names = Names
+	}
+
+	patterns := map[Name]bool{}
+	for _, name := range names {
+		name, err := ParseName(string(name))
+		if err != nil {
+			return nil, err
+		}
+		patterns[name] = true
+	}
+
+	return &Scanner{
+		patterns: patterns,
+		history:  make([]market.Candlestick, 0, trendPeriod+3),
+		size:     trendPeriod + 3,
+	}, nil
+
+}
+
+// Update adds the next candlestick in the series to the scanner and returns
+// the patterns completed by it.
+func (s *Scanner) Update(candlestick market.Candlestick) []Match {
+
+	if len(s.history) == s.size {
+		s.history = s.history[1:]
+	}
+	s.history = append(s.history, candlestick)
+
+	matches := []Match{}
+	for _, detect := range detectors {
+		match, ok := detect(s.history)
+		if !ok || !s.patterns[match.Pattern] {
+			continue
+		}
+		match.Confidence = math.Round(match.Confidence*100) / 100
+		match.CreatedAt = candlestick.CreatedAt
+		match.Close = candlestick.Close
+		matches = append(matches, match)
+	}
+
+	return matches
+
+}
+
+// Lookback gets the number of candlesticks that should be supplied before the
+// scanner so the trend preceding a pattern can be measured.
+func (s *Scanner) Lookback() int {
+	return trendPeriod
+}
+
+// Scan looks for the supplied patterns in a series of candlesticks, or for
+// every pattern if none are supplied, and returns every match in the order
+// they were completed.
+func Scan(candlesticks []market.Candlestick, names ...Name) ([]Match, error) {
+
+	scanner, err := NewScanner(names...)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []Match{}
+	for _, candlestick := range candlesticks {
+		matches = append(matches, scanner.Update(candlestick)...)
+	}
+
+	return matches, nil
+
+}
@@ -0,0 +1,92 @@
+package pattern
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mojito/market"
+)
+
+// candle creates a candlestick opening the supplied number of minutes after
+// the unix epoch.
+func candle(minute int, open, high, low, close float64) market.Candlestick {
+	return market.Candlestick{
+		CreatedAt: time.Unix(int64(minute)*60, 0).UTC(),
+		Open:      open,
+		High:      high,
+		Low:       low,
+		Close:     close,
+	}
+}
+
+// decline creates a series of steadily falling candlesticks starting at the
+// supplied minute and price.
+func decline(minute, count int, price float64) []market.Candlestick {
+	items := []market.Candlestick{}
+	for i := 0; i < count; i++ {
+		items = append(items, candle(minute+i, price, price+0.5, price-2.5,
+			price-2))
+		price -= 2
+	}
+	return items
+}
+
+func TestScannerWindow(t *testing.T) {
+
+	// the matches completed by the last candlestick must not depend on how
+	// many candlesticks were scanned before the window
+	tail := append(decline(0, trendPeriod+2, 1000),
+		candle(trendPeriod+2, 974, 974.1, 971, 973.5))
+
+	var expected []Match
+
+	tests := []struct {
+		name   string
+		prefix int
+	}{
+		{"window only", 0},
+		{"one extra", 1},
+		{"twice the window", trendPeriod + 3},
+		{"long running", 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			scanner, err := NewScanner()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// scan unrelated candlesticks at a different price level first
+			for _, c := range decline(-test.prefix, test.prefix, 5000) {
+				scanner.Update(c)
+			}
+
+			var matches []Match
+			for _, c := range tail {
+				matches = scanner.Update(c)
+			}
+
+			if len(scanner.history) > scanner.size {
+				t.Fatalf("history grew to %d candlesticks, expected at most %d",
+					len(scanner.history), scanner.size)
+			}
+			if cap(scanner.history) > 2*scanner.size {
+				t.Fatalf("history capacity grew to %d", cap(scanner.history))
+			}
+
+			if expected == nil {
+				expected = matches
+				if len(expected) == 0 {
+					t.Fatal("expected the last candlestick to complete a pattern")
+				}
+			} else if !reflect.DeepEqual(matches, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, matches)
+			}
+
+		})
+	}
+
+}
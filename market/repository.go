@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetLastByTicker retrieves the most recently added candlestick for the
@@ -16,7 +17,7 @@ func GetLastByTicker(ctx context.Context, db *gorm.DB, exchange,
 	var item Candlestick
 
 	if err := db.Model(&Candlestick{}).
-		Where("exchange = ? AND ticker = ?", exchange, ticker).
+		Scopes(whereTicker(exchange, ticker)).
 		Last(&item).Error; err != nil {
 		return Candlestick{}, err
 	}
@@ -26,28 +27,40 @@ func GetLastByTicker(ctx context.Context, db *gorm.DB, exchange,
 }
 
 // ListByTicker retrieves all candlestick records associated with the specified
-// ticker and between the specified start and end date.
+// ticker and between the specified start and end date. Candlesticks are
+// aggregated to the specified resolution; aggregation is performed in the
+// database where the dialect supports it.
 func ListByTicker(ctx context.Context, db *gorm.DB, exchange, ticker string,
-	hourly, daily bool, startDate, endDate time.Time) ([]Candlestick, error) {
+	resolution Resolution, startDate, endDate time.Time) ([]Candlestick, error) {
 
-	var items []Candlestick
+	// candlesticks are stored at one minute intervals, aggregate them if a
+	// coarser resolution was requested
+	if resolution.Duration() > time.Minute {
 
-	res := db.Model(&Candlestick{}).
-		Where("exchange = ? AND ticker = ?", exchange, ticker).
-		Where("created_at > ? AND created_at < ?", startDate, endDate)
+		items, ok, err := listAggregateSQL(ctx, db, exchange, ticker,
+			resolution, startDate, endDate)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			return items, nil
+		}
 
-	if hourly {
-		res = res.Where("opens_hour")
 	}
 
-	if daily {
-		res = res.Where("opens_day")
-	}
+	var items []Candlestick
 
-	if err := res.Find(&items).Error; err != nil {
+	if err := db.Model(&Candlestick{}).
+		Scopes(whereTicker(exchange, ticker)).
+		Where("created_at > ? AND created_at < ?", startDate, endDate).
+		Order("created_at").
+		Find(&items).Error; err != nil {
 		return nil, err
 	}
 
+	if resolution.Duration() > time.Minute {
+		return Aggregate(items, resolution), nil
+	}
+
 	return items, nil
 
 }
@@ -76,34 +89,200 @@ func ListExchanges(ctx context.Context, db *gorm.DB) ([]string, error) {
 
 }
 
-// ListTickers retrieves all tickers for which candlestick data exists.
+// ListTickers retrieves all tickers for which candlestick data exists. Tickers
+// are formatted with their quote currency as described by FormatTicker.
 func ListTickers(ctx context.Context, db *gorm.DB,
 	exchange string) ([]string, error) {
 
 	var tickers []string
 
 	rows, err := db.Raw(
-		"SELECT DISTINCT ticker FROM candlesticks WHERE exchange = ?",
+		"SELECT DISTINCT ticker, quote FROM candlesticks WHERE exchange = ?",
 		exchange).Rows()
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
-		var ticker sql.NullString
+		var ticker, quote sql.NullString
 
-		if err := rows.Scan(&ticker); err != nil {
+		if err := rows.Scan(&ticker, &quote); err != nil {
 			return nil, err
 		}
 
-		tickers = append(tickers, ticker.String)
+		tickers = append(tickers, FormatTicker(ticker.String, quote.String))
 	}
 
 	return tickers, nil
 
 }
 
+// GetPlatformByKey retrieves a platform record by key.
+func GetPlatformByKey(ctx context.Context, db *gorm.DB,
+	key PlatformKey) (*Platform, error) {
+
+	var item Platform
+
+	if err := db.Model(&Platform{}).
+		Where("`key` = ?", key).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// whereTicker limits a query to the candlesticks of the specified exchange and
+// ticker. The ticker may include a quote currency as described by SplitTicker.
+func whereTicker(exchange, ticker string) func(*gorm.DB) *gorm.DB {
+	base, quote := SplitTicker(ticker)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("exchange = ? AND ticker = ? AND quote = ?", exchange,
+			base, quote)
+	}
+}
+
 // SaveCandlestick inserts or updates the supplied candlestick record.
 func SaveCandlestick(ctx context.Context, db *gorm.DB, item Candlestick) error {
 	return db.Save(&item).Error
 }
+
+// GetLastBefore retrieves the most recent candlestick for the specified
+// exchange and ticker created before the specified time.
+func GetLastBefore(ctx context.Context, db *gorm.DB, exchange, ticker string,
+	before time.Time) (Candlestick, error) {
+
+	var item Candlestick
+
+	if err := db.Model(&Candlestick{}).
+		Scopes(whereTicker(exchange, ticker)).
+		Where("created_at < ?", before).
+		Order("created_at DESC").
+		First(&item).Error; err != nil {
+		return Candlestick{}, err
+	}
+
+	return item, nil
+
+}
+
+// SaveCandlesticks inserts the supplied candlestick records, skipping any that
+// are already stored for the same security and open time. Returns the number
+// of candlesticks inserted.
+func SaveCandlesticks(ctx context.Context, db *gorm.DB,
+	items []Candlestick) (int, error) {
+
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&items)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+
+}
+
+// duplicateCandlesticks selects the candlesticks that repeat the security and
+// open time of a candlestick stored before them.
+const duplicateCandlesticks = "FROM candlesticks WHERE id NOT IN " +
+	"(SELECT id FROM (SELECT MIN(id) AS id FROM candlesticks " +
+	"GROUP BY exchange, ticker, quote, created_at) AS kept)"
+
+// CountDuplicates counts the candlesticks that repeat the security and open
+// time of a candlestick stored before them.
+func CountDuplicates(ctx context.Context, db *gorm.DB) (int64, error) {
+
+	var count int64
+
+	if err := db.Raw("SELECT COUNT(*) " +
+		duplicateCandlesticks).Scan(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+
+}
+
+// RemoveDuplicates deletes the candlesticks that repeat the security and open
+// time of a candlestick stored before them, then creates the unique index that
+// rejects further duplicates. Returns the number of candlesticks deleted.
+func RemoveDuplicates(ctx context.Context, db *gorm.DB) (int64, error) {
+
+	result := db.Exec("DELETE " + duplicateCandlesticks)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if !db.Migrator().HasIndex(&Candlestick{}, securityIndex) {
+		if err := db.Migrator().CreateIndex(&Candlestick{},
+			securityIndex); err != nil {
+			return result.RowsAffected, err
+		}
+	}
+
+	return result.RowsAffected, nil
+
+}
+
+// UpdateOpens recomputes whether each candlestick for the specified exchange
+// and ticker between the specified start and end date, and the candlestick
+// following them, opens a new hour or a new day. Candlesticks are processed in
+// pages so the date range may be arbitrarily large.
+func UpdateOpens(ctx context.Context, db *gorm.DB, exchange, ticker string,
+	startDate, endDate time.Time) error {
+
+	const pageSize = 5000
+
+	last, err := GetLastBefore(ctx, db, exchange, ticker, startDate)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	after := startDate.Add(-time.Nanosecond)
+	for {
+
+		var items []Candlestick
+		if err := db.Model(&Candlestick{}).
+			Scopes(whereTicker(exchange, ticker)).
+			Where("created_at > ?", after).
+			Order("created_at").
+			Limit(pageSize).
+			Find(&items).Error; err != nil {
+			return err
+		}
+
+		for _, item := range items {
+
+			// stop after the first candlestick past the end of the range
+			if item.CreatedAt.After(endDate) && last.CreatedAt.After(endDate) {
+				return nil
+			}
+
+			updated := item.SetOpensAfter(last)
+			if updated.OpensHour != item.OpensHour ||
+				updated.OpensDay != item.OpensDay {
+				if err := db.Model(&item).UpdateColumns(map[string]interface{}{
+					"opens_hour": updated.OpensHour,
+					"opens_day":  updated.OpensDay,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			last = updated
+
+		}
+
+		if len(items) < pageSize {
+			return nil
+		}
+
+		after = items[len(items)-1].CreatedAt
+
+	}
+
+}
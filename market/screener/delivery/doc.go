@@ -0,0 +1,3 @@
+// Package delivery exposes an API for screening securities and managing saved
+// screens.
+package delivery
@@ -0,0 +1,507 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mojito/cache"
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market"
+	"mojito/market/screener"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the screener API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(runScreenerEndpoint, user.JWTAuthMiddleware(),
+		runScreener)
+	server.Router().POST(createScreenEndpoint, user.JWTAuthMiddleware(),
+		createScreen)
+	server.Router().GET(listScreenEndpoint, user.JWTAuthMiddleware(),
+		listScreen)
+	server.Router().GET(getScreenEndpoint, user.JWTAuthMiddleware(),
+		getScreen)
+	server.Router().PUT(updateScreenEndpoint, user.JWTAuthMiddleware(),
+		updateScreen)
+	server.Router().DELETE(deleteScreenEndpoint, user.JWTAuthMiddleware(),
+		deleteScreen)
+	server.Router().GET(runScreenEndpoint, user.JWTAuthMiddleware(),
+		runScreen)
+
+}
+
+const (
+	// runScreenerEndpoint the API endpoint used to screen securities against
+	// criteria supplied in the request body.
+	runScreenerEndpoint = "/screener"
+	// createScreenEndpoint the API endpoint used to save a new screen.
+	createScreenEndpoint = "/screener/screen"
+	// listScreenEndpoint the API endpoint used to retrieve the logged in
+	// user's saved screens.
+	listScreenEndpoint = "/screener/screen"
+	// getScreenEndpoint the API endpoint used to retrieve a saved screen.
+	getScreenEndpoint = "/screener/screen/:id"
+	// updateScreenEndpoint the API endpoint used to update a saved screen.
+	updateScreenEndpoint = "/screener/screen/:id"
+	// deleteScreenEndpoint the API endpoint used to delete a saved screen.
+	deleteScreenEndpoint = "/screener/screen/:id"
+	// runScreenEndpoint the API endpoint used to screen securities against the
+	// criteria of a saved screen.
+	runScreenEndpoint = "/screener/screen/:id/result"
+	// screenNotFound is an error message returned when the requested screen
+	// does not exist or belongs to another user.
+	screenNotFound = "screen not found"
+	// defaultPageSize the number of rows returned if the request does not
+	// specify a page size.
+	defaultPageSize = 50
+	// maxPageSize the maximum number of rows that may be requested at once.
+	maxPageSize = 500
+	// screenerCacheTTL how long the results of screen criteria are reused.
+	screenerCacheTTL = 60 * time.Second
+)
+
+// runScreener screens securities against the criteria in the request body.
+// Results may be sorted and paginated with the sort, order, page, and page_size
+// GET parameters; the total number of matching securities is returned in the
+// X-Total-Records header.
+func runScreener(c *gin.Context) {
+
+	var criteria screener.Criteria
+
+	// read request parameters
+	if err := c.BindJSON(&criteria); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	screen(c, criteria, "", false)
+
+}
+
+// createScreen saves a new screen for the logged in user.
+func createScreen(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	item := &screener.Screen{UserID: u.ID}
+
+	if ok := readScreenRequest(c, item); !ok {
+		return
+	}
+
+	if err := screener.SaveScreen(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the new screen
+	c.JSON(http.StatusOK, formatScreen(item))
+
+}
+
+// listScreen retrieves the logged in user's saved screens.
+func listScreen(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve screens
+	items, err := screener.ListScreenByUserID(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	response := []screenResponse{}
+	for _, item := range items {
+		response = append(response, formatScreen(item))
+	}
+
+	// respond with screens
+	c.JSON(http.StatusOK, response)
+
+}
+
+// getScreen retrieves one of the logged in user's saved screens.
+func getScreen(c *gin.Context) {
+
+	item, ok := readScreen(c)
+	if !ok {
+		return
+	}
+
+	// respond with the screen
+	c.JSON(http.StatusOK, formatScreen(item))
+
+}
+
+// updateScreen updates one of the logged in user's saved screens.
+func updateScreen(c *gin.Context) {
+
+	item, ok := readScreen(c)
+	if !ok {
+		return
+	}
+
+	if ok := readScreenRequest(c, item); !ok {
+		return
+	}
+
+	if err := screener.SaveScreen(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the updated screen
+	c.JSON(http.StatusOK, formatScreen(item))
+
+}
+
+// deleteScreen deletes one of the logged in user's saved screens.
+func deleteScreen(c *gin.Context) {
+
+	item, ok := readScreen(c)
+	if !ok {
+		return
+	}
+
+	if err := screener.DeleteScreen(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the screen was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// runScreen screens securities against the criteria of one of the logged in
+// user's saved screens. Results are sorted as configured by the screen unless
+// the request overrides it, otherwise the request is handled as described by
+// runScreener.
+func runScreen(c *gin.Context) {
+
+	item, ok := readScreen(c)
+	if !ok {
+		return
+	}
+
+	criteria, err := item.Criteria()
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	screen(c, criteria, item.Sort, item.Descending)
+
+}
+
+// screen runs the supplied criteria and responds with the requested page of
+// results. The sort field and direction are read from the sort and order GET
+// parameters, falling back to the supplied defaults.
+func screen(c *gin.Context, criteria screener.Criteria, sort string,
+	descending bool) {
+
+	s, err := screener.New(criteria)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// read query parameters
+	if value := c.Query("sort"); value != "" {
+		sort = value
+	}
+
+	switch strings.ToLower(c.Query("order")) {
+	case "":
+	case "asc":
+		descending = false
+	case "desc":
+		descending = true
+	default:
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid order, expected asc or desc",
+		})
+		return
+	}
+
+	if err := s.ValidateSort(sort); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid sort, " + err.Error(),
+		})
+		return
+	}
+
+	page, ok := readIntQuery(c, "page", 1, 0)
+	if !ok {
+		return
+	}
+
+	pageSize, ok := readIntQuery(c, "page_size", defaultPageSize, maxPageSize)
+	if !ok {
+		return
+	}
+
+	// screen securities
+	rows, err := screenRows(c, s, criteria)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	if err := s.Sort(rows, sort, descending); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// select the requested page
+	c.Header("X-Total-Records", strconv.Itoa(len(rows)))
+
+	// pages past the last are empty, the page is compared to the number of
+	// pages before multiplying so large pages cannot overflow
+	start := len(rows)
+	if page-1 < (len(rows)+pageSize-1)/pageSize {
+		start = (page - 1) * pageSize
+	}
+
+	end := start + pageSize
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	// respond with the page of results
+	c.JSON(http.StatusOK, rows[start:end])
+
+}
+
+// screenRows runs the supplied screener, reusing the results of identical
+// criteria for a short time so paging through the results does not screen
+// every security again. The returned rows may be sorted by the caller.
+func screenRows(c *gin.Context, s *screener.Screener,
+	criteria screener.Criteria) ([]screener.Row, error) {
+
+	key, err := json.Marshal(criteria)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := "screener:" + string(key)
+
+	// check if the results are cached
+	if item, ok := cache.GetLocal(cacheKey); ok {
+		if rows, ok := item.([]screener.Row); ok {
+			return append([]screener.Row{}, rows...), nil
+		}
+	}
+
+	rows, err := s.Run(c, data.DB())
+	if err != nil {
+		return nil, err
+	}
+
+	cache.SetLocal(cacheKey, rows, screenerCacheTTL)
+
+	return append([]screener.Row{}, rows...), nil
+
+}
+
+// readIntQuery reads a positive integer GET parameter, returning the supplied
+// default if it is not set. Values above the supplied maximum are rejected
+// unless the maximum is zero. Writes an error response and returns false if
+// the parameter is invalid.
+func readIntQuery(c *gin.Context, key string, defaultVal,
+	max int) (int, bool) {
+
+	value := c.Query(key)
+	if value == "" {
+		return defaultVal, true
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 1 || (max > 0 && result > max) {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid " + key,
+		})
+		return 0, false
+	}
+
+	return result, true
+
+}
+
+// readScreen retrieves the screen specified in the request path. Writes an
+// error response and returns false if the screen cannot be retrieved.
+func readScreen(c *gin.Context) (*screener.Screen, bool) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: screenNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the screen
+	item, err := screener.GetScreenByID(c, data.DB(), u.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: screenNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// readScreenRequest reads and validates a screen from the request body and
+// applies it to the supplied screen. Writes an error response and returns
+// false if the request is invalid.
+func readScreenRequest(c *gin.Context, item *screener.Screen) bool {
+
+	var req saveScreenRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return false
+	}
+
+	// validate request parameters
+	if strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "name is required",
+		})
+		return false
+	}
+
+	if req.Filters == nil {
+		req.Filters = []screener.Filter{}
+	}
+
+	s, err := screener.New(screener.Criteria{
+		Exchange:   req.Exchange,
+		Resolution: req.Resolution,
+		Filters:    req.Filters,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return false
+	}
+
+	if err := s.ValidateSort(req.Sort); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid sort, " + err.Error(),
+		})
+		return false
+	}
+
+	filters, err := json.Marshal(req.Filters)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	item.Name = strings.TrimSpace(req.Name)
+	item.Exchange = strings.ToUpper(strings.TrimSpace(req.Exchange))
+	item.Resolution = market.Resolution(strings.ToLower(
+		string(req.Resolution)))
+	item.Filters = string(filters)
+	item.Sort = strings.ToLower(strings.TrimSpace(req.Sort))
+	item.Descending = req.Descending
+
+	return true
+
+}
+
+// formatScreen decodes the stored filters of a screen for use in an API
+// response.
+func formatScreen(item *screener.Screen) screenResponse {
+
+	response := screenResponse{Screen: *item, Filters: []screener.Filter{}}
+
+	if item.Filters != "" {
+		if err := json.Unmarshal([]byte(item.Filters),
+			&response.Filters); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return response
+
+}
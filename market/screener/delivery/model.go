@@ -0,0 +1,23 @@
+package delivery
+
+import (
+	"mojito/market"
+	"mojito/market/screener"
+)
+
+// saveScreenRequest is used to read a request to the create and update screen
+// endpoints.
+type saveScreenRequest struct {
+	Name       string            `json:"name"`
+	Exchange   string            `json:"exchange"`
+	Resolution market.Resolution `json:"resolution"`
+	Filters    []screener.Filter `json:"filters"`
+	Sort       string            `json:"sort"`
+	Descending bool              `json:"descending"`
+}
+
+// screenResponse is used to format a screen record in API responses.
+type screenResponse struct {
+	screener.Screen
+	Filters []screener.Filter `json:"filters"`
+}
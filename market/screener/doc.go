@@ -0,0 +1,12 @@
+// Package screener finds the securities whose latest candlestick and
+// technical indicators meet a set of filter criteria. Every ticker stored for
+// the screened exchanges is evaluated at its most recent candlestick, so
+// securities that have not traded recently are screened on their last known
+// prices. Users may save screens to run again later.
+//
+// Filters compare a field to a value. Fields are either a candlestick field,
+// one of open, high, low, close, volume, or change (the percent change from
+// the previous close), or an indicator line written as the indicator name
+// followed by the line, such as rsi, macd.signal, or bollinger.lower. The line
+// may be omitted for indicators that produce a single value.
+package screener
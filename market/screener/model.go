@@ -0,0 +1,71 @@
+package screener
+
+import (
+	"time"
+
+	"mojito/market"
+	"mojito/market/indicator"
+
+	"gorm.io/gorm"
+)
+
+// Operator refers to how a filter compares a field to its value.
+type Operator string
+
+// Define filter operators.
+const (
+	OperatorGreater        Operator = "gt"  // the field is greater than the value
+	OperatorGreaterOrEqual Operator = "gte" // the field is greater than or equal to the value
+	OperatorLess           Operator = "lt"  // the field is less than the value
+	OperatorLessOrEqual    Operator = "lte" // the field is less than or equal to the value
+)
+
+/* Data Types */
+
+// Filter is a condition a security must meet to be included in the results of
+// a screen.
+type Filter struct {
+	Field      string           `json:"field"` // a candlestick field or an indicator line
+	Operator   Operator         `json:"operator"`
+	Value      float64          `json:"value"`
+	Parameters indicator.Params `json:"parameters,omitempty"` // configures the indicator of an indicator field
+}
+
+// Criteria describes which securities are screened and the filters they must
+// meet.
+type Criteria struct {
+	Exchange   string            `json:"exchange"`   // limits the screen to one exchange, every exchange if empty
+	Resolution market.Resolution `json:"resolution"` // the resolution of the candlesticks screened
+	Filters    []Filter          `json:"filters"`
+}
+
+// Row is a security that met the criteria of a screen.
+type Row struct {
+	Exchange  string             `json:"exchange"`
+	Ticker    string             `json:"ticker"`
+	CreatedAt time.Time          `json:"created_at"` // when the latest candlestick opened
+	Open      float64            `json:"open"`
+	High      float64            `json:"high"`
+	Low       float64            `json:"low"`
+	Close     float64            `json:"close"`
+	Volume    float64            `json:"volume"`
+	Change    float64            `json:"change"` // percent change from the previous close
+	Values    map[string]float64 `json:"values"` // the value of each filtered field
+}
+
+// Screen stores screen criteria saved by a user.
+type Screen struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	Name       string            `json:"name"`
+	Exchange   string            `json:"exchange"`
+	Resolution market.Resolution `json:"resolution"`
+	Filters    string            `gorm:"type:text" json:"-"` // JSON encoded filters
+	Sort       string            `json:"sort"`               // the field results are sorted by
+	Descending bool              `json:"descending"`         // whether results are sorted from highest to lowest
+}
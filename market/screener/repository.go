@@ -0,0 +1,52 @@
+package screener
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// GetScreenByID retrieves a screen record belonging to the specified user by
+// id.
+func GetScreenByID(ctx context.Context, db *gorm.DB, userID,
+	id uint) (*Screen, error) {
+
+	var item Screen
+
+	if err := db.Model(&Screen{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListScreenByUserID retrieves all screen records belonging to the specified
+// user sorted by name.
+func ListScreenByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Screen, error) {
+
+	var items []*Screen
+
+	if err := db.Model(&Screen{}).
+		Where("user_id = ?", userID).
+		Order("name").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveScreen inserts or updates the supplied screen record.
+func SaveScreen(ctx context.Context, db *gorm.DB, item *Screen) error {
+	return db.Save(item).Error
+}
+
+// DeleteScreen deletes the supplied screen record.
+func DeleteScreen(ctx context.Context, db *gorm.DB, item *Screen) error {
+	return db.Delete(item).Error
+}
@@ -0,0 +1,396 @@
+package screener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"mojito/market"
+	"mojito/market/indicator"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxFilters the maximum number of filters a screen may apply.
+	maxFilters = 10
+	// defaultResolution the resolution screened if the criteria do not
+	// specify one.
+	defaultResolution = market.Resolution1Day
+)
+
+// ErrUnknownField is returned when a filter or sort refers to a field that is
+// not defined.
+var ErrUnknownField = errors.New("unknown field")
+
+// candlestickFields maps each field read from the latest candlestick to the
+// column of a row that stores it.
+var candlestickFields = map[string]func(row Row) float64{
+	"open":   func(row Row) float64 { return row.Open },
+	"high":   func(row Row) float64 { return row.High },
+	"low":    func(row Row) float64 { return row.Low },
+	"close":  func(row Row) float64 { return row.Close },
+	"volume": func(row Row) float64 { return row.Volume },
+	"change": func(row Row) float64 { return row.Change },
+}
+
+// Screener evaluates screen criteria over every ticker stored for the screened
+// exchanges.
+type Screener struct {
+	exchange   string
+	resolution market.Resolution
+	filters    []filter
+	indicators map[indicator.Name]indicator.Params
+	lookback   int
+}
+
+// filter is a validated filter along with the field it reads.
+type filter struct {
+	Filter
+	name indicator.Name // the indicator read by indicator fields
+	line string         // the indicator line read by indicator fields
+}
+
+// New validates the supplied criteria and creates a screener that evaluates
+// them.
+func New(criteria Criteria) (*Screener, error) {
+
+	s := &Screener{
+		exchange:   strings.ToUpper(strings.TrimSpace(criteria.Exchange)),
+		resolution: defaultResolution,
+		indicators: map[indicator.Name]indicator.Params{},
+	}
+
+	if criteria.Resolution != "" {
+		resolution, err := market.ParseResolution(string(criteria.Resolution))
+		if err != nil {
+			return nil, errors.New(
+				"invalid resolution, expected one of 1m, 5m, 15m, 1h, 4h, 1d, 1w")
+		}
+		s.resolution = resolution
+	}
+
+	if len(criteria.Filters) > maxFilters {
+		return nil, fmt.Errorf("a screen may apply at most %d filters",
+			maxFilters)
+	}
+
+	for _, item := range criteria.Filters {
+		f, err := s.compile(item)
+		if err != nil {
+			return nil, err
+		}
+		s.filters = append(s.filters, f)
+	}
+
+	return s, nil
+
+}
+
+// compile validates a filter and records the indicator it reads, if any.
+func (s *Screener) compile(item Filter) (filter, error) {
+
+	f := filter{Filter: item}
+	f.Field = strings.ToLower(strings.TrimSpace(item.Field))
+	f.Operator = Operator(strings.ToLower(string(item.Operator)))
+
+	switch f.Operator {
+	case OperatorGreater, OperatorGreaterOrEqual, OperatorLess,
+		OperatorLessOrEqual:
+	default:
+		return filter{}, fmt.Errorf(
+			"invalid operator for %s, expected one of gt, gte, lt, lte", f.Field)
+	}
+
+	if _, ok := candlestickFields[f.Field]; ok {
+		return f, nil
+	}
+
+	// indicator fields name the indicator followed by the line they read
+	name, line := f.Field, ""
+	if i := strings.Index(f.Field, "."); i >= 0 {
+		name, line = f.Field[:i], f.Field[i+1:]
+	}
+	f.name = indicator.Name(name)
+
+	lines, err := indicator.Lines(f.name)
+	if err != nil {
+		return filter{}, fmt.Errorf("%w %s", ErrUnknownField, f.Field)
+	}
+
+	if line == "" {
+		if len(lines) > 1 {
+			return filter{}, fmt.Errorf("%s requires a line, one of %s", name,
+				strings.Join(lines, ", "))
+		}
+		line = lines[0]
+	}
+
+	for _, item := range lines {
+		if item == line {
+			f.line = line
+		}
+	}
+
+	if f.line == "" {
+		return filter{}, fmt.Errorf("%w %s", ErrUnknownField, f.Field)
+	}
+
+	params := f.Parameters
+	if params == nil {
+		params = indicator.Params{}
+	}
+
+	// each indicator is computed once so every filter reading it must
+	// configure it the same way
+	if existing, ok := s.indicators[f.name]; ok {
+		if !reflect.DeepEqual(existing, params) {
+			return filter{}, fmt.Errorf("conflicting parameters for %s", name)
+		}
+		return f, nil
+	}
+
+	ind, err := indicator.New(f.name, params)
+	if err != nil {
+		return filter{}, fmt.Errorf("%s: %v", name, err)
+	}
+
+	s.indicators[f.name] = params
+	if ind.Lookback() > s.lookback {
+		s.lookback = ind.Lookback()
+	}
+
+	return f, nil
+
+}
+
+// Run evaluates the criteria over every ticker stored for the screened
+// exchanges and returns the securities that meet them, sorted by exchange and
+// ticker.
+func (s *Screener) Run(ctx context.Context, db *gorm.DB) ([]Row, error) {
+
+	exchanges := []string{s.exchange}
+	if s.exchange == "" {
+		var err error
+		if exchanges, err = market.ListExchanges(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	rows := []Row{}
+
+	for _, exchange := range exchanges {
+
+		tickers, err := market.ListTickers(ctx, db, exchange)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ticker := range tickers {
+			row, ok, err := s.evaluate(ctx, db, exchange, ticker)
+			if err != nil {
+				return nil, err
+			} else if ok {
+				rows = append(rows, row)
+			}
+		}
+
+	}
+
+	if err := s.Sort(rows, "", false); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+
+}
+
+// evaluate computes the fields of a security at its latest candlestick and
+// checks whether they meet every filter.
+func (s *Screener) evaluate(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) (Row, bool, error) {
+
+	last, err := market.GetLastByTicker(ctx, db, exchange, ticker)
+	if err == gorm.ErrRecordNotFound {
+		return Row{}, false, nil
+	} else if err != nil {
+		return Row{}, false, err
+	}
+
+	// retrieve enough candlesticks before the latest for each indicator to
+	// produce a value, allowing for intervals where the security did not trade
+	duration := s.resolution.Duration()
+	end := market.BucketStart(last.CreatedAt, s.resolution).Add(duration)
+	start := end.Add(-time.Duration(2*(s.lookback+2)) * duration)
+
+	candlesticks, err := market.ListByTicker(ctx, db, exchange, ticker,
+		s.resolution, start, end)
+	if err != nil {
+		return Row{}, false, err
+	} else if len(candlesticks) == 0 {
+		return Row{}, false, nil
+	}
+
+	latest := candlesticks[len(candlesticks)-1]
+	row := Row{
+		Exchange:  exchange,
+		Ticker:    ticker,
+		CreatedAt: latest.CreatedAt,
+		Open:      latest.Open,
+		High:      latest.High,
+		Low:       latest.Low,
+		Close:     latest.Close,
+		Volume:    latest.Volume,
+		Values:    map[string]float64{},
+	}
+
+	// compare to the previous close, or to the open if there is none
+	base := latest.Open
+	if len(candlesticks) > 1 {
+		base = candlesticks[len(candlesticks)-2].Close
+	}
+	if base != 0 {
+		row.Change = (latest.Close - base) / base * 100
+	}
+
+	// compute the value of each indicator at the latest candlestick
+	values := map[indicator.Name]indicator.Value{}
+	for name, params := range s.indicators {
+		ind, err := indicator.New(name, params)
+		if err != nil {
+			return Row{}, false, err
+		}
+		var value indicator.Value
+		for _, candlestick := range candlesticks {
+			value, _ = ind.Update(candlestick)
+		}
+		values[name] = value
+	}
+
+	for _, f := range s.filters {
+
+		var value float64
+		if field, ok := candlestickFields[f.Field]; ok {
+			value = field(row)
+		} else if line, ok := values[f.name][f.line]; ok {
+			value = line
+		} else {
+			return Row{}, false, nil
+		}
+
+		if math.IsNaN(value) || !f.compare(value) {
+			return Row{}, false, nil
+		}
+
+		row.Values[f.Field] = value
+
+	}
+
+	return row, true, nil
+
+}
+
+// compare checks whether the supplied value of the filtered field meets the
+// filter.
+func (f filter) compare(value float64) bool {
+	switch f.Operator {
+	case OperatorGreater:
+		return value > f.Value
+	case OperatorGreaterOrEqual:
+		return value >= f.Value
+	case OperatorLess:
+		return value < f.Value
+	default:
+		return value <= f.Value
+	}
+}
+
+// ValidateSort checks that rows produced by the screener can be sorted by the
+// supplied field. Rows may be sorted by exchange, ticker, a candlestick field,
+// or any field the screener filters on.
+func (s *Screener) ValidateSort(field string) error {
+	_, err := s.sortValue(field)
+	return err
+}
+
+// Sort sorts rows produced by the screener by the supplied field. Rows that
+// tie are sorted by exchange and ticker, rows are sorted by exchange and ticker
+// if no field is supplied.
+func (s *Screener) Sort(rows []Row, field string, descending bool) error {
+
+	value, err := s.sortValue(field)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if descending {
+			a, b = b, a
+		}
+		if value != nil && value(a) != value(b) {
+			return value(a) < value(b)
+		}
+		if strings.EqualFold(field, "ticker") && a.Ticker != b.Ticker {
+			return a.Ticker < b.Ticker
+		}
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		return a.Ticker < b.Ticker
+	})
+
+	return nil
+
+}
+
+// sortValue gets the numeric value rows are sorted by for the supplied field.
+// Returns nil for the exchange and ticker fields, which are sorted as text.
+func (s *Screener) sortValue(field string) (func(row Row) float64, error) {
+
+	field = strings.ToLower(strings.TrimSpace(field))
+
+	switch field {
+	case "", "exchange", "ticker":
+		return nil, nil
+	}
+
+	if value, ok := candlestickFields[field]; ok {
+		return value, nil
+	}
+
+	for _, f := range s.filters {
+		if f.Field == field {
+			return func(row Row) float64 { return row.Values[field] }, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w %s", ErrUnknownField, field)
+
+}
+
+// Criteria decodes the criteria of a saved screen.
+func (item *Screen) Criteria() (Criteria, error) {
+
+	criteria := Criteria{
+		Exchange:   item.Exchange,
+		Resolution: item.Resolution,
+		Filters:    []Filter{},
+	}
+
+	if item.Filters != "" {
+		if err := json.Unmarshal([]byte(item.Filters),
+			&criteria.Filters); err != nil {
+			return Criteria{}, err
+		}
+	}
+
+	return criteria, nil
+
+}
@@ -0,0 +1,2 @@
+// Package delivery exposes an API for defining synthetic tickers.
+package delivery
@@ -0,0 +1,296 @@
+package delivery
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/market/synthetic"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the synthetic ticker API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().POST(createSyntheticEndpoint, user.JWTAuthMiddleware(),
+		createSynthetic)
+	server.Router().GET(listSyntheticEndpoint, user.JWTAuthMiddleware(),
+		listSynthetic)
+	server.Router().GET(getSyntheticEndpoint, user.JWTAuthMiddleware(),
+		getSynthetic)
+	server.Router().PUT(updateSyntheticEndpoint, user.JWTAuthMiddleware(),
+		updateSynthetic)
+	server.Router().DELETE(deleteSyntheticEndpoint, user.JWTAuthMiddleware(),
+		deleteSynthetic)
+
+}
+
+const (
+	// createSyntheticEndpoint the API endpoint used to define a new synthetic
+	// ticker.
+	createSyntheticEndpoint = "/synthetic"
+	// listSyntheticEndpoint the API endpoint used to retrieve all synthetic
+	// tickers.
+	listSyntheticEndpoint = "/synthetic"
+	// getSyntheticEndpoint the API endpoint used to retrieve a synthetic
+	// ticker.
+	getSyntheticEndpoint = "/synthetic/:id"
+	// updateSyntheticEndpoint the API endpoint used to update a synthetic
+	// ticker.
+	updateSyntheticEndpoint = "/synthetic/:id"
+	// deleteSyntheticEndpoint the API endpoint used to delete a synthetic
+	// ticker.
+	deleteSyntheticEndpoint = "/synthetic/:id"
+	// syntheticNotFound is an error message returned when the requested
+	// synthetic ticker does not exist.
+	syntheticNotFound = "synthetic ticker not found"
+)
+
+// syntheticNamePattern matches valid synthetic ticker names. Hyphens are not
+// allowed since they separate the quote currency of a ticker.
+var syntheticNamePattern = regexp.MustCompile(`^[A-Z0-9_]{1,32}$`)
+
+// createSynthetic defines a new synthetic ticker owned by the logged in user.
+func createSynthetic(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	item := &synthetic.Synthetic{UserID: u.ID}
+
+	if ok := readSyntheticRequest(c, item); !ok {
+		return
+	}
+
+	if err := synthetic.SaveSynthetic(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the new synthetic ticker
+	c.JSON(http.StatusOK, item)
+
+}
+
+// listSynthetic retrieves all synthetic tickers.
+func listSynthetic(c *gin.Context) {
+
+	items, err := synthetic.ListSynthetic(c, data.DB())
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with synthetic tickers
+	c.JSON(http.StatusOK, items)
+
+}
+
+// getSynthetic retrieves a synthetic ticker.
+func getSynthetic(c *gin.Context) {
+
+	item, ok := readSynthetic(c, false)
+	if !ok {
+		return
+	}
+
+	// respond with the synthetic ticker
+	c.JSON(http.StatusOK, item)
+
+}
+
+// updateSynthetic updates a synthetic ticker owned by the logged in user.
+// Subscriptions to the synthetic ticker begin using its new expression.
+func updateSynthetic(c *gin.Context) {
+
+	item, ok := readSynthetic(c, true)
+	if !ok {
+		return
+	}
+
+	name := item.Name
+
+	if ok := readSyntheticRequest(c, item); !ok {
+		return
+	}
+
+	if err := synthetic.SaveSynthetic(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	synthetic.Refresh(c, data.DB(), name)
+	if item.Name != name {
+		synthetic.Refresh(c, data.DB(), item.Name)
+	}
+
+	// respond with the updated synthetic ticker
+	c.JSON(http.StatusOK, item)
+
+}
+
+// deleteSynthetic deletes a synthetic ticker owned by the logged in user.
+func deleteSynthetic(c *gin.Context) {
+
+	item, ok := readSynthetic(c, true)
+	if !ok {
+		return
+	}
+
+	if err := synthetic.DeleteSynthetic(c, data.DB(), item); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	synthetic.Refresh(c, data.DB(), item.Name)
+
+	// respond with 200 - OK if the synthetic ticker was deleted
+	c.Status(http.StatusOK)
+
+}
+
+// readSynthetic retrieves the synthetic ticker specified in the request path.
+// If the synthetic ticker is being changed the logged in user must own it or
+// be an admin. Writes an error response and returns false if the synthetic
+// ticker cannot be retrieved.
+func readSynthetic(c *gin.Context, change bool) (*synthetic.Synthetic, bool) {
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: syntheticNotFound,
+		})
+		return nil, false
+	}
+
+	// retrieve the synthetic ticker
+	item, err := synthetic.GetSyntheticByID(c, data.DB(), uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: syntheticNotFound,
+		})
+		return nil, false
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	if !change {
+		return item, true
+	}
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return nil, false
+	}
+
+	if item.UserID != u.ID && !u.Admin {
+		c.JSON(http.StatusForbidden, httperror.ErrorResponse{
+			ErrorMessage: "synthetic ticker belongs to another user",
+		})
+		return nil, false
+	}
+
+	return item, true
+
+}
+
+// readSyntheticRequest reads and validates a synthetic ticker definition from
+// the request body and applies it to the supplied synthetic ticker. Writes an
+// error response and returns false if the request is invalid.
+func readSyntheticRequest(c *gin.Context, item *synthetic.Synthetic) bool {
+
+	var req saveSyntheticRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return false
+	}
+
+	// validate request parameters
+	name := strings.ToUpper(strings.TrimSpace(req.Name))
+	if !syntheticNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "name must be 1 to 32 letters, digits, or underscores",
+		})
+		return false
+	}
+
+	exchange := strings.ToUpper(strings.TrimSpace(req.Exchange))
+	if synthetic.IsSynthetic(exchange) {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "exchange must not be the synthetic exchange",
+		})
+		return false
+	}
+
+	if _, err := synthetic.ParseExpression(req.Expression,
+		exchange); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return false
+	}
+
+	// check that the name is not used by another synthetic ticker
+	existing, err := synthetic.GetSyntheticByName(c, data.DB(), name)
+	if err == nil && existing.ID != item.ID {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "a synthetic ticker with this name already exists",
+		})
+		return false
+	} else if err != nil && err != gorm.ErrRecordNotFound {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return false
+	}
+
+	item.Name = name
+	item.Exchange = exchange
+	item.Expression = strings.TrimSpace(req.Expression)
+
+	return true
+
+}
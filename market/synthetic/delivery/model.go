@@ -0,0 +1,9 @@
+package delivery
+
+// saveSyntheticRequest is used to read a request to the create and update
+// synthetic ticker endpoints.
+type saveSyntheticRequest struct {
+	Name       string `json:"name"`
+	Exchange   string `json:"exchange"`
+	Expression string `json:"expression"`
+}
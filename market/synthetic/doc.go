@@ -0,0 +1,18 @@
+// Package synthetic provides tickers whose candlesticks are computed from the
+// candlesticks of other securities. Each synthetic ticker is defined by an
+// expression, such as ETH/BTC or BTC - 1.5*ETH, and is served under the
+// SYNTHETIC exchange so it can be requested anywhere an exchange and ticker are
+// accepted.
+//
+// Expressions combine tickers and numbers with +, -, *, / and parentheses.
+// Tickers are read from the default exchange of the synthetic ticker unless
+// they are qualified with an exchange, such as COINBASE:BTC, and may include a
+// quote currency, such as BTC-EUR. Subtraction must be separated from tickers
+// by spaces, otherwise it is read as part of the ticker.
+//
+// Candlesticks are aligned by the interval they open at. A security that has
+// no candlestick for an interval is carried forward at its last close, so
+// intervals are only skipped until every security has reported a price. The
+// open, close, high, and low of each security are combined separately and
+// synthetic candlesticks do not report volume.
+package synthetic
@@ -0,0 +1,344 @@
+package synthetic
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"mojito/market"
+)
+
+const (
+	// maxExpressionLength the maximum number of characters in an expression.
+	maxExpressionLength = 256
+	// maxLegs the maximum number of different securities an expression may
+	// refer to.
+	maxLegs = 8
+)
+
+// Leg is a security referred to by an expression.
+type Leg struct {
+	Exchange string `json:"exchange"`
+	Ticker   string `json:"ticker"`
+}
+
+// Expression computes synthetic candlesticks from the candlesticks of the
+// securities it refers to.
+type Expression struct {
+	root node
+	legs []Leg
+}
+
+// node is an operation in a parsed expression. Numbers are evaluated as
+// candlesticks whose prices all equal the number so they can be combined with
+// the candlestick arithmetic of the market package.
+type node interface {
+	evaluate(legs []market.Candlestick) market.Candlestick
+}
+
+// numberNode is a number in an expression.
+type numberNode float64
+
+func (n numberNode) evaluate(legs []market.Candlestick) market.Candlestick {
+	value := float64(n)
+	return market.Candlestick{Open: value, Close: value, High: value,
+		Low: value}
+}
+
+// legNode refers to the candlestick of a security in an expression.
+type legNode int
+
+func (n legNode) evaluate(legs []market.Candlestick) market.Candlestick {
+	return legs[n]
+}
+
+// binaryNode combines the results of two nodes with an arithmetic operator.
+type binaryNode struct {
+	operator    rune
+	left, right node
+}
+
+func (n binaryNode) evaluate(legs []market.Candlestick) market.Candlestick {
+
+	left, right := n.left.evaluate(legs), n.right.evaluate(legs)
+
+	switch n.operator {
+	case '+':
+		return left.AddCandlestick(right)
+	case '-':
+		return left.SubtractCandlestick(right)
+	case '*':
+		return left.MultiplyCandlestick(right)
+	default:
+		return left.DivideCandlestick(right)
+	}
+
+}
+
+// ParseExpression parses an expression over other securities. Tickers that are
+// not qualified with an exchange are read from the supplied exchange.
+func ParseExpression(expression, exchange string) (*Expression, error) {
+
+	if strings.TrimSpace(expression) == "" {
+		return nil, errors.New("expression is required")
+	}
+
+	if len(expression) > maxExpressionLength {
+		return nil, fmt.Errorf("expression must be at most %d characters",
+			maxExpressionLength)
+	}
+
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		tokens:   tokens,
+		exchange: strings.ToUpper(exchange),
+		legs:     map[Leg]int{},
+	}
+
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in expression",
+			p.tokens[p.position])
+	}
+
+	if len(p.order) == 0 {
+		return nil, errors.New("expression must refer to at least one ticker")
+	}
+
+	return &Expression{root: root, legs: p.order}, nil
+
+}
+
+// Legs lists the securities the expression refers to. Candlesticks passed to
+// Evaluate are supplied in the same order.
+func (e *Expression) Legs() []Leg {
+	return append([]Leg{}, e.legs...)
+}
+
+// Evaluate computes a synthetic candlestick from one candlestick for each leg
+// of the expression. The high and low are the highest and lowest of the
+// computed prices since combining the highs and lows of different securities
+// may reverse them, volumes are not reported. Returns false if the result is
+// not a finite number, such as when dividing by zero.
+func (e *Expression) Evaluate(legs []market.Candlestick) (market.Candlestick,
+	bool) {
+
+	result := e.root.evaluate(legs)
+
+	prices := []float64{result.Open, result.Close, result.High, result.Low}
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, price := range prices {
+		if math.IsNaN(price) || math.IsInf(price, 0) {
+			return market.Candlestick{}, false
+		}
+		high, low = math.Max(high, price), math.Min(low, price)
+	}
+
+	return market.Candlestick{
+		Open:  result.Open,
+		Close: result.Close,
+		High:  high,
+		Low:   low,
+	}, true
+
+}
+
+// parser reads an expression by recursive descent. Each ticker is assigned the
+// index of its leg the first time it is read.
+type parser struct {
+	tokens   []string
+	position int
+	exchange string
+	legs     map[Leg]int
+	order    []Leg
+}
+
+// peek gets the next token without consuming it, empty at the end of the
+// expression.
+func (p *parser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+// parseSum reads terms separated by addition or subtraction.
+func (p *parser) parseSum() (node, error) {
+
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "+" || p.peek() == "-" {
+		operator := rune(p.peek()[0])
+		p.position++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: operator, left: left, right: right}
+	}
+
+	return left, nil
+
+}
+
+// parseProduct reads factors separated by multiplication or division.
+func (p *parser) parseProduct() (node, error) {
+
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek() == "*" || p.peek() == "/" {
+		operator := rune(p.peek()[0])
+		p.position++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: operator, left: left, right: right}
+	}
+
+	return left, nil
+
+}
+
+// parseFactor reads a number, a ticker, a negated factor, or a parenthesized
+// expression.
+func (p *parser) parseFactor() (node, error) {
+
+	token := p.peek()
+	if token == "" {
+		return nil, errors.New("unexpected end of expression")
+	}
+	p.position++
+
+	switch token {
+	case "-":
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{operator: '*', left: numberNode(-1),
+			right: operand}, nil
+	case "(":
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing ) in expression")
+		}
+		p.position++
+		return inner, nil
+	case "+", "*", "/", ")":
+		return nil, fmt.Errorf("unexpected %q in expression", token)
+	}
+
+	// words starting with a digit that parse as numbers are numbers, other
+	// words are tickers such as 1INCH or NAN
+	if unicode.IsDigit(rune(token[0])) || token[0] == '.' {
+		if value, err := strconv.ParseFloat(token, 64); err == nil {
+			return numberNode(value), nil
+		}
+	}
+
+	return p.parseLeg(token)
+
+}
+
+// parseLeg reads a ticker, optionally qualified with an exchange.
+func (p *parser) parseLeg(token string) (node, error) {
+
+	leg := Leg{Exchange: p.exchange, Ticker: token}
+	if i := strings.Index(token, ":"); i >= 0 {
+		leg.Exchange, leg.Ticker = token[:i], token[i+1:]
+	}
+
+	leg.Exchange = strings.ToUpper(leg.Exchange)
+	leg.Ticker = market.FormatTicker(market.SplitTicker(leg.Ticker))
+
+	if leg.Exchange == "" {
+		return nil, fmt.Errorf("exchange is required for ticker %s",
+			leg.Ticker)
+	}
+
+	if leg.Ticker == "" || strings.Contains(leg.Ticker, ":") {
+		return nil, fmt.Errorf("invalid ticker %q in expression", token)
+	}
+
+	if IsSynthetic(leg.Exchange) {
+		return nil, errors.New("expressions may not refer to synthetic tickers")
+	}
+
+	index, ok := p.legs[leg]
+	if !ok {
+		if len(p.order) >= maxLegs {
+			return nil, fmt.Errorf("expression may refer to at most %d tickers",
+				maxLegs)
+		}
+		index = len(p.order)
+		p.legs[leg] = index
+		p.order = append(p.order, leg)
+	}
+
+	return legNode(index), nil
+
+}
+
+// tokenize splits an expression into operators, parentheses, and words. A word
+// is a number or a ticker, a hyphen between letters or digits with no spaces
+// is read as part of a ticker so currency pairs such as BTC-EUR may be used.
+func tokenize(expression string) ([]string, error) {
+
+	runes := []rune(expression)
+	tokens := []string{}
+
+	for i := 0; i < len(runes); {
+
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/()", r):
+			tokens = append(tokens, string(r))
+			i++
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && (isWordRune(runes[i]) ||
+				(runes[i] == '-' && i+1 < len(runes) &&
+					isWordRune(runes[i+1]) &&
+					unicode.IsLetter(runes[start]))) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			return nil, fmt.Errorf("unexpected %q in expression", r)
+		}
+
+	}
+
+	return tokens, nil
+
+}
+
+// isWordRune checks whether a character may appear in a number or ticker.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' ||
+		r == ':'
+}
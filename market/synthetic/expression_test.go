@@ -0,0 +1,132 @@
+package synthetic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"mojito/market"
+)
+
+// flat creates a candlestick opening the supplied number of minutes after the
+// unix epoch whose prices all equal the supplied close.
+func flat(minute int, close float64) market.Candlestick {
+	return market.Candlestick{
+		CreatedAt: time.Unix(int64(minute)*60, 0).UTC(),
+		Open:      close,
+		High:      close,
+		Low:       close,
+		Close:     close,
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+
+	tests := []struct {
+		expression string
+		legs       []float64 // the close of each leg in the order it is read
+		expected   float64
+	}{
+		{"BTC + ETH * 2", []float64{10, 3}, 16},
+		{"(BTC + ETH) * 2", []float64{10, 3}, 26},
+		{"BTC - ETH - SOL", []float64{10, 3, 1}, 6},
+		{"BTC / ETH / 2", []float64{10, 4}, 1.25},
+		{"BTC - ETH * SOL / 2", []float64{10, 3, 4}, 4},
+		{"-BTC + ETH", []float64{10, 3}, -7},
+		{"BTC * -ETH", []float64{10, 3}, -30},
+		{"--BTC", []float64{10}, 10},
+		{"-(BTC - ETH)", []float64{10, 3}, -7},
+		{"2 - -BTC", []float64{10}, 12},
+		{"-BTC * 2 + 1", []float64{10}, -19},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+
+			expression, err := ParseExpression(test.expression, "COINBASE")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(expression.Legs()) != len(test.legs) {
+				t.Fatalf("expected %d legs, got %+v", len(test.legs),
+					expression.Legs())
+			}
+
+			legs := []market.Candlestick{}
+			for _, close := range test.legs {
+				legs = append(legs, flat(0, close))
+			}
+
+			result, ok := expression.Evaluate(legs)
+			if !ok {
+				t.Fatal("expected expression to evaluate")
+			}
+			if result.Close != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, result.Close)
+			}
+
+		})
+	}
+
+}
+
+func TestParseExpressionLegs(t *testing.T) {
+
+	tests := []struct {
+		expression string
+		legs       []Leg
+	}{
+		{"BTC-ETH", []Leg{{"COINBASE", "BTC-ETH"}}},
+		{"BTC - ETH", []Leg{{"COINBASE", "BTC"}, {"COINBASE", "ETH"}}},
+		{"BTC -ETH", []Leg{{"COINBASE", "BTC"}, {"COINBASE", "ETH"}}},
+		{"BTC-ETH - ETH", []Leg{{"COINBASE", "BTC-ETH"}, {"COINBASE", "ETH"}}},
+		{"(BTC-EUR)/2", []Leg{{"COINBASE", "BTC-EUR"}}},
+		{"btc-usd / eth", []Leg{{"COINBASE", "BTC"}, {"COINBASE", "ETH"}}},
+		{"IEX:AAPL / COINBASE:BTC-EUR", []Leg{{"IEX", "AAPL"},
+			{"COINBASE", "BTC-EUR"}}},
+		{"1INCH-2", []Leg{{"COINBASE", "1INCH"}}},
+		{"BTC + BTC", []Leg{{"COINBASE", "BTC"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+
+			expression, err := ParseExpression(test.expression, "COINBASE")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if legs := expression.Legs(); !reflect.DeepEqual(legs,
+				test.legs) {
+				t.Fatalf("expected legs %+v, got %+v", test.legs, legs)
+			}
+
+		})
+	}
+
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+
+	tests := []string{
+		"",
+		"BTC +",
+		"(BTC",
+		"BTC)",
+		"BTC ETH",
+		"* BTC",
+		"2 * 3",
+		"BTC $ ETH",
+		"SYNTHETIC:BTC",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			if _, err := ParseExpression(test, "COINBASE"); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+}
@@ -0,0 +1,12 @@
+package synthetic
+
+import (
+	"mojito/data"
+)
+
+// init migrates the package model.
+func init() {
+	data.DB().AutoMigrate(
+		Synthetic{},
+	)
+}
@@ -0,0 +1,25 @@
+package synthetic
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* Data Types */
+
+// Synthetic stores the definition of a synthetic ticker. Synthetic tickers are
+// shared by all users, only the user that created a synthetic ticker or an
+// admin may change it.
+type Synthetic struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	Name       string `gorm:"index" json:"name"`           // the ticker the synthetic ticker is served as
+	Exchange   string `json:"exchange"`                    // the exchange of tickers in the expression that are not qualified with one
+	Expression string `gorm:"type:text" json:"expression"` // how candlesticks are computed from other tickers
+}
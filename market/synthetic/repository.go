@@ -0,0 +1,65 @@
+package synthetic
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GetSyntheticByID retrieves a synthetic ticker record by id.
+func GetSyntheticByID(ctx context.Context, db *gorm.DB,
+	id uint) (*Synthetic, error) {
+
+	var item Synthetic
+
+	if err := db.Model(&Synthetic{}).
+		Where("id = ?", id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// GetSyntheticByName retrieves a synthetic ticker record by name.
+func GetSyntheticByName(ctx context.Context, db *gorm.DB,
+	name string) (*Synthetic, error) {
+
+	var item Synthetic
+
+	if err := db.Model(&Synthetic{}).
+		Where("name = ?", strings.ToUpper(name)).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListSynthetic retrieves all synthetic ticker records sorted by name.
+func ListSynthetic(ctx context.Context, db *gorm.DB) ([]*Synthetic, error) {
+
+	var items []*Synthetic
+
+	if err := db.Model(&Synthetic{}).
+		Order("name").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveSynthetic inserts or updates the supplied synthetic ticker record.
+func SaveSynthetic(ctx context.Context, db *gorm.DB, item *Synthetic) error {
+	return db.Save(item).Error
+}
+
+// DeleteSynthetic deletes the supplied synthetic ticker record.
+func DeleteSynthetic(ctx context.Context, db *gorm.DB, item *Synthetic) error {
+	return db.Delete(item).Error
+}
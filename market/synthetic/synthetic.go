@@ -0,0 +1,453 @@
+package synthetic
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+	"mojito/market/feed"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Exchange is the exchange synthetic tickers are served under.
+const Exchange = "SYNTHETIC"
+
+// ErrNotFound is returned when candlesticks are requested for a synthetic
+// ticker that is not defined.
+var ErrNotFound = errors.New("synthetic ticker not found")
+
+// IsSynthetic checks whether the supplied exchange is the exchange synthetic
+// tickers are served under.
+func IsSynthetic(exchange string) bool {
+	return strings.EqualFold(exchange, Exchange)
+}
+
+// ListByTicker retrieves candlesticks for the specified exchange and ticker as
+// described by market.ListByTicker. Candlesticks for synthetic tickers are
+// computed from the candlesticks of the securities in their expression.
+func ListByTicker(ctx context.Context, db *gorm.DB, exchange, ticker string,
+	resolution market.Resolution, startDate,
+	endDate time.Time) ([]market.Candlestick, error) {
+
+	if !IsSynthetic(exchange) {
+		return market.ListByTicker(ctx, db, exchange, ticker, resolution,
+			startDate, endDate)
+	}
+
+	item, err := GetSyntheticByName(ctx, db, ticker)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	expression, err := ParseExpression(item.Expression, item.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCombiner(item.Name, expression, resolution)
+	updates := []legCandlestick{}
+
+	for i, leg := range expression.Legs() {
+
+		// carry the last close before the date range forward so the first
+		// intervals are not skipped for securities that trade infrequently
+		last, err := market.GetLastBefore(ctx, db, leg.Exchange, leg.Ticker,
+			startDate)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		} else if err == nil {
+			c.seed(i, last)
+		}
+
+		candlesticks, err := market.ListByTicker(ctx, db, leg.Exchange,
+			leg.Ticker, resolution, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candlestick := range candlesticks {
+			updates = append(updates, legCandlestick{leg: i,
+				candlestick: candlestick})
+		}
+
+	}
+
+	// combine the candlesticks in the order they open
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].candlestick.CreatedAt.Before(
+			updates[j].candlestick.CreatedAt)
+	})
+
+	items := []market.Candlestick{}
+	for _, update := range updates {
+		items = append(items, c.add(update.leg, update.candlestick)...)
+	}
+
+	return append(items, c.flush()...), nil
+
+}
+
+// legCandlestick is a candlestick for one of the legs of an expression.
+type legCandlestick struct {
+	leg         int
+	candlestick market.Candlestick
+}
+
+// combiner aligns the candlesticks of the legs of an expression and computes a
+// synthetic candlestick for each interval. Candlesticks must be added in the
+// order they open. An interval is computed once every leg has a candlestick
+// for it, or once a later interval begins, in which case legs without a
+// candlestick are carried forward at their last close.
+type combiner struct {
+	name       string
+	expression *Expression
+	resolution market.Resolution
+
+	opens   time.Time
+	pending []*market.Candlestick
+	last    []*market.Candlestick
+	done    bool
+}
+
+// newCombiner creates a combiner that computes candlesticks for the synthetic
+// ticker with the supplied name.
+func newCombiner(name string, expression *Expression,
+	resolution market.Resolution) *combiner {
+	legs := len(expression.Legs())
+	return &combiner{
+		name:       name,
+		expression: expression,
+		resolution: resolution,
+		pending:    make([]*market.Candlestick, legs),
+		last:       make([]*market.Candlestick, legs),
+	}
+}
+
+// seed sets the last candlestick of a leg before any candlesticks are added.
+func (c *combiner) seed(leg int, candlestick market.Candlestick) {
+	c.last[leg] = &candlestick
+}
+
+// add adds the next candlestick of a leg and returns any synthetic
+// candlesticks that are complete.
+func (c *combiner) add(leg int,
+	candlestick market.Candlestick) []market.Candlestick {
+
+	opens := market.BucketStart(candlestick.CreatedAt, c.resolution)
+
+	// candlesticks for intervals that have already been computed are only
+	// used to carry the leg forward
+	if opens.Before(c.opens) || (opens.Equal(c.opens) && c.done) {
+		if c.last[leg] == nil || !c.last[leg].CreatedAt.After(
+			candlestick.CreatedAt) {
+			c.last[leg] = &candlestick
+		}
+		return nil
+	}
+
+	items := []market.Candlestick{}
+	if opens.After(c.opens) {
+		items = append(items, c.flush()...)
+		c.opens, c.done = opens, false
+	}
+
+	c.pending[leg] = &candlestick
+	c.last[leg] = &candlestick
+
+	for _, pending := range c.pending {
+		if pending == nil {
+			return items
+		}
+	}
+
+	return append(items, c.flush()...)
+
+}
+
+// flush computes the synthetic candlestick of the current interval, carrying
+// forward legs that have no candlestick for it. Returns nothing if no leg has a
+// candlestick for the interval or a leg has never reported a price.
+func (c *combiner) flush() []market.Candlestick {
+
+	if c.done {
+		return nil
+	}
+
+	legs := make([]market.Candlestick, len(c.pending))
+	reported := false
+
+	for i, pending := range c.pending {
+		switch {
+		case pending != nil:
+			legs[i], reported = *pending, true
+		case c.last[i] != nil:
+			close := c.last[i].Close
+			legs[i] = market.Candlestick{Open: close, Close: close,
+				High: close, Low: close}
+		default:
+			return c.reset()
+		}
+	}
+
+	if !reported {
+		return c.reset()
+	}
+
+	candlestick, ok := c.expression.Evaluate(legs)
+
+	c.reset()
+
+	if !ok {
+		return nil
+	}
+
+	candlestick.CreatedAt = c.opens
+	candlestick.Exchange = Exchange
+	candlestick.Ticker = c.name
+
+	return []market.Candlestick{candlestick}
+
+}
+
+// reset clears the candlesticks of the current interval and marks it as
+// computed.
+func (c *combiner) reset() []market.Candlestick {
+	c.pending = make([]*market.Candlestick, len(c.pending))
+	c.done = true
+	return nil
+}
+
+// subscription delivers synthetic candlesticks computed from the candlesticks
+// committed by the market data feed.
+type subscription struct {
+	name    string
+	results chan market.Candlestick
+	done    chan struct{}
+	cancel  func() // cancels the subscriptions to the legs of the expression
+}
+
+// subscriptions keeps track of the subscriptions to each synthetic ticker by
+// name so they can be updated when the synthetic ticker changes.
+var subscriptions = struct {
+	mutex *sync.Mutex
+	items map[string]map[*subscription]bool
+}{
+	mutex: &sync.Mutex{},
+	items: map[string]map[*subscription]bool{},
+}
+
+// Securities retrieves the securities whose candlesticks the specified ticker
+// is computed from. A ticker that is not synthetic is computed from itself.
+func Securities(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) ([]Leg, error) {
+
+	if !IsSynthetic(exchange) {
+		return []Leg{{Exchange: exchange, Ticker: ticker}}, nil
+	}
+
+	item, err := GetSyntheticByName(ctx, db, ticker)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	expression, err := ParseExpression(item.Expression, item.Exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	return expression.Legs(), nil
+
+}
+
+// Subscribe retrieves a channel that will receive a candlestick every time the
+// market data feed commits price data for the specified exchange and ticker,
+// as described by feed.Subscribe. Synthetic candlesticks are computed at one
+// minute resolution as the feed commits each of the securities in the
+// expression. Returns a function that must be called to cancel the
+// subscription.
+func Subscribe(exchange, ticker string) (<-chan market.Candlestick, func(),
+	error) {
+
+	if !IsSynthetic(exchange) {
+		return feed.Subscribe(exchange, ticker)
+	}
+
+	item, err := GetSyntheticByName(context.Background(), data.DB(), ticker)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, ErrNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	s := &subscription{
+		name:    item.Name,
+		results: make(chan market.Candlestick),
+		done:    make(chan struct{}),
+	}
+
+	subscriptions.mutex.Lock()
+	defer subscriptions.mutex.Unlock()
+
+	if err := s.connect(item); err != nil {
+		return nil, nil, err
+	}
+
+	if subscriptions.items[s.name] == nil {
+		subscriptions.items[s.name] = map[*subscription]bool{}
+	}
+	subscriptions.items[s.name][s] = true
+
+	// deliver candlesticks until the subscription is cancelled, the channel
+	// is only closed here so it is never written to after it is closed
+	channel := make(chan market.Candlestick)
+	go func() {
+		defer close(channel)
+		for {
+			select {
+			case candlestick := <-s.results:
+				select {
+				case channel <- candlestick:
+				case <-s.done:
+					return
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	once := &sync.Once{}
+	return channel, func() {
+		once.Do(func() {
+			subscriptions.mutex.Lock()
+			defer subscriptions.mutex.Unlock()
+			delete(subscriptions.items[s.name], s)
+			s.cancel()
+			close(s.done)
+		})
+	}, nil
+
+}
+
+// Refresh updates the subscriptions to the synthetic ticker with the supplied
+// name after it has been changed or deleted. Subscriptions to a deleted
+// synthetic ticker stop receiving candlesticks.
+func Refresh(ctx context.Context, db *gorm.DB, name string) {
+
+	subscriptions.mutex.Lock()
+	defer subscriptions.mutex.Unlock()
+
+	items := subscriptions.items[strings.ToUpper(name)]
+	if len(items) == 0 {
+		return
+	}
+
+	item, err := GetSyntheticByName(ctx, db, name)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		logrus.Error(err)
+		return
+	}
+
+	for s := range items {
+		s.cancel()
+		s.cancel = func() {}
+		if item == nil {
+			continue
+		}
+		if err := s.connect(item); err != nil {
+			logrus.Errorf("synthetic ticker %s: %v", item.Name, err)
+		}
+	}
+
+}
+
+// connect subscribes to each leg of the expression of the supplied synthetic
+// ticker and begins computing candlesticks. The subscriptions mutex must be
+// held.
+func (s *subscription) connect(item *Synthetic) error {
+
+	expression, err := ParseExpression(item.Expression, item.Exchange)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	updates := make(chan legCandlestick)
+	cancels := []func(){}
+
+	s.cancel = func() {
+		close(stop)
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+
+	for i, leg := range expression.Legs() {
+
+		channel, cancel, err := feed.Subscribe(leg.Exchange, leg.Ticker)
+		if err != nil {
+			s.cancel()
+			s.cancel = func() {}
+			return err
+		}
+		cancels = append(cancels, cancel)
+
+		// forward the candlesticks of each leg to the combiner
+		go func(leg int, channel <-chan market.Candlestick) {
+			for candlestick := range channel {
+				select {
+				case updates <- legCandlestick{leg: leg,
+					candlestick: candlestick}:
+				case <-stop:
+					return
+				}
+			}
+		}(i, channel)
+
+	}
+
+	// carry the last stored close of each leg forward so candlesticks are
+	// computed without waiting for every leg to be committed
+	c := newCombiner(item.Name, expression, market.Resolution1Minute)
+	for i, leg := range expression.Legs() {
+		last, err := market.GetLastByTicker(context.Background(), data.DB(),
+			leg.Exchange, leg.Ticker)
+		if err == nil {
+			c.seed(i, last)
+		} else if err != gorm.ErrRecordNotFound {
+			logrus.Error(err)
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case update := <-updates:
+				for _, candlestick := range c.add(update.leg,
+					update.candlestick) {
+					select {
+					case s.results <- candlestick:
+					case <-stop:
+						return
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+
+}
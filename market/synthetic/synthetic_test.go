@@ -0,0 +1,111 @@
+package synthetic
+
+import (
+	"reflect"
+	"testing"
+
+	"mojito/market"
+)
+
+func TestCombiner(t *testing.T) {
+
+	// each step adds a one minute candlestick to a leg of A / B
+	type step struct {
+		leg    int
+		minute int
+		close  float64
+	}
+
+	// point is a synthetic candlestick produced by the combiner
+	type point struct {
+		minute int
+		close  float64
+	}
+
+	tests := []struct {
+		name     string
+		seeds    []step // candlesticks seeded before any are added
+		steps    []step
+		expected []point
+	}{
+		{
+			name:     "aligned legs",
+			steps:    []step{{0, 0, 10}, {1, 0, 2}, {0, 1, 12}, {1, 1, 3}},
+			expected: []point{{0, 5}, {1, 4}},
+		},
+		{
+			name:     "missing leg carried at its last close",
+			steps:    []step{{0, 0, 10}, {1, 0, 2}, {0, 1, 12}, {0, 2, 14}},
+			expected: []point{{0, 5}, {1, 6}, {2, 7}},
+		},
+		{
+			name: "leg arriving after a later interval begins",
+			steps: []step{{0, 0, 10}, {1, 0, 2}, {0, 1, 12}, {0, 2, 14},
+				{1, 2, 7}},
+			expected: []point{{0, 5}, {1, 6}, {2, 2}},
+		},
+		{
+			name: "late candlestick only carries the leg forward",
+			steps: []step{{0, 0, 10}, {0, 1, 12}, {1, 1, 3}, {1, 0, 2},
+				{0, 2, 15}},
+			expected: []point{{1, 4}, {2, 5}},
+		},
+		{
+			name:     "leg that never reported a price",
+			steps:    []step{{0, 0, 10}, {0, 1, 12}},
+			expected: []point{},
+		},
+		{
+			name:     "seeded leg",
+			seeds:    []step{{1, -1, 4}},
+			steps:    []step{{0, 0, 8}, {0, 1, 12}},
+			expected: []point{{0, 2}, {1, 3}},
+		},
+		{
+			name:     "division by zero skipped",
+			steps:    []step{{0, 0, 10}, {1, 0, 0}, {0, 1, 12}, {1, 1, 3}},
+			expected: []point{{1, 4}},
+		},
+	}
+
+	expression, err := ParseExpression("A / B", "COINBASE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			c := newCombiner("A_B", expression, market.Resolution1Minute)
+
+			for _, seed := range test.seeds {
+				c.seed(seed.leg, flat(seed.minute, seed.close))
+			}
+
+			items := []market.Candlestick{}
+			for _, step := range test.steps {
+				items = append(items, c.add(step.leg, flat(step.minute,
+					step.close))...)
+			}
+			items = append(items, c.flush()...)
+
+			points := []point{}
+			for _, item := range items {
+				if item.Exchange != Exchange || item.Ticker != "A_B" {
+					t.Fatalf("expected %s:A_B, got %s:%s", Exchange,
+						item.Exchange, item.Ticker)
+				}
+				points = append(points, point{
+					minute: int(item.CreatedAt.Unix() / 60),
+					close:  item.Close,
+				})
+			}
+
+			if !reflect.DeepEqual(points, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, points)
+			}
+
+		})
+	}
+
+}
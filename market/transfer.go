@@ -0,0 +1,662 @@
+package market
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Format refers to a file format used to import and export candlesticks.
+type Format string
+
+// Define supported import and export formats.
+const (
+	FormatCSV      Format = "csv"      // comma separated values with a header row
+	FormatNDJSON   Format = "ndjson"   // one JSON candlestick per line
+	FormatColumnar Format = "columnar" // a JSON object holding an array for each column
+)
+
+// ErrInvalidFormat is returned when a file format is requested that is not
+// supported.
+var ErrInvalidFormat = errors.New("invalid format, expected one of csv, ndjson, columnar")
+
+// transferColumns lists the candlestick columns in the order they are
+// exported.
+var transferColumns = []string{"created_at", "exchange", "ticker", "quote",
+	"open", "close", "high", "low", "volume", "quote_volume", "trade_count",
+	"buy_volume", "sell_volume"}
+
+// importBatchSize is the number of candlesticks inserted at once when
+// importing.
+const importBatchSize = 500
+
+// ImportResult summarizes the outcome of importing candlesticks.
+type ImportResult struct {
+	Inserted int `json:"inserted"`
+	Skipped  int `json:"skipped"`
+}
+
+// ParseFormat parses an import and export format from the supplied string.
+func ParseFormat(value string) (Format, error) {
+
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatCSV, FormatNDJSON, FormatColumnar:
+		return format, nil
+	}
+
+	return "", ErrInvalidFormat
+
+}
+
+// ContentType gets the MIME type of files in this format.
+func (f Format) ContentType() string {
+
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	}
+
+	return "application/json"
+
+}
+
+// Extension gets the file extension used for files in this format.
+func (f Format) Extension() string {
+
+	if f == FormatColumnar {
+		return "json"
+	}
+
+	return string(f)
+
+}
+
+// Export writes all candlestick records associated with the specified ticker
+// and between the specified start and end date to the supplied writer in the
+// specified format. Candlesticks are streamed from the database rather than
+// loaded at once.
+func Export(ctx context.Context, db *gorm.DB, w io.Writer, format Format,
+	exchange, ticker string, startDate, endDate time.Time) error {
+
+	buf := bufio.NewWriter(w)
+
+	// query selects the specified columns, or every column if none are
+	// specified, of the candlesticks being exported
+	query := func(columns ...string) *gorm.DB {
+		res := db.Model(&Candlestick{}).
+			Scopes(whereTicker(exchange, ticker)).
+			Where("created_at >= ? AND created_at < ?", startDate, endDate).
+			Order("created_at")
+		if len(columns) > 0 {
+			res = res.Select(columns)
+		}
+		return res
+	}
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = exportCSV(db, query(transferColumns...), buf)
+	case FormatNDJSON:
+		err = exportNDJSON(db, query(), buf)
+	case FormatColumnar:
+		err = exportColumnar(db, query, buf)
+	default:
+		return ErrInvalidFormat
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return buf.Flush()
+
+}
+
+// Import reads candlesticks in the specified format from the supplied reader
+// and inserts them in batches. Candlesticks that already exist for the same
+// exchange, ticker, quote currency, and time are skipped. Candlesticks without
+// a quote currency take it from the ticker as described by SplitTicker. The
+// open hour and open day flags of the affected candlesticks are updated once
+// all candlesticks are inserted.
+func Import(ctx context.Context, db *gorm.DB, r io.Reader,
+	format Format) (ImportResult, error) {
+
+	var next func() (Candlestick, error)
+	switch format {
+	case FormatCSV:
+		next = csvDecoder(r)
+	case FormatNDJSON:
+		next = ndjsonDecoder(r)
+	case FormatColumnar:
+		next = columnarDecoder(r)
+	default:
+		return ImportResult{}, ErrInvalidFormat
+	}
+
+	result := ImportResult{}
+
+	// track the time span imported for each security
+	type span struct {
+		exchange, ticker string
+		start, end       time.Time
+	}
+	spans := map[string]*span{}
+
+	batch := []Candlestick{}
+	for line := 1; ; line++ {
+
+		item, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return result, fmt.Errorf("record %d: %v", line, err)
+		}
+
+		if item.Exchange == "" || item.Ticker == "" || item.CreatedAt.IsZero() {
+			return result, fmt.Errorf(
+				"record %d: created_at, exchange, and ticker are required", line)
+		}
+
+		item.ID = 0
+		item.Exchange = strings.ToUpper(item.Exchange)
+		if item.Quote == "" {
+			item.Ticker, item.Quote = SplitTicker(item.Ticker)
+		} else {
+			item.Ticker = strings.ToUpper(item.Ticker)
+			item.Quote = strings.ToUpper(item.Quote)
+		}
+
+		key := item.Exchange + "-" + item.Symbol()
+		if s, ok := spans[key]; !ok {
+			spans[key] = &span{item.Exchange, item.Symbol(), item.CreatedAt,
+				item.CreatedAt}
+		} else if item.CreatedAt.Before(s.start) {
+			s.start = item.CreatedAt
+		} else if item.CreatedAt.After(s.end) {
+			s.end = item.CreatedAt
+		}
+
+		batch = append(batch, item)
+		if len(batch) < importBatchSize {
+			continue
+		}
+
+		inserted, err := importBatch(ctx, db, batch)
+		result.Inserted += inserted
+		result.Skipped += len(batch) - inserted
+		if err != nil {
+			return result, err
+		}
+
+		batch = batch[:0]
+
+	}
+
+	inserted, err := importBatch(ctx, db, batch)
+	result.Inserted += inserted
+	result.Skipped += len(batch) - inserted
+	if err != nil {
+		return result, err
+	}
+
+	for _, s := range spans {
+		if err := UpdateOpens(ctx, db, s.exchange, s.ticker, s.start,
+			s.end); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+
+}
+
+// importBatch inserts the supplied candlesticks, skipping any that already
+// exist. Returns the number of candlesticks inserted.
+func importBatch(ctx context.Context, db *gorm.DB,
+	batch []Candlestick) (int, error) {
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	// group the batch by security so existing candlesticks can be retrieved
+	// with one query per security
+	groups := map[string][]Candlestick{}
+	for _, item := range batch {
+		key := item.Exchange + "-" + item.Symbol()
+		groups[key] = append(groups[key], item)
+	}
+
+	inserts := []Candlestick{}
+	for _, items := range groups {
+
+		start, end := items[0].CreatedAt, items[0].CreatedAt
+		for _, item := range items {
+			if item.CreatedAt.Before(start) {
+				start = item.CreatedAt
+			}
+			if item.CreatedAt.After(end) {
+				end = item.CreatedAt
+			}
+		}
+
+		var existing []Candlestick
+		if err := db.Model(&Candlestick{}).
+			Select("created_at").
+			Scopes(whereTicker(items[0].Exchange, items[0].Symbol())).
+			Where("created_at >= ? AND created_at < ?",
+				start.Truncate(time.Second), end.Add(time.Second)).
+			Find(&existing).Error; err != nil {
+			return 0, err
+		}
+
+		// candlesticks are considered duplicates if they open in the same
+		// second, this also removes duplicates within the batch
+		seen := map[int64]bool{}
+		for _, item := range existing {
+			seen[item.CreatedAt.Unix()] = true
+		}
+
+		for _, item := range items {
+			if seen[item.CreatedAt.Unix()] {
+				continue
+			}
+			seen[item.CreatedAt.Unix()] = true
+			inserts = append(inserts, item)
+		}
+
+	}
+
+	return SaveCandlesticks(ctx, db, inserts)
+
+}
+
+// exportCSV writes the rows returned by the supplied query as CSV.
+func exportCSV(db, query *gorm.DB, w io.Writer) error {
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(transferColumns); err != nil {
+		return err
+	}
+
+	if err := scanCandlesticks(db, query, func(item Candlestick) error {
+		return writer.Write([]string{
+			item.CreatedAt.UTC().Format(time.RFC3339Nano),
+			item.Exchange,
+			item.Ticker,
+			item.Quote,
+			strconv.FormatFloat(item.Open, 'f', -1, 64),
+			strconv.FormatFloat(item.Close, 'f', -1, 64),
+			strconv.FormatFloat(item.High, 'f', -1, 64),
+			strconv.FormatFloat(item.Low, 'f', -1, 64),
+			strconv.FormatFloat(item.Volume, 'f', -1, 64),
+			strconv.FormatFloat(item.QuoteVolume, 'f', -1, 64),
+			strconv.Itoa(item.TradeCount),
+			strconv.FormatFloat(item.BuyVolume, 'f', -1, 64),
+			strconv.FormatFloat(item.SellVolume, 'f', -1, 64),
+		})
+	}); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+
+}
+
+// exportNDJSON writes the rows returned by the supplied query as newline
+// delimited JSON.
+func exportNDJSON(db, query *gorm.DB, w io.Writer) error {
+
+	encoder := json.NewEncoder(w)
+
+	return scanCandlesticks(db, query, func(item Candlestick) error {
+		return encoder.Encode(item)
+	})
+
+}
+
+// exportColumnar writes candlesticks as a JSON object holding an array of
+// values for each column. Each column is read with a separate query so the
+// candlesticks do not need to be held in memory.
+func exportColumnar(db *gorm.DB, query func(columns ...string) *gorm.DB,
+	w io.Writer) error {
+
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+
+	for i, column := range transferColumns {
+
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%q:[", column); err != nil {
+			return err
+		}
+
+		first := true
+		if err := scanCandlesticks(db, query(column),
+			func(item Candlestick) error {
+
+				if !first {
+					if _, err := io.WriteString(w, ","); err != nil {
+						return err
+					}
+				}
+				first = false
+
+				value, err := json.Marshal(columnValue(item, column))
+				if err != nil {
+					return err
+				}
+
+				_, err = w.Write(value)
+				return err
+
+			}); err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+
+	}
+
+	_, err := io.WriteString(w, "}\n")
+	return err
+
+}
+
+// scanCandlesticks calls the supplied function with each row returned by the
+// supplied query.
+func scanCandlesticks(db, query *gorm.DB, fn func(Candlestick) error) error {
+
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var item Candlestick
+		if err := db.ScanRows(rows, &item); err != nil {
+			return err
+		}
+
+		if err := fn(item); err != nil {
+			return err
+		}
+
+	}
+
+	return rows.Err()
+
+}
+
+// columnValue gets the value of the specified column of a candlestick.
+func columnValue(item Candlestick, column string) interface{} {
+
+	switch column {
+	case "created_at":
+		return item.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "exchange":
+		return item.Exchange
+	case "ticker":
+		return item.Ticker
+	case "quote":
+		return item.Quote
+	case "open":
+		return item.Open
+	case "close":
+		return item.Close
+	case "high":
+		return item.High
+	case "low":
+		return item.Low
+	case "volume":
+		return item.Volume
+	case "quote_volume":
+		return item.QuoteVolume
+	case "trade_count":
+		return item.TradeCount
+	case "buy_volume":
+		return item.BuyVolume
+	case "sell_volume":
+		return item.SellVolume
+	}
+
+	return nil
+
+}
+
+// csvDecoder reads candlesticks from CSV with a header row naming the
+// columns. Columns may appear in any order and unknown columns are ignored.
+func csvDecoder(r io.Reader) func() (Candlestick, error) {
+
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	var header map[string]int
+
+	return func() (Candlestick, error) {
+
+		if header == nil {
+			names, err := reader.Read()
+			if err != nil {
+				return Candlestick{}, err
+			}
+			header = map[string]int{}
+			for i, name := range names {
+				header[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+		}
+
+		record, err := reader.Read()
+		if err != nil {
+			return Candlestick{}, err
+		}
+
+		field := func(name string) string {
+			if i, ok := header[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := Candlestick{
+			Exchange: field("exchange"),
+			Ticker:   field("ticker"),
+			Quote:    field("quote"),
+		}
+
+		if item.CreatedAt, err = parseTransferTime(field("created_at")); err != nil {
+			return Candlestick{}, errors.New("invalid created_at")
+		}
+
+		for _, column := range []struct {
+			name  string
+			value *float64
+		}{
+			{"open", &item.Open},
+			{"close", &item.Close},
+			{"high", &item.High},
+			{"low", &item.Low},
+		} {
+			if *column.value, err = strconv.ParseFloat(field(column.name),
+				64); err != nil {
+				return Candlestick{}, fmt.Errorf("invalid %s", column.name)
+			}
+		}
+
+		// volume columns are optional
+		for _, column := range []struct {
+			name  string
+			value *float64
+		}{
+			{"volume", &item.Volume},
+			{"quote_volume", &item.QuoteVolume},
+			{"buy_volume", &item.BuyVolume},
+			{"sell_volume", &item.SellVolume},
+		} {
+			if value := field(column.name); value != "" {
+				if *column.value, err = strconv.ParseFloat(value,
+					64); err != nil {
+					return Candlestick{}, fmt.Errorf("invalid %s", column.name)
+				}
+			}
+		}
+
+		if value := field("trade_count"); value != "" {
+			if item.TradeCount, err = strconv.Atoi(value); err != nil {
+				return Candlestick{}, errors.New("invalid trade_count")
+			}
+		}
+
+		return item, nil
+
+	}
+
+}
+
+// ndjsonDecoder reads candlesticks from newline delimited JSON.
+func ndjsonDecoder(r io.Reader) func() (Candlestick, error) {
+
+	decoder := json.NewDecoder(r)
+
+	return func() (Candlestick, error) {
+		var item Candlestick
+		err := decoder.Decode(&item)
+		return item, err
+	}
+
+}
+
+// columnarDecoder reads candlesticks from a JSON object holding an array of
+// values for each column. The object is decoded in full before the first
+// candlestick is returned.
+func columnarDecoder(r io.Reader) func() (Candlestick, error) {
+
+	var columns struct {
+		CreatedAt []string  `json:"created_at"`
+		Exchange  []string  `json:"exchange"`
+		Ticker    []string  `json:"ticker"`
+		Open      []float64 `json:"open"`
+		Close     []float64 `json:"close"`
+		High      []float64 `json:"high"`
+		Low       []float64 `json:"low"`
+
+		// the quote and volume columns are optional
+		Quote       []string  `json:"quote"`
+		Volume      []float64 `json:"volume"`
+		QuoteVolume []float64 `json:"quote_volume"`
+		TradeCount  []int     `json:"trade_count"`
+		BuyVolume   []float64 `json:"buy_volume"`
+		SellVolume  []float64 `json:"sell_volume"`
+	}
+
+	var decoded bool
+	var index int
+
+	return func() (Candlestick, error) {
+
+		if !decoded {
+			decoded = true
+			if err := json.NewDecoder(r).Decode(&columns); err != nil {
+				return Candlestick{}, err
+			}
+			n := len(columns.CreatedAt)
+			if len(columns.Exchange) != n || len(columns.Ticker) != n ||
+				len(columns.Open) != n || len(columns.Close) != n ||
+				len(columns.High) != n || len(columns.Low) != n ||
+				(columns.Quote != nil && len(columns.Quote) != n) ||
+				(columns.Volume != nil && len(columns.Volume) != n) ||
+				(columns.QuoteVolume != nil && len(columns.QuoteVolume) != n) ||
+				(columns.TradeCount != nil && len(columns.TradeCount) != n) ||
+				(columns.BuyVolume != nil && len(columns.BuyVolume) != n) ||
+				(columns.SellVolume != nil && len(columns.SellVolume) != n) {
+				return Candlestick{}, errors.New("columns must be the same length")
+			}
+		}
+
+		if index >= len(columns.CreatedAt) {
+			return Candlestick{}, io.EOF
+		}
+
+		i := index
+		index++
+
+		createdAt, err := parseTransferTime(columns.CreatedAt[i])
+		if err != nil {
+			return Candlestick{}, errors.New("invalid created_at")
+		}
+
+		item := Candlestick{
+			CreatedAt: createdAt,
+			Exchange:  columns.Exchange[i],
+			Ticker:    columns.Ticker[i],
+			Open:      columns.Open[i],
+			Close:     columns.Close[i],
+			High:      columns.High[i],
+			Low:       columns.Low[i],
+		}
+
+		if columns.Quote != nil {
+			item.Quote = columns.Quote[i]
+		}
+
+		if columns.Volume != nil {
+			item.Volume = columns.Volume[i]
+		}
+
+		if columns.QuoteVolume != nil {
+			item.QuoteVolume = columns.QuoteVolume[i]
+		}
+
+		if columns.TradeCount != nil {
+			item.TradeCount = columns.TradeCount[i]
+		}
+
+		if columns.BuyVolume != nil {
+			item.BuyVolume = columns.BuyVolume[i]
+		}
+
+		if columns.SellVolume != nil {
+			item.SellVolume = columns.SellVolume[i]
+		}
+
+		return item, nil
+
+	}
+
+}
+
+// parseTransferTime parses an imported candlestick time. Times may be
+// formatted as RFC 3339 strings or unix timestamps in seconds.
+func parseTransferTime(value string) (time.Time, error) {
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339Nano, value)
+
+}
@@ -0,0 +1,2 @@
+// Package delivery exposes an API for trading through a paper trading account.
+package delivery
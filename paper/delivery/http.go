@@ -0,0 +1,314 @@
+package delivery
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mojito/data"
+	"mojito/httperror"
+	"mojito/paper"
+	"mojito/server"
+	"mojito/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// init registers the paper trading API with the application router.
+func init() {
+
+	// bind private endpoints
+	server.Router().GET(listBalanceEndpoint, user.JWTAuthMiddleware(),
+		listBalance)
+	server.Router().GET(listOpenOrderEndpoint, user.JWTAuthMiddleware(),
+		listOpenOrder)
+	server.Router().GET(listOrderHistoryEndpoint, user.JWTAuthMiddleware(),
+		listOrderHistory)
+	server.Router().GET(listFillEndpoint, user.JWTAuthMiddleware(), listFill)
+	server.Router().POST(placeOrderEndpoint, user.JWTAuthMiddleware(),
+		placeOrder)
+	server.Router().DELETE(cancelOrderEndpoint, user.JWTAuthMiddleware(),
+		cancelOrder)
+	server.Router().POST(resetAccountEndpoint, user.JWTAuthMiddleware(),
+		resetAccount)
+
+}
+
+const (
+	// listBalanceEndpoint the API endpoint used to retrieve the balances in the
+	// logged in user's paper trading account.
+	listBalanceEndpoint = "/paper/balance"
+	// listOpenOrderEndpoint the API endpoint used to retrieve open paper
+	// trading orders.
+	listOpenOrderEndpoint = "/paper/order"
+	// listOrderHistoryEndpoint the API endpoint used to retrieve paper trading
+	// orders that are no longer open.
+	listOrderHistoryEndpoint = "/paper/order/history"
+	// listFillEndpoint the API endpoint used to retrieve paper trading fills.
+	listFillEndpoint = "/paper/fill"
+	// placeOrderEndpoint the API endpoint used to place a paper trading order.
+	placeOrderEndpoint = "/paper/order"
+	// cancelOrderEndpoint the API endpoint used to cancel an open paper
+	// trading order.
+	cancelOrderEndpoint = "/paper/order/:id"
+	// resetAccountEndpoint the API endpoint used to restore the logged in
+	// user's paper trading account to the starting cash.
+	resetAccountEndpoint = "/paper/reset"
+	// orderNotFound is an error message returned when the requested order does
+	// not exist or belongs to another user.
+	orderNotFound = "order not found"
+)
+
+// listBalance retrieves the balances in the logged in user's paper trading
+// account.
+func listBalance(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve balances
+	balances, err := paper.ListBalance(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	response := []balanceResponse{}
+	for _, balance := range balances {
+		response = append(response, balanceResponse{
+			Balance:   *balance,
+			Available: balance.Available(),
+		})
+	}
+
+	// respond with balances
+	c.JSON(http.StatusOK, response)
+
+}
+
+// listOpenOrder retrieves the logged in user's open paper trading orders.
+func listOpenOrder(c *gin.Context) {
+	listOrder(c, paper.OrderStatusOpen)
+}
+
+// listOrderHistory retrieves the logged in user's paper trading orders that
+// have been filled, cancelled, or rejected.
+func listOrderHistory(c *gin.Context) {
+	listOrder(c, paper.OrderStatusFilled, paper.OrderStatusCancelled,
+		paper.OrderStatusRejected)
+}
+
+// listOrder retrieves the logged in user's paper trading orders with any of the
+// supplied statuses.
+func listOrder(c *gin.Context, statuses ...paper.OrderStatus) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve orders
+	orders, err := paper.ListOrderByUserID(c, data.DB(), u.ID, statuses...)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with orders
+	c.JSON(http.StatusOK, orders)
+
+}
+
+// listFill retrieves the fills in the logged in user's paper trading account.
+func listFill(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// retrieve fills
+	fills, err := paper.ListFillByUserID(c, data.DB(), u.ID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with fills
+	c.JSON(http.StatusOK, fills)
+
+}
+
+// placeOrder places an order through the logged in user's paper trading
+// account.
+func placeOrder(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	var req placeOrderRequest
+
+	// read request parameters
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: "invalid request body",
+		})
+		return
+	}
+
+	order := &paper.Order{
+		UserID:     u.ID,
+		Exchange:   req.Exchange,
+		Ticker:     req.Ticker,
+		Side:       paper.Side(strings.ToLower(req.Side)),
+		Type:       paper.OrderType(strings.ToLower(req.Type)),
+		Quantity:   req.Quantity,
+		LimitPrice: req.LimitPrice,
+		StopPrice:  req.StopPrice,
+	}
+
+	// validate request parameters
+	if err := paper.ValidateOrder(order); err != nil {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	}
+
+	// place the order
+	if err := paper.PlaceOrder(c, data.DB(), order); err == paper.ErrInsufficientFunds ||
+		err == paper.ErrNoPrice {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the order
+	c.JSON(http.StatusOK, order)
+
+}
+
+// cancelOrder cancels one of the logged in user's open paper trading orders.
+func cancelOrder(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// read path parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: orderNotFound,
+		})
+		return
+	}
+
+	// retrieve the order
+	order, err := paper.GetOrderByID(c, data.DB(), u.ID, uint(id))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, httperror.ErrorResponse{
+			ErrorMessage: orderNotFound,
+		})
+		return
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// cancel the order
+	if err := paper.CancelOrder(c, data.DB(), order); err == paper.ErrOrderNotOpen {
+		c.JSON(http.StatusBadRequest, httperror.ErrorResponse{
+			ErrorMessage: err.Error(),
+		})
+		return
+	} else if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with the cancelled order
+	c.JSON(http.StatusOK, order)
+
+}
+
+// resetAccount cancels all open orders in the logged in user's paper trading
+// account and restores the account to the starting cash.
+func resetAccount(c *gin.Context) {
+
+	// get user from JWT
+	u, err := user.JWTGetUser(c)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	if err := paper.ResetAccount(c, data.DB(), u.ID); err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+			ErrorMessage: httperror.InternalServerError,
+		})
+		return
+	}
+
+	// respond with 200 - OK if the account was reset
+	c.Status(http.StatusOK)
+
+}
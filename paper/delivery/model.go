@@ -0,0 +1,20 @@
+package delivery
+
+import "mojito/paper"
+
+// placeOrderRequest is used to read a request to the place order endpoint.
+type placeOrderRequest struct {
+	Exchange   string  `json:"exchange"`
+	Ticker     string  `json:"ticker"`
+	Side       string  `json:"side"`
+	Type       string  `json:"type"`
+	Quantity   float64 `json:"quantity"`
+	LimitPrice float64 `json:"limit_price"`
+	StopPrice  float64 `json:"stop_price"`
+}
+
+// balanceResponse is used to format a paper trading balance in API responses.
+type balanceResponse struct {
+	paper.Balance
+	Available float64 `json:"available"`
+}
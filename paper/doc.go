@@ -0,0 +1,16 @@
+// Package paper provides a simulated exchange for trading with virtual funds.
+// Each user has a paper trading account holding virtual cash and assets.
+// Orders are filled against candlesticks committed by the market data feed:
+// market orders at the close of the next candlestick, limit and stop orders
+// once the price reaches them. Orders cannot be placed on exchanges the feed
+// does not serve.
+//
+// Environment:
+//     MOJITO_PAPER_STARTING_CASH
+//         float - the amount of virtual cash a new paper trading account
+//                 starts with.
+//                 Default: 100000
+//     MOJITO_PAPER_FEE_RATE
+//         float - the fraction of each fill's value charged as a fee.
+//                 Default: 0.005
+package paper
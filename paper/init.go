@@ -0,0 +1,52 @@
+package paper
+
+import (
+	"context"
+
+	"mojito/data"
+	"mojito/env"
+
+	"github.com/sirupsen/logrus"
+)
+
+// init migrates the package model and reads the paper trading configuration.
+func init() {
+
+	data.DB().AutoMigrate(
+		Balance{},
+		Order{},
+		Fill{},
+	)
+
+	startingCash = env.GetFloat64Safe(startingCashVariable, 100000)
+	feeRate = env.GetFloat64Safe(feeRateVariable, 0.005)
+
+}
+
+// Resume resumes watching the price of securities with open orders. Resume
+// should be called once the market data feeds are started.
+func Resume() {
+
+	// retrieve open orders
+	orders, err := ListOpenOrder(context.Background(), data.DB())
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	// watch the price of each security with open orders
+	for _, order := range orders {
+		if err := watch(order.Exchange, order.Ticker); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+}
+
+const (
+	// startingCashVariable defines an environment variable for the amount of
+	// virtual cash in a new paper trading account.
+	startingCashVariable = "MOJITO_PAPER_STARTING_CASH"
+	// feeRateVariable defines an environment variable for the fraction of each
+	// fill's value charged as a fee.
+	feeRateVariable = "MOJITO_PAPER_FEE_RATE"
+)
@@ -0,0 +1,98 @@
+package paper
+
+import (
+	"time"
+)
+
+// CashAsset is the asset that stores virtual cash in a paper trading account.
+const CashAsset = "USD"
+
+// Side determines whether an order buys or sells.
+type Side string
+
+// Define order sides.
+const (
+	SideBuy  Side = "buy"
+	SideSell Side = "sell"
+)
+
+// OrderType determines how the price of an order is chosen.
+type OrderType string
+
+// Define order types.
+const (
+	OrderTypeMarket OrderType = "market" // filled at the next available price
+	OrderTypeLimit  OrderType = "limit"  // filled once the price reaches the limit price or better
+	OrderTypeStop   OrderType = "stop"   // becomes a market order once the price reaches the stop price
+)
+
+// OrderStatus describes the state of an order.
+type OrderStatus string
+
+// Define order statuses.
+const (
+	OrderStatusOpen      OrderStatus = "open"
+	OrderStatusFilled    OrderStatus = "filled"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRejected  OrderStatus = "rejected"
+)
+
+/* Data Types */
+
+// Balance stores the amount of an asset held in a user's paper trading
+// account.
+type Balance struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint    `gorm:"index" json:"user_id"`
+	Asset  string  `gorm:"index" json:"asset"`
+	Amount float64 `json:"amount"`
+	Hold   float64 `json:"hold"` // the portion of the amount reserved by open orders
+}
+
+// Available gets the amount of the asset that is not reserved by open orders.
+func (b Balance) Available() float64 {
+	return b.Amount - b.Hold
+}
+
+// Order stores an order placed through a user's paper trading account.
+type Order struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID uint `gorm:"index" json:"user_id"`
+
+	Exchange   string      `gorm:"index" json:"exchange"`
+	Ticker     string      `gorm:"index" json:"ticker"`
+	Side       Side        `json:"side"`
+	Type       OrderType   `json:"type"`
+	Quantity   float64     `json:"quantity"`
+	LimitPrice float64     `json:"limit_price"`
+	StopPrice  float64     `json:"stop_price"`
+	Status     OrderStatus `gorm:"index" json:"status"`
+	Reason     string      `json:"reason"` // records why an order was rejected
+
+	Hold     float64    `json:"hold"` // the amount of the quote currency or base asset reserved by this order
+	FilledAt *time.Time `json:"filled_at"`
+
+	Fills []Fill `json:"fills,omitempty"`
+}
+
+// Fill records the execution of an order.
+type Fill struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrderID uint `gorm:"index" json:"order_id"`
+	UserID  uint `gorm:"index" json:"user_id"`
+
+	Exchange string  `json:"exchange"`
+	Ticker   string  `json:"ticker"`
+	Side     Side    `json:"side"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	Fee      float64 `json:"fee"`
+}
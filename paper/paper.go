@@ -0,0 +1,501 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"mojito/data"
+	"mojito/market"
+	"mojito/market/feed"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrInsufficientFunds is returned when an order is placed that the paper
+// trading account cannot afford.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrNoPrice is returned when an order cannot be filled because there is no
+// price data for the security.
+var ErrNoPrice = errors.New("no price data for this security")
+
+// ErrOrderNotOpen is returned when attempting to cancel an order that is no
+// longer open.
+var ErrOrderNotOpen = errors.New("order is not open")
+
+// startingCash is the amount of virtual cash in a new paper trading account.
+var startingCash float64
+
+// feeRate is the fraction of each fill's value charged as a fee.
+var feeRate float64
+
+// mutex serializes changes to paper trading accounts so orders are never
+// filled twice or filled against stale balances.
+var mutex = &sync.Mutex{}
+
+// watchers tracks the securities whose price is being watched to fill open
+// orders along with the function that stops watching each of them.
+var watchers = struct {
+	mutex   *sync.Mutex
+	cancels map[string]func()
+}{
+	mutex:   &sync.Mutex{},
+	cancels: map[string]func(){},
+}
+
+// FeeRate gets the fraction of each fill's value charged as a fee.
+func FeeRate() float64 {
+	return feeRate
+}
+
+// ListBalance retrieves all balances in a user's paper trading account. If the
+// user does not have an account one is opened with the starting cash.
+func ListBalance(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Balance, error) {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := openAccount(ctx, db, userID); err != nil {
+		return nil, err
+	}
+
+	return ListBalanceByUserID(ctx, db, userID)
+
+}
+
+// ResetAccount cancels all open orders in a user's paper trading account and
+// restores the account to the starting cash.
+func ResetAccount(ctx context.Context, db *gorm.DB, userID uint) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+
+		if err := tx.Model(&Order{}).
+			Where("user_id = ? AND status = ?", userID, OrderStatusOpen).
+			Updates(map[string]interface{}{
+				"status": OrderStatusCancelled,
+				"hold":   0,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := DeleteBalanceByUserID(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return openAccount(ctx, tx, userID)
+
+	})
+
+}
+
+// ValidateOrder checks that the supplied order is well formed.
+func ValidateOrder(order *Order) error {
+
+	if order.Exchange == "" || order.Ticker == "" {
+		return errors.New("exchange and ticker are required")
+	}
+
+	if order.Side != SideBuy && order.Side != SideSell {
+		return errors.New("invalid side, expected buy or sell")
+	}
+
+	if order.Quantity <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+
+	switch order.Type {
+	case OrderTypeMarket:
+	case OrderTypeLimit:
+		if order.LimitPrice <= 0 {
+			return errors.New("limit price must be greater than zero")
+		}
+	case OrderTypeStop:
+		if order.StopPrice <= 0 {
+			return errors.New("stop price must be greater than zero")
+		}
+	default:
+		return errors.New("invalid type, expected market, limit, or stop")
+	}
+
+	return nil
+
+}
+
+// PlaceOrder places the supplied order through the paper trading account of
+// the user it belongs to. Orders can only be placed if the market data feed
+// tracks the exchange. Market orders are filled at the close of the next
+// candlestick committed by the feed, other orders remain open until the price
+// reaches them. Orders reserve the funds they need while they are open, except
+// market buys, whose price is not known until they are filled.
+func PlaceOrder(ctx context.Context, db *gorm.DB, order *Order) error {
+
+	if err := ValidateOrder(order); err != nil {
+		return err
+	}
+
+	order.Exchange = strings.ToUpper(order.Exchange)
+	order.Ticker = strings.ToUpper(order.Ticker)
+	order.Status = OrderStatusOpen
+
+	// orders are filled against candlesticks committed by the market data
+	// feed, the latest price is used to check that a market buy is affordable
+	f, err := feed.ForExchange(order.Exchange)
+	if err != nil {
+		return ErrNoPrice
+	}
+
+	var price float64
+	if candlestick, err := f.Check(order.Exchange,
+		order.Ticker); err == nil {
+		price = candlestick.Close
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+
+		if err := openAccount(ctx, tx, order.UserID); err != nil {
+			return err
+		}
+
+		// reserve the funds needed to fill the order
+		asset, amount := heldAsset(order), order.Quantity
+		if order.Side == SideBuy {
+			switch order.Type {
+			case OrderTypeMarket:
+				amount = order.Quantity * price * (1 + feeRate)
+			case OrderTypeLimit:
+				amount = order.Quantity * order.LimitPrice * (1 + feeRate)
+			case OrderTypeStop:
+				amount = order.Quantity * order.StopPrice * (1 + feeRate)
+			}
+		}
+
+		balance, err := GetBalance(ctx, tx, order.UserID, asset)
+		if err != nil {
+			return err
+		}
+
+		if balance.Available() < amount {
+			return ErrInsufficientFunds
+		}
+
+		if order.Type != OrderTypeMarket || order.Side != SideBuy {
+			balance.Hold += amount
+			order.Hold = amount
+		}
+
+		if err := SaveBalance(ctx, tx, balance); err != nil {
+			return err
+		}
+
+		return SaveOrder(ctx, tx, order)
+
+	}); err != nil {
+		return err
+	}
+
+	// watch the price of the security so the order can be filled
+	return watch(order.Exchange, order.Ticker)
+
+}
+
+// CancelOrder cancels the supplied open order and releases the funds it
+// reserved.
+func CancelOrder(ctx context.Context, db *gorm.DB, order *Order) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+
+		// reload the order in case it was filled since it was retrieved
+		current, err := GetOrderByID(ctx, tx, order.UserID, order.ID)
+		if err != nil {
+			return err
+		}
+
+		if current.Status != OrderStatusOpen {
+			return ErrOrderNotOpen
+		}
+
+		if err := releaseHold(ctx, tx, current); err != nil {
+			return err
+		}
+
+		current.Status = OrderStatusCancelled
+		if err := SaveOrder(ctx, tx, current); err != nil {
+			return err
+		}
+
+		*order = *current
+		return nil
+
+	})
+
+}
+
+// openAccount opens a paper trading account with the starting cash if the
+// user does not already have one.
+func openAccount(ctx context.Context, db *gorm.DB, userID uint) error {
+
+	balances, err := ListBalanceByUserID(ctx, db, userID)
+	if err != nil {
+		return err
+	}
+
+	if len(balances) > 0 {
+		return nil
+	}
+
+	return SaveBalance(ctx, db, &Balance{
+		UserID: userID,
+		Asset:  CashAsset,
+		Amount: startingCash,
+	})
+
+}
+
+// releaseHold returns the funds reserved by an order to the account.
+func releaseHold(ctx context.Context, db *gorm.DB, order *Order) error {
+
+	if order.Hold == 0 {
+		return nil
+	}
+
+	balance, err := GetBalance(ctx, db, order.UserID, heldAsset(order))
+	if err != nil {
+		return err
+	}
+
+	balance.Hold = math.Max(balance.Hold-order.Hold, 0)
+	order.Hold = 0
+
+	return SaveBalance(ctx, db, balance)
+
+}
+
+// heldAsset gets the asset an order reserves while it is open: the quote
+// currency of the security for buys and the base asset for sells.
+func heldAsset(order *Order) string {
+	base, quote := market.SplitTicker(order.Ticker)
+	if order.Side == SideBuy {
+		return quote
+	}
+	return base
+}
+
+// fill executes the supplied order at the supplied price, updating the account
+// balances and recording the fill. If the account cannot afford the order it
+// is rejected.
+func fill(ctx context.Context, db *gorm.DB, order *Order,
+	price float64) error {
+
+	if err := releaseHold(ctx, db, order); err != nil {
+		return err
+	}
+
+	// the order trades the base asset of the security for its quote currency
+	base, quote := market.SplitTicker(order.Ticker)
+
+	cash, err := GetBalance(ctx, db, order.UserID, quote)
+	if err != nil {
+		return err
+	}
+
+	asset, err := GetBalance(ctx, db, order.UserID, base)
+	if err != nil {
+		return err
+	}
+
+	value := order.Quantity * price
+	fee := value * feeRate
+
+	// check that the account can afford the order
+	if (order.Side == SideBuy && cash.Available() < value+fee) ||
+		(order.Side == SideSell && asset.Available() < order.Quantity) {
+		order.Status = OrderStatusRejected
+		order.Reason = ErrInsufficientFunds.Error()
+		return SaveOrder(ctx, db, order)
+	}
+
+	if order.Side == SideBuy {
+		cash.Amount -= value + fee
+		asset.Amount += order.Quantity
+	} else {
+		cash.Amount += value - fee
+		asset.Amount -= order.Quantity
+	}
+
+	if err := SaveBalance(ctx, db, cash); err != nil {
+		return err
+	}
+
+	if err := SaveBalance(ctx, db, asset); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	order.Status = OrderStatusFilled
+	order.FilledAt = &now
+
+	if err := SaveOrder(ctx, db, order); err != nil {
+		return err
+	}
+
+	item := Fill{
+		OrderID:  order.ID,
+		UserID:   order.UserID,
+		Exchange: order.Exchange,
+		Ticker:   order.Ticker,
+		Side:     order.Side,
+		Quantity: order.Quantity,
+		Price:    price,
+		Fee:      fee,
+	}
+
+	if err := SaveFill(ctx, db, &item); err != nil {
+		return err
+	}
+
+	order.Fills = append(order.Fills, item)
+	return nil
+
+}
+
+// watch subscribes to the candlesticks committed for the specified security
+// and fills any open orders the price reaches. The security is watched until
+// it has no open orders.
+func watch(exchange, ticker string) error {
+
+	key := watchKey(exchange, ticker)
+
+	watchers.mutex.Lock()
+	defer watchers.mutex.Unlock()
+
+	if _, ok := watchers.cancels[key]; ok {
+		return nil
+	}
+
+	channel, cancel, err := feed.Subscribe(exchange, ticker)
+	if err != nil {
+		return err
+	}
+
+	watchers.cancels[key] = cancel
+
+	go func() {
+		for candlestick := range channel {
+			if err := match(context.Background(), data.DB(), exchange,
+				ticker, candlestick); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}()
+
+	return nil
+
+}
+
+// unwatch stops watching the price of the specified security.
+func unwatch(exchange, ticker string) {
+
+	watchers.mutex.Lock()
+	defer watchers.mutex.Unlock()
+
+	key := watchKey(exchange, ticker)
+	if cancel, ok := watchers.cancels[key]; ok {
+		cancel()
+		delete(watchers.cancels, key)
+	}
+
+}
+
+// watchKey formats the key used to track the watcher of a security.
+func watchKey(exchange, ticker string) string {
+	return strings.ToUpper(exchange + "-" + ticker)
+}
+
+// match fills each open order for the specified security whose price was
+// reached during the supplied candlestick. The security is no longer watched
+// once it has no open orders.
+func match(ctx context.Context, db *gorm.DB, exchange, ticker string,
+	candlestick market.Candlestick) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	orders, err := ListOpenOrderByTicker(ctx, db, exchange, ticker)
+	if err != nil {
+		return err
+	}
+
+	open := 0
+	for _, order := range orders {
+
+		price, ok := triggerPrice(order, candlestick)
+		if !ok {
+			open++
+			continue
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return fill(ctx, tx, order, price)
+		}); err != nil {
+			logrus.Error(err)
+			open++
+		}
+
+	}
+
+	// orders are placed while holding the mutex and watch their security
+	// once placed, so no order can be left without a watcher
+	if open == 0 {
+		unwatch(exchange, ticker)
+	}
+
+	return nil
+
+}
+
+// triggerPrice determines whether the price reached an order during the
+// supplied candlestick and, if so, the price the order is filled at. Market
+// orders are filled at the close. If the candlestick opens beyond the price of
+// a limit or stop order the order is filled at the open.
+func triggerPrice(order *Order,
+	candlestick market.Candlestick) (float64, bool) {
+
+	switch {
+	case order.Type == OrderTypeMarket:
+		return candlestick.Close, true
+	case order.Type == OrderTypeLimit && order.Side == SideBuy:
+		if candlestick.Low <= order.LimitPrice {
+			return math.Min(order.LimitPrice, candlestick.Open), true
+		}
+	case order.Type == OrderTypeLimit && order.Side == SideSell:
+		if candlestick.High >= order.LimitPrice {
+			return math.Max(order.LimitPrice, candlestick.Open), true
+		}
+	case order.Type == OrderTypeStop && order.Side == SideBuy:
+		if candlestick.High >= order.StopPrice {
+			return math.Max(order.StopPrice, candlestick.Open), true
+		}
+	case order.Type == OrderTypeStop && order.Side == SideSell:
+		if candlestick.Low <= order.StopPrice {
+			return math.Min(order.StopPrice, candlestick.Open), true
+		}
+	}
+
+	return 0, false
+
+}
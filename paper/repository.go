@@ -0,0 +1,169 @@
+package paper
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Balance                                                                    //
+////////////////////////////////////////////////////////////////////////////////
+
+// GetBalance retrieves the balance of the specified asset in a user's paper
+// trading account. If the account holds none of the asset an empty balance is
+// returned.
+func GetBalance(ctx context.Context, db *gorm.DB, userID uint,
+	asset string) (*Balance, error) {
+
+	var items []*Balance
+
+	if err := db.Model(&Balance{}).
+		Where("user_id = ? AND asset = ?", userID, asset).
+		Limit(1).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return &Balance{UserID: userID, Asset: asset}, nil
+	}
+
+	return items[0], nil
+
+}
+
+// ListBalanceByUserID retrieves all balances in a user's paper trading
+// account.
+func ListBalanceByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Balance, error) {
+
+	var items []*Balance
+
+	if err := db.Model(&Balance{}).
+		Where("user_id = ?", userID).
+		Order("asset").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveBalance inserts or updates the supplied balance record.
+func SaveBalance(ctx context.Context, db *gorm.DB, item *Balance) error {
+	return db.Save(item).Error
+}
+
+// DeleteBalanceByUserID deletes all balances in a user's paper trading
+// account.
+func DeleteBalanceByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) error {
+	return db.Where("user_id = ?", userID).Delete(&Balance{}).Error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Order                                                                      //
+////////////////////////////////////////////////////////////////////////////////
+
+// GetOrderByID retrieves an order record and its fills by id. The order must
+// belong to the specified user.
+func GetOrderByID(ctx context.Context, db *gorm.DB, userID,
+	id uint) (*Order, error) {
+
+	var item Order
+
+	if err := db.Preload("Fills").Model(&Order{}).
+		Where("user_id = ? AND id = ?", userID, id).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// ListOrderByUserID retrieves a user's orders with any of the supplied
+// statuses, most recent first.
+func ListOrderByUserID(ctx context.Context, db *gorm.DB, userID uint,
+	statuses ...OrderStatus) ([]*Order, error) {
+
+	var items []*Order
+
+	if err := db.Preload("Fills").Model(&Order{}).
+		Where("user_id = ? AND status IN ?", userID, statuses).
+		Order("created_at DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// ListOpenOrder retrieves all open orders.
+func ListOpenOrder(ctx context.Context, db *gorm.DB) ([]*Order, error) {
+
+	var items []*Order
+
+	if err := db.Model(&Order{}).
+		Where("status = ?", OrderStatusOpen).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// ListOpenOrderByTicker retrieves all open orders for the specified exchange
+// and ticker in the order they were placed.
+func ListOpenOrderByTicker(ctx context.Context, db *gorm.DB, exchange,
+	ticker string) ([]*Order, error) {
+
+	var items []*Order
+
+	if err := db.Model(&Order{}).
+		Where("exchange = ? AND ticker = ? AND status = ?", exchange, ticker,
+			OrderStatusOpen).
+		Order("id").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveOrder inserts or updates the supplied order record.
+func SaveOrder(ctx context.Context, db *gorm.DB, item *Order) error {
+	return db.Save(item).Error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Fill                                                                       //
+////////////////////////////////////////////////////////////////////////////////
+
+// ListFillByUserID retrieves all fills in a user's paper trading account, most
+// recent first.
+func ListFillByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) ([]*Fill, error) {
+
+	var items []*Fill
+
+	if err := db.Model(&Fill{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+
+}
+
+// SaveFill inserts or updates the supplied fill record.
+func SaveFill(ctx context.Context, db *gorm.DB, item *Fill) error {
+	return db.Save(item).Error
+}
@@ -0,0 +1,6 @@
+// Package pubsub provides an in-process message broker. Producers publish
+// messages to named topics without waiting on consumers, each subscriber to a
+// topic receives messages through its own bounded buffer. A policy chosen at
+// subscription time determines whether a subscriber that falls behind misses
+// messages or is disconnected.
+package pubsub
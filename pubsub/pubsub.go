@@ -0,0 +1,201 @@
+package pubsub
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy determines what happens when a message is published to a subscriber
+// whose buffer is full.
+type Policy int
+
+// Define subscriber policies.
+const (
+	// PolicyDrop discards the message for that subscriber only.
+	PolicyDrop Policy = iota
+	// PolicyDisconnect unsubscribes the subscriber, closing its channel.
+	PolicyDisconnect
+)
+
+// Hub delivers messages published to a topic to every subscriber of the topic.
+type Hub struct {
+	mutex  *sync.RWMutex
+	topics map[string]map[*Subscription]bool
+}
+
+// Subscription receives the messages published to a single topic.
+type Subscription struct {
+	dropped uint64 // accessed atomically, kept first for alignment
+
+	// C receives each message published to the topic. The channel is closed
+	// when the subscription ends.
+	C <-chan interface{}
+
+	hub     *Hub
+	topic   string
+	channel chan interface{}
+	done    chan struct{}
+	policy  Policy
+	once    *sync.Once
+}
+
+// defaultHub is the hub used by the package level functions.
+var defaultHub = NewHub()
+
+// NewHub creates a hub with no topics.
+func NewHub() *Hub {
+	return &Hub{
+		mutex:  &sync.RWMutex{},
+		topics: map[string]map[*Subscription]bool{},
+	}
+}
+
+// Subscribe creates a subscription to the specified topic on the default hub.
+func Subscribe(topic string, size int, policy Policy) *Subscription {
+	return defaultHub.Subscribe(topic, size, policy)
+}
+
+// Publish delivers a message to every subscriber of the specified topic on the
+// default hub.
+func Publish(topic string, message interface{}) int {
+	return defaultHub.Publish(topic, message)
+}
+
+// Subscribers counts the subscribers to the specified topic on the default hub.
+func Subscribers(topic string) int {
+	return defaultHub.Subscribers(topic)
+}
+
+// Topics lists each topic with at least one subscriber on the default hub.
+func Topics() []string {
+	return defaultHub.Topics()
+}
+
+// Subscribe creates a subscription to the specified topic. Up to size messages
+// are buffered for the subscriber, the policy determines what happens to
+// messages published once the buffer is full.
+func (h *Hub) Subscribe(topic string, size int, policy Policy) *Subscription {
+
+	if size < 0 {
+		size = 0
+	}
+
+	channel := make(chan interface{}, size)
+
+	s := &Subscription{
+		C:       channel,
+		hub:     h,
+		topic:   topic,
+		channel: channel,
+		done:    make(chan struct{}),
+		policy:  policy,
+		once:    &sync.Once{},
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	subscribers, ok := h.topics[topic]
+	if !ok {
+		subscribers = map[*Subscription]bool{}
+		h.topics[topic] = subscribers
+	}
+
+	subscribers[s] = true
+
+	return s
+
+}
+
+// Publish delivers a message to every subscriber of the specified topic
+// without blocking. Returns the number of subscribers the message was
+// delivered to.
+func (h *Hub) Publish(topic string, message interface{}) int {
+
+	delivered := 0
+	disconnect := []*Subscription{}
+
+	h.mutex.RLock()
+	for s := range h.topics[topic] {
+		select {
+		case s.channel <- message:
+			delivered++
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			if s.policy == PolicyDisconnect {
+				disconnect = append(disconnect, s)
+			}
+		}
+	}
+	h.mutex.RUnlock()
+
+	// subscribers cannot be removed while the hub is read locked
+	for _, s := range disconnect {
+		s.Unsubscribe()
+	}
+
+	return delivered
+
+}
+
+// Subscribers counts the subscribers to the specified topic.
+func (h *Hub) Subscribers(topic string) int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Topics lists each topic with at least one subscriber.
+func (h *Hub) Topics() []string {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	topics := []string{}
+	for topic := range h.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	return topics
+
+}
+
+// Topic gets the topic the subscription receives messages from.
+func (s *Subscription) Topic() string {
+	return s.topic
+}
+
+// Done gets a channel that is closed when the subscription ends.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped counts the messages that could not be delivered to the subscriber
+// because its buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe ends the subscription and closes its channel. Messages already
+// buffered may still be received. Calling Unsubscribe more than once has no
+// effect.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+
+		s.hub.mutex.Lock()
+		defer s.hub.mutex.Unlock()
+
+		if subscribers, ok := s.hub.topics[s.topic]; ok {
+			delete(subscribers, s)
+			if len(subscribers) == 0 {
+				delete(s.hub.topics, s.topic)
+			}
+		}
+
+		close(s.channel)
+		close(s.done)
+
+	})
+}
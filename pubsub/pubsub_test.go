@@ -0,0 +1,276 @@
+package pubsub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// drain reads each message buffered for the supplied subscription without
+// waiting for more.
+func drain(s *Subscription) []interface{} {
+	messages := []interface{}{}
+	for {
+		select {
+		case message, ok := <-s.C:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+}
+
+// isClosed determines whether the supplied channel is closed once any buffered
+// messages are read.
+func isClosed(channel <-chan interface{}) bool {
+	for {
+		select {
+		case _, ok := <-channel:
+			if !ok {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+
+	h := NewHub()
+
+	first := h.Subscribe("a", 4, PolicyDrop)
+	second := h.Subscribe("a", 4, PolicyDrop)
+	other := h.Subscribe("b", 4, PolicyDrop)
+
+	if delivered := h.Publish("a", 1); delivered != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", delivered)
+	}
+	if delivered := h.Publish("c", 2); delivered != 0 {
+		t.Fatalf("expected 0 deliveries, got %d", delivered)
+	}
+
+	for _, s := range []*Subscription{first, second} {
+		if messages := drain(s); !reflect.DeepEqual(messages,
+			[]interface{}{1}) {
+			t.Fatalf("expected [1], got %v", messages)
+		}
+	}
+	if messages := drain(other); len(messages) != 0 {
+		t.Fatalf("expected no messages, got %v", messages)
+	}
+
+	if topics := h.Topics(); !reflect.DeepEqual(topics,
+		[]string{"a", "b"}) {
+		t.Fatalf("expected topics [a b], got %v", topics)
+	}
+
+}
+
+func TestPolicy(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		policy     Policy
+		size       int
+		publish    int
+		delivered  []int         // the result of each publish
+		received   []interface{} // the messages left in the buffer
+		dropped    uint64
+		subscribed bool
+	}{
+		{
+			name:       "drop keeps the subscriber",
+			policy:     PolicyDrop,
+			size:       2,
+			publish:    4,
+			delivered:  []int{1, 1, 0, 0},
+			received:   []interface{}{0, 1},
+			dropped:    2,
+			subscribed: true,
+		},
+		{
+			name:       "drop without a buffer",
+			policy:     PolicyDrop,
+			size:       0,
+			publish:    2,
+			delivered:  []int{0, 0},
+			received:   []interface{}{},
+			dropped:    2,
+			subscribed: true,
+		},
+		{
+			name:       "disconnect once the buffer is full",
+			policy:     PolicyDisconnect,
+			size:       2,
+			publish:    4,
+			delivered:  []int{1, 1, 0, 0},
+			received:   []interface{}{0, 1},
+			dropped:    1,
+			subscribed: false,
+		},
+		{
+			name:       "disconnect with room in the buffer",
+			policy:     PolicyDisconnect,
+			size:       2,
+			publish:    2,
+			delivered:  []int{1, 1},
+			received:   []interface{}{0, 1},
+			dropped:    0,
+			subscribed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			h := NewHub()
+			s := h.Subscribe("topic", test.size, test.policy)
+
+			delivered := []int{}
+			for i := 0; i < test.publish; i++ {
+				delivered = append(delivered, h.Publish("topic", i))
+			}
+
+			if !reflect.DeepEqual(delivered, test.delivered) {
+				t.Fatalf("expected deliveries %v, got %v", test.delivered,
+					delivered)
+			}
+			if s.Dropped() != test.dropped {
+				t.Fatalf("expected %d dropped, got %d", test.dropped,
+					s.Dropped())
+			}
+			if subscribed := h.Subscribers("topic") == 1; subscribed !=
+				test.subscribed {
+				t.Fatalf("expected subscribed %v, got %v", test.subscribed,
+					subscribed)
+			}
+
+			// messages buffered before a disconnect can still be received
+			if received := drain(s); !reflect.DeepEqual(received,
+				test.received) {
+				t.Fatalf("expected messages %v, got %v", test.received,
+					received)
+			}
+
+			if closed := isClosed(s.C); closed == test.subscribed {
+				t.Fatalf("expected channel closed %v, got %v",
+					!test.subscribed, closed)
+			}
+
+		})
+	}
+
+}
+
+func TestUnsubscribe(t *testing.T) {
+
+	h := NewHub()
+	s := h.Subscribe("topic", 1, PolicyDrop)
+	h.Publish("topic", "message")
+
+	s.Unsubscribe()
+	s.Unsubscribe()
+
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("expected done to be closed")
+	}
+
+	if messages := drain(s); !reflect.DeepEqual(messages,
+		[]interface{}{"message"}) {
+		t.Fatalf("expected buffered message, got %v", messages)
+	}
+	if !isClosed(s.C) {
+		t.Fatal("expected channel to be closed")
+	}
+
+	if delivered := h.Publish("topic", "message"); delivered != 0 {
+		t.Fatalf("expected 0 deliveries, got %d", delivered)
+	}
+	if topics := h.Topics(); len(topics) != 0 {
+		t.Fatalf("expected no topics, got %v", topics)
+	}
+
+}
+
+func TestUnsubscribeDuringPublish(t *testing.T) {
+
+	const (
+		publishers  = 4
+		subscribers = 32
+		messages    = 200
+	)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		size   int
+	}{
+		{"drop", PolicyDrop, 1},
+		{"disconnect", PolicyDisconnect, 1},
+		{"disconnect without a buffer", PolicyDisconnect, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			h := NewHub()
+
+			items := []*Subscription{}
+			for i := 0; i < subscribers; i++ {
+				items = append(items, h.Subscribe("topic", test.size,
+					test.policy))
+			}
+
+			// consume messages until each subscription ends
+			consumers := &sync.WaitGroup{}
+			for _, s := range items {
+				consumers.Add(1)
+				go func(s *Subscription) {
+					defer consumers.Done()
+					for range s.C {
+					}
+				}(s)
+			}
+
+			wg := &sync.WaitGroup{}
+			for i := 0; i < publishers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < messages; j++ {
+						h.Publish("topic", j)
+					}
+				}()
+			}
+
+			// unsubscribe while messages are being published, subscribers
+			// with the disconnect policy may also be disconnected by a
+			// publisher at the same time
+			for _, s := range items {
+				wg.Add(1)
+				go func(s *Subscription) {
+					defer wg.Done()
+					s.Unsubscribe()
+				}(s)
+			}
+
+			wg.Wait()
+			consumers.Wait()
+
+			if count := h.Subscribers("topic"); count != 0 {
+				t.Fatalf("expected no subscribers, got %d", count)
+			}
+			if delivered := h.Publish("topic", 0); delivered != 0 {
+				t.Fatalf("expected 0 deliveries, got %d", delivered)
+			}
+
+		})
+	}
+
+}
@@ -35,7 +35,8 @@
 //               Default: 600
 //     MOJITO_CLIENT_BASE_URL
 //         string - the base URL of the server that is used to serve the
-//                  application front-end.
+//                  application front-end. Browsers may only open websockets
+//                  from this origin.
 package server
 
 import (
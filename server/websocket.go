@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// upgrader is used to upgrade stream requests to websockets.
+var upgrader = websocket.Upgrader{CheckOrigin: checkOrigin}
+
+// Upgrade upgrades the supplied request to a websocket connection. Browsers
+// may only open a websocket from the application front-end, requests that do
+// not supply an origin are made by other servers and are accepted.
+func Upgrade(c *gin.Context) (*websocket.Conn, error) {
+	return upgrader.Upgrade(c.Writer, c.Request, nil)
+}
+
+// checkOrigin determines whether the origin of a websocket request matches the
+// scheme and host of the client base URL.
+func checkOrigin(r *http.Request) bool {
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	clientURL, err := url.Parse(ClientBaseURL())
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(originURL.Scheme, clientURL.Scheme) &&
+		strings.EqualFold(originURL.Host, clientURL.Host)
+
+}
@@ -0,0 +1,5 @@
+// Package strategy provides trading strategies that turn a series of
+// candlesticks into buy and sell signals. Strategies are evaluated one
+// candlestick at a time so they can be replayed over stored price data or run
+// against a live market data feed.
+package strategy
@@ -0,0 +1,295 @@
+package strategy
+
+import (
+	"errors"
+	"strings"
+
+	"mojito/market"
+	"mojito/market/indicator"
+	"mojito/market/pattern"
+)
+
+// Signal is the action a strategy recommends after evaluating a candlestick.
+type Signal string
+
+// Define strategy signals.
+const (
+	SignalHold Signal = "hold"
+	SignalBuy  Signal = "buy"
+	SignalSell Signal = "sell"
+)
+
+// Type refers to a specific trading strategy.
+type Type string
+
+// Define supported strategies.
+const (
+	TypeSMACrossover Type = "sma_crossover"
+	TypeEMACrossover Type = "ema_crossover"
+	TypeRSI          Type = "rsi"
+	TypeMACD         Type = "macd"
+	TypeBollinger    Type = "bollinger"
+	TypePattern      Type = "pattern"
+)
+
+// ErrUnknownStrategy is returned when a strategy is requested that is not
+// defined.
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// Strategy evaluates a series of candlesticks and produces trading signals.
+type Strategy interface {
+	// Update adds the next candlestick in the series to the strategy and
+	// returns the recommended action.
+	Update(candlestick market.Candlestick) Signal
+	// Lookback gets the number of candlesticks that must be supplied before
+	// the strategy is able to produce signals.
+	Lookback() int
+}
+
+// New creates the specified strategy configured with the supplied parameters.
+// Parameters that are not supplied take the default for the strategy.
+func New(strategyType Type, params indicator.Params) (Strategy, error) {
+
+	if params == nil {
+		params = indicator.Params{}
+	}
+
+	strategyType = Type(strings.ToLower(string(strategyType)))
+
+	switch strategyType {
+	case TypeSMACrossover, TypeEMACrossover:
+		fast, err := params.Int("fast", 10)
+		if err != nil {
+			return nil, err
+		}
+		slow, err := params.Int("slow", 30)
+		if err != nil {
+			return nil, err
+		}
+		if fast >= slow {
+			return nil, errors.New("fast must be less than slow")
+		}
+		if strategyType == TypeEMACrossover {
+			return newCrossover(indicator.NewEMA(fast),
+				indicator.NewEMA(slow)), nil
+		}
+		return newCrossover(indicator.NewSMA(fast),
+			indicator.NewSMA(slow)), nil
+	case TypeRSI:
+		period, err := params.Int("period", 14)
+		if err != nil {
+			return nil, err
+		}
+		oversold, err := params.Float64("oversold", 30)
+		if err != nil {
+			return nil, err
+		}
+		overbought, err := params.Float64("overbought", 70)
+		if err != nil {
+			return nil, err
+		}
+		if oversold >= overbought {
+			return nil, errors.New("oversold must be less than overbought")
+		}
+		return &rsiStrategy{
+			rsi:        indicator.NewRSI(period),
+			oversold:   oversold,
+			overbought: overbought,
+		}, nil
+	case TypeMACD:
+		ind, err := indicator.New(indicator.NameMACD, params)
+		if err != nil {
+			return nil, err
+		}
+		return &macdStrategy{macd: ind}, nil
+	case TypeBollinger:
+		ind, err := indicator.New(indicator.NameBollinger, params)
+		if err != nil {
+			return nil, err
+		}
+		return &bollingerStrategy{bands: ind}, nil
+	case TypePattern:
+		confidence, err := params.Float64("confidence", 0.6)
+		if err != nil {
+			return nil, err
+		}
+		if confidence > 1 {
+			return nil, errors.New("confidence must be at most 1")
+		}
+		scanner, err := pattern.NewScanner()
+		if err != nil {
+			return nil, err
+		}
+		return &patternStrategy{scanner: scanner, confidence: confidence}, nil
+	}
+
+	return nil, ErrUnknownStrategy
+
+}
+
+// crossover buys when a fast moving average crosses above a slow moving
+// average and sells when it crosses below.
+type crossover struct {
+	fast     indicator.Indicator
+	slow     indicator.Indicator
+	prevDiff float64
+	ready    bool
+}
+
+// newCrossover creates a crossover strategy from the supplied moving averages.
+func newCrossover(fast, slow indicator.Indicator) *crossover {
+	return &crossover{fast: fast, slow: slow}
+}
+
+// Update adds the next candlestick to the strategy.
+func (c *crossover) Update(candlestick market.Candlestick) Signal {
+	fast, fastOK := c.fast.Update(candlestick)
+	slow, slowOK := c.slow.Update(candlestick)
+	if !fastOK || !slowOK {
+		return SignalHold
+	}
+
+	diff := fast["value"] - slow["value"]
+	prevDiff, ready := c.prevDiff, c.ready
+	c.prevDiff, c.ready = diff, true
+
+	if !ready {
+		return SignalHold
+	} else if prevDiff <= 0 && diff > 0 {
+		return SignalBuy
+	} else if prevDiff >= 0 && diff < 0 {
+		return SignalSell
+	}
+
+	return SignalHold
+}
+
+// Lookback gets the number of candlesticks needed before the strategy
+// produces signals.
+func (c *crossover) Lookback() int {
+	return c.slow.Lookback() + 1
+}
+
+// rsiStrategy buys when the relative strength index indicates the security is
+// oversold and sells when it indicates the security is overbought.
+type rsiStrategy struct {
+	rsi        indicator.Indicator
+	oversold   float64
+	overbought float64
+}
+
+// Update adds the next candlestick to the strategy.
+func (r *rsiStrategy) Update(candlestick market.Candlestick) Signal {
+	value, ok := r.rsi.Update(candlestick)
+	if !ok {
+		return SignalHold
+	} else if value["value"] < r.oversold {
+		return SignalBuy
+	} else if value["value"] > r.overbought {
+		return SignalSell
+	}
+	return SignalHold
+}
+
+// Lookback gets the number of candlesticks needed before the strategy
+// produces signals.
+func (r *rsiStrategy) Lookback() int {
+	return r.rsi.Lookback()
+}
+
+// macdStrategy buys when the MACD line crosses above its signal line and sells
+// when it crosses below.
+type macdStrategy struct {
+	macd          indicator.Indicator
+	prevHistogram float64
+	ready         bool
+}
+
+// Update adds the next candlestick to the strategy.
+func (m *macdStrategy) Update(candlestick market.Candlestick) Signal {
+	value, ok := m.macd.Update(candlestick)
+	if !ok {
+		return SignalHold
+	}
+
+	histogram := value["histogram"]
+	prevHistogram, ready := m.prevHistogram, m.ready
+	m.prevHistogram, m.ready = histogram, true
+
+	if !ready {
+		return SignalHold
+	} else if prevHistogram <= 0 && histogram > 0 {
+		return SignalBuy
+	} else if prevHistogram >= 0 && histogram < 0 {
+		return SignalSell
+	}
+
+	return SignalHold
+}
+
+// Lookback gets the number of candlesticks needed before the strategy
+// produces signals.
+func (m *macdStrategy) Lookback() int {
+	return m.macd.Lookback() + 1
+}
+
+// bollingerStrategy buys when the price closes below the lower Bollinger Band
+// and sells when it closes above the upper band.
+type bollingerStrategy struct {
+	bands indicator.Indicator
+}
+
+// Update adds the next candlestick to the strategy.
+func (b *bollingerStrategy) Update(candlestick market.Candlestick) Signal {
+	value, ok := b.bands.Update(candlestick)
+	if !ok {
+		return SignalHold
+	} else if candlestick.Close < value["lower"] {
+		return SignalBuy
+	} else if candlestick.Close > value["upper"] {
+		return SignalSell
+	}
+	return SignalHold
+}
+
+// Lookback gets the number of candlesticks needed before the strategy
+// produces signals.
+func (b *bollingerStrategy) Lookback() int {
+	return b.bands.Lookback()
+}
+
+// patternStrategy buys when a bullish candlestick pattern forms and sells when
+// a bearish pattern forms, ignoring patterns below the minimum confidence. If
+// patterns in both directions complete on the same candlestick the most
+// confident pattern is followed.
+type patternStrategy struct {
+	scanner    *pattern.Scanner
+	confidence float64
+}
+
+// Update adds the next candlestick to the strategy.
+func (p *patternStrategy) Update(candlestick market.Candlestick) Signal {
+
+	signal, best := SignalHold, 0.0
+
+	for _, match := range p.scanner.Update(candlestick) {
+		if match.Confidence < p.confidence || match.Confidence <= best {
+			continue
+		}
+		switch match.Direction {
+		case pattern.DirectionBullish:
+			signal, best = SignalBuy, match.Confidence
+		case pattern.DirectionBearish:
+			signal, best = SignalSell, match.Confidence
+		}
+	}
+
+	return signal
+
+}
+
+// Lookback gets the number of candlesticks needed before the strategy
+// produces signals.
+func (p *patternStrategy) Lookback() int {
+	return p.scanner.Lookback()
+}
@@ -16,6 +16,7 @@ func init() {
 
 	data.DB().AutoMigrate(
 		User{},
+		UserSettings{},
 		Login{},
 	)
 
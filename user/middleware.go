@@ -13,6 +13,7 @@ import (
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
@@ -39,6 +40,9 @@ const (
 	// insufficientPermissionsGeneric is returned when a user does not have
 	// required permissions to complete a request.
 	insufficientPermissionsGeneric = "insufficient user permissions"
+	// accessTokenParam is the query parameter that may carry the access token
+	// of a websocket upgrade, which cannot be sent in the authorization header.
+	accessTokenParam = "access_token"
 )
 
 // JWTAuthMiddleware gets middleware that handles request authentication using
@@ -57,6 +61,41 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// JWTAdminMiddleware gets middleware that handles request authentication using
+// a JWT bearer token and only allows requests from admin users.
+func JWTAdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := jwtAccessTokenValid(c); err != nil {
+			logrus.Debug(err)
+			c.JSON(http.StatusUnauthorized, httperror.ErrorResponse{
+				ErrorMessage: authorizationFailedGeneric,
+			})
+			c.Abort()
+			return
+		}
+
+		u, err := JWTGetUser(c)
+		if err != nil {
+			logrus.Error(err)
+			c.JSON(http.StatusInternalServerError, httperror.ErrorResponse{
+				ErrorMessage: httperror.InternalServerError,
+			})
+			c.Abort()
+			return
+		}
+
+		if !u.Admin {
+			c.JSON(http.StatusForbidden, httperror.ErrorResponse{
+				ErrorMessage: insufficientPermissionsGeneric,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // JWTGetUser extracts a user record from the request access token.
 func JWTGetUser(c *gin.Context) (*User, error) {
 
@@ -181,6 +220,9 @@ func jwtGetAccessMetadata(c *gin.Context) (*jwtAccessMetadata, error) {
 }
 
 // getAccessToken retrieves the bearer auth token from the supplied request.
+// Browsers cannot set headers on websocket requests, so the token may also be
+// supplied in the access_token query parameter of a websocket upgrade. Other
+// requests must use the header so tokens are not leaked in URLs.
 func getAccessToken(c *gin.Context) string {
 
 	tokenParts := strings.Split(c.Request.Header.Get("Authorization"), " ")
@@ -189,7 +231,11 @@ func getAccessToken(c *gin.Context) string {
 		return tokenParts[1]
 	}
 
-	return ""
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		return ""
+	}
+
+	return c.Query(accessTokenParam)
 
 }
 
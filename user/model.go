@@ -36,8 +36,9 @@ type UserSettings struct {
 	UserID uint `gorm:"index" json:"user_id"`
 
 	// Coinbase credentials for executing trades via API
-	CoinbaseAPIKey    string `json:"coinbase_api_key"`
-	CoinbaseSignature string `json:"coinbase_signature"`
+	CoinbaseAPIKey     string `json:"coinbase_api_key"`
+	CoinbaseSignature  string `json:"coinbase_signature"`
+	CoinbasePassphrase string `json:"coinbase_passphrase"`
 
 	// Alpaca credentials for executing trades via API
 	AlpacaAPIKey    string `json:"alpaca_api_key"`
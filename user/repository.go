@@ -52,6 +52,33 @@ func DeleteUser(ctx context.Context, db *gorm.DB, item *User) error {
 	return db.Delete(item).Error
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// UserSettings                                                               //
+////////////////////////////////////////////////////////////////////////////////
+
+// GetUserSettingsByUserID retrieves the settings record associated with the
+// supplied user id.
+func GetUserSettingsByUserID(ctx context.Context, db *gorm.DB,
+	userID uint) (*UserSettings, error) {
+
+	var item UserSettings
+
+	if err := db.Model(&UserSettings{}).
+		Where("user_id = ?", userID).
+		First(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+
+}
+
+// SaveUserSettings inserts or updates the supplied settings record.
+func SaveUserSettings(ctx context.Context, db *gorm.DB,
+	item *UserSettings) error {
+	return db.Save(item).Error
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Login                                                                      //
 ////////////////////////////////////////////////////////////////////////////////